@@ -0,0 +1,217 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/config"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/httpserver"
+	"github.com/dapplux/twitter-haiku-bot/services"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+// API serves the admin endpoints for inspecting and fixing haikus. Every
+// request must carry the configured token as a bearer token. Errors are
+// returned as {"error": {"code": ..., "message": ...}}.
+//
+//	GET   /admin/haikus                list haikus: state, platform, from, to, limit, offset
+//	GET   /admin/haikus/{id}           a haiku with its post, history and publications
+//	PATCH /admin/haikus/{id}           edit the text: {"text": "...", "reviewer": "...", "reason": "..."};
+//	                                   a reviewer is required while it is under review
+//	POST  /admin/haikus/{id}/retry     put a failed haiku back into the pipeline
+//	POST  /admin/haikus/{id}/cancel    cancel it: {"reason": "..."} (optional)
+//	POST  /admin/haikus/{id}/publish   queue it for publishing now
+//	POST  /admin/haikus/{id}/approve   approve it: {"reviewer": "...", "reason": "..."} (reason optional)
+//	POST  /admin/haikus/{id}/reject    reject it: {"reviewer": "...", "reason": "..."}
+//	GET   /admin/haikus/{id}/engagement
+//	                                   the engagement time series of a published haiku
+//	GET   /admin/engagement            rank haikus published since from by engagement, grouped
+//	                                   by haiku, prompt, model, search_profile or experiment arm:
+//	                                   by, from, limit; scores carry 95% confidence intervals
+//	GET   /admin/posts                 list posts: q, platform, from, to, limit, offset
+//	GET   /admin/authors/{list}        list the blocklist or optout list: limit, offset
+//	POST  /admin/authors/{list}        add an author: {"platform": "...", "author_id": "...", "username": "...", "reason": "..."}
+//	DELETE /admin/authors/{list}/{platform}/{author_id}
+//	                                   remove an author
+//
+// from and to bound the creation time; from is inclusive, to exclusive. They
+// are RFC 3339 timestamps or YYYY-MM-DD days in UTC.
+type API struct {
+	haikuSvc   *services.HaikuService
+	authorSvc  *services.AuthorService
+	haikuRepo  repositories.HaikuRepository
+	postRepo   repositories.PostRepository
+	outboxRepo repositories.OutboxRepository
+	authorRepo repositories.AuthorListRepository
+	// engagementRepo holds the metrics sampled from published haikus.
+	engagementRepo repositories.EngagementRepository
+	token          config.Secret
+}
+
+// New creates the API. Changes go through haikuSvc and authorSvc so they
+// follow the same locking and history rules as the pipeline.
+func New(haikuSvc *services.HaikuService, authorSvc *services.AuthorService, haikuRepo repositories.HaikuRepository, postRepo repositories.PostRepository, outboxRepo repositories.OutboxRepository, authorRepo repositories.AuthorListRepository, engagementRepo repositories.EngagementRepository, token config.Secret) *API {
+	return &API{
+		haikuSvc:       haikuSvc,
+		authorSvc:      authorSvc,
+		haikuRepo:      haikuRepo,
+		postRepo:       postRepo,
+		outboxRepo:     outboxRepo,
+		authorRepo:     authorRepo,
+		engagementRepo: engagementRepo,
+		token:          token,
+	}
+}
+
+// Register adds the endpoints to mux.
+func (a *API) Register(mux httpserver.Mux) {
+	routes := map[string]handlerFunc{
+		"GET /admin/haikus":                                   a.listHaikus,
+		"GET /admin/haikus/{id}":                              a.getHaiku,
+		"PATCH /admin/haikus/{id}":                            a.editHaiku,
+		"POST /admin/haikus/{id}/retry":                       a.retryHaiku,
+		"POST /admin/haikus/{id}/cancel":                      a.cancelHaiku,
+		"POST /admin/haikus/{id}/publish":                     a.publishHaiku,
+		"POST /admin/haikus/{id}/approve":                     a.approveHaiku,
+		"POST /admin/haikus/{id}/reject":                      a.rejectHaiku,
+		"GET /admin/haikus/{id}/engagement":                   a.haikuEngagement,
+		"GET /admin/engagement":                               a.rankEngagement,
+		"GET /admin/posts":                                    a.listPosts,
+		"GET /admin/authors/{list}":                           a.listAuthors,
+		"POST /admin/authors/{list}":                          a.addAuthor,
+		"DELETE /admin/authors/{list}/{platform}/{author_id}": a.removeAuthor,
+	}
+	for pattern, h := range routes {
+		mux.Handle(pattern, a.authenticate(h))
+	}
+	mux.Handle("/admin/", a.authenticate(func(w http.ResponseWriter, r *http.Request) error {
+		return &apiError{status: http.StatusNotFound, code: "not_found", message: "no such endpoint"}
+	}))
+}
+
+// handlerFunc is a handler whose error is written as a JSON error response.
+type handlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// authenticate rejects requests without the admin token and serves the rest with h.
+func (a *API) authenticate(h handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.token.Reveal())) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeError(w, r, &apiError{status: http.StatusUnauthorized, code: "unauthorized", message: "missing or invalid token"})
+			return
+		}
+
+		if err := h(w, r); err != nil {
+			writeError(w, r, err)
+		}
+	})
+}
+
+// apiError is an error with the response it maps to.
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func badRequest(format string, args ...any) error {
+	return &apiError{status: http.StatusBadRequest, code: "bad_request", message: fmt.Sprintf(format, args...)}
+}
+
+// writeError maps err to a status code and writes it. Unexpected errors are
+// logged and reported without their details.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *apiError
+	switch {
+	case errors.As(err, &apiErr):
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		apiErr = &apiError{status: http.StatusNotFound, code: "not_found", message: err.Error()}
+	case errors.Is(err, services.ErrInvalidState):
+		apiErr = &apiError{status: http.StatusConflict, code: "conflict", message: err.Error()}
+	case errors.Is(err, services.ErrInvalidInput):
+		apiErr = &apiError{status: http.StatusBadRequest, code: "bad_request", message: err.Error()}
+	default:
+		slog.ErrorContext(r.Context(), "Admin API request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+		apiErr = &apiError{status: http.StatusInternalServerError, code: "internal", message: "internal error"}
+	}
+
+	httpserver.WriteJSON(w, r, apiErr.status, map[string]any{
+		"error": map[string]string{"code": apiErr.code, "message": apiErr.message},
+	})
+}
+
+// readJSON decodes the request body into v, rejecting unknown fields. An empty
+// body leaves v untouched.
+func readJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 64<<10))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return badRequest("invalid request body: %v", err)
+	}
+	return nil
+}
+
+// page is a paginated listing.
+type page[T any] struct {
+	Items  []T   `json:"items"`
+	Total  int64 `json:"total"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+}
+
+// parsePage reads limit and offset, defaulting to the first defaultLimit results.
+func parsePage(r *http.Request) (repositories.Page, error) {
+	p := repositories.Page{Limit: defaultLimit}
+	q := r.URL.Query()
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLimit {
+			return p, badRequest("limit must be between 1 and %d", maxLimit)
+		}
+		p.Limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return p, badRequest("offset must be a non-negative integer")
+		}
+		p.Offset = n
+	}
+	return p, nil
+}
+
+// parseTime reads an RFC 3339 timestamp or a YYYY-MM-DD day from the query;
+// a missing parameter yields the zero time.
+func parseTime(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, badRequest("%s must be an RFC 3339 timestamp or a YYYY-MM-DD date", name)
+}
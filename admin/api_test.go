@@ -0,0 +1,154 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/memory"
+	"github.com/dapplux/twitter-haiku-bot/services"
+	"gorm.io/gorm"
+)
+
+// testAPI serves an API over an in-memory store holding haikus haikus, one
+// minute apart, the first one oldest.
+func testAPI(t *testing.T, haikus int) http.Handler {
+	t.Helper()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := memory.NewStore(func() time.Time { return now })
+	haikuRepo := memory.NewHaikuRepository(store)
+	for i := 0; i < haikus; i++ {
+		now = now.Add(time.Minute)
+		h := entities.Haiku{ID: fmt.Sprintf("h%d", i), State: entities.HaikuStateCreated}
+		if err := haikuRepo.Create(context.Background(), nil, &h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	api := &API{haikuRepo: haikuRepo, postRepo: memory.NewPostRepository(store), token: "s3cret"}
+	mux := http.NewServeMux()
+	api.Register(mux)
+	return mux
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		wantStatus    int
+	}{
+		{"no token", "/admin/haikus", "", http.StatusUnauthorized},
+		{"wrong token", "/admin/haikus", "Bearer guess", http.StatusUnauthorized},
+		{"token without scheme", "/admin/haikus", "s3cret", http.StatusUnauthorized},
+		{"basic scheme", "/admin/haikus", "Basic s3cret", http.StatusUnauthorized},
+		{"valid token", "/admin/haikus", "Bearer s3cret", http.StatusOK},
+		{"unknown endpoint without token", "/admin/nothing", "", http.StatusUnauthorized},
+		{"unknown endpoint with token", "/admin/nothing", "Bearer s3cret", http.StatusNotFound},
+	}
+	api := testAPI(t, 0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			api.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestListHaikusPagination(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantIDs    []string
+	}{
+		{"default page", "", http.StatusOK, []string{"h4", "h3", "h2", "h1", "h0"}},
+		{"first page", "?limit=2", http.StatusOK, []string{"h4", "h3"}},
+		{"second page", "?limit=2&offset=2", http.StatusOK, []string{"h2", "h1"}},
+		{"partial last page", "?limit=2&offset=4", http.StatusOK, []string{"h0"}},
+		{"past the end", "?offset=10", http.StatusOK, nil},
+		{"zero limit", "?limit=0", http.StatusBadRequest, nil},
+		{"limit above maximum", fmt.Sprintf("?limit=%d", maxLimit+1), http.StatusBadRequest, nil},
+		{"negative offset", "?offset=-1", http.StatusBadRequest, nil},
+		{"unknown state", "?state=bogus", http.StatusBadRequest, nil},
+		{"malformed from", "?from=yesterday", http.StatusBadRequest, nil},
+	}
+	api := testAPI(t, 5)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/haikus"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer s3cret")
+			rec := httptest.NewRecorder()
+			api.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var got page[haikuView]
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Total != 5 {
+				t.Errorf("total = %d, want 5", got.Total)
+			}
+			var ids []string
+			for _, h := range got.Items {
+				ids = append(ids, h.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("items = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"invalid state", fmt.Errorf("%w: haiku h1 is done", services.ErrInvalidState), http.StatusConflict, "conflict"},
+		{"invalid input", fmt.Errorf("%w: reason must not be empty", services.ErrInvalidInput), http.StatusBadRequest, "bad_request"},
+		{"missing record", fmt.Errorf("haiku h1: %w", gorm.ErrRecordNotFound), http.StatusNotFound, "not_found"},
+		{"api error", badRequest("limit must be positive"), http.StatusBadRequest, "bad_request"},
+		{"unexpected", fmt.Errorf("connection reset"), http.StatusInternalServerError, "internal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeError(rec, httptest.NewRequest(http.MethodGet, "/admin/haikus", nil), tt.err)
+
+			var body struct {
+				Error struct{ Code, Message string }
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != tt.wantStatus || body.Error.Code != tt.wantCode {
+				t.Errorf("writeError() = %d %s, want %d %s", rec.Code, body.Error.Code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/httpserver"
+	"github.com/guregu/null"
+)
+
+func (a *API) listHaikus(w http.ResponseWriter, r *http.Request) error {
+	p, err := parsePage(r)
+	if err != nil {
+		return err
+	}
+	filter := repositories.HaikuFilter{
+		State:    entities.HaikuState(r.URL.Query().Get("state")),
+		Platform: entities.Platform(r.URL.Query().Get("platform")),
+	}
+	if filter.State != "" && !validState(filter.State) {
+		return badRequest("unknown state %q", filter.State)
+	}
+	if filter.CreatedFrom, err = parseTime(r, "from"); err != nil {
+		return err
+	}
+	if filter.CreatedTo, err = parseTime(r, "to"); err != nil {
+		return err
+	}
+
+	haikus, total, err := a.haikuRepo.List(r.Context(), nil, filter, p)
+	if err != nil {
+		return err
+	}
+	httpserver.WriteJSON(w, r, http.StatusOK, page[haikuView]{
+		Items:  mapViews(haikus, newHaikuView),
+		Total:  total,
+		Limit:  p.Limit,
+		Offset: p.Offset,
+	})
+	return nil
+}
+
+func (a *API) getHaiku(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	h, err := a.haikuRepo.FindByID(ctx, nil, r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("haiku %s: %w", r.PathValue("id"), err)
+	}
+	events, err := a.haikuRepo.FindEvents(ctx, nil, h.ID)
+	if err != nil {
+		return err
+	}
+	entries, err := a.outboxRepo.FindByHaikuID(ctx, nil, h.ID)
+	if err != nil {
+		return err
+	}
+
+	httpserver.WriteJSON(w, r, http.StatusOK, haikuDetail{
+		Haiku:        newHaikuView(*h),
+		Post:         newPostView(h.Post),
+		Events:       mapViews(events, newEventView),
+		Publications: mapViews(entries, newPublicationView),
+	})
+	return nil
+}
+
+func (a *API) editHaiku(w http.ResponseWriter, r *http.Request) error {
+	var body struct {
+		Text     *string `json:"text"`
+		Reviewer string  `json:"reviewer"`
+		Reason   string  `json:"reason"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		return err
+	}
+	if body.Text == nil {
+		return badRequest("text is required")
+	}
+
+	h, err := a.haikuSvc.EditText(r.Context(), r.PathValue("id"), *body.Text, body.Reviewer, body.Reason)
+	return a.writeHaiku(w, r, h, err)
+}
+
+// reviewBody is the request body of review decisions.
+type reviewBody struct {
+	Reviewer string `json:"reviewer"`
+	Reason   string `json:"reason"`
+}
+
+func (a *API) approveHaiku(w http.ResponseWriter, r *http.Request) error {
+	var body reviewBody
+	if err := readJSON(r, &body); err != nil {
+		return err
+	}
+
+	h, err := a.haikuSvc.Approve(r.Context(), r.PathValue("id"), body.Reviewer, body.Reason)
+	return a.writeHaiku(w, r, h, err)
+}
+
+func (a *API) rejectHaiku(w http.ResponseWriter, r *http.Request) error {
+	var body reviewBody
+	if err := readJSON(r, &body); err != nil {
+		return err
+	}
+
+	h, err := a.haikuSvc.Reject(r.Context(), r.PathValue("id"), body.Reviewer, body.Reason)
+	return a.writeHaiku(w, r, h, err)
+}
+
+func (a *API) retryHaiku(w http.ResponseWriter, r *http.Request) error {
+	h, err := a.haikuSvc.Retry(r.Context(), r.PathValue("id"))
+	return a.writeHaiku(w, r, h, err)
+}
+
+func (a *API) cancelHaiku(w http.ResponseWriter, r *http.Request) error {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		return err
+	}
+
+	h, err := a.haikuSvc.Cancel(r.Context(), r.PathValue("id"), body.Reason)
+	return a.writeHaiku(w, r, h, err)
+}
+
+func (a *API) publishHaiku(w http.ResponseWriter, r *http.Request) error {
+	h, err := a.haikuSvc.ForcePublish(r.Context(), r.PathValue("id"))
+	return a.writeHaiku(w, r, h, err)
+}
+
+// writeHaiku responds with the haiku returned by a change, or passes on its error.
+func (a *API) writeHaiku(w http.ResponseWriter, r *http.Request, h *entities.Haiku, err error) error {
+	if err != nil {
+		return err
+	}
+	httpserver.WriteJSON(w, r, http.StatusOK, newHaikuView(*h))
+	return nil
+}
+
+func (a *API) haikuEngagement(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	h, err := a.haikuRepo.FindByID(ctx, nil, r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("haiku %s: %w", r.PathValue("id"), err)
+	}
+	samples, err := a.engagementRepo.FindByHaikuID(ctx, nil, h.ID)
+	if err != nil {
+		return err
+	}
+
+	httpserver.WriteJSON(w, r, http.StatusOK, engagementSeries{
+		HaikuID:     h.ID,
+		ReplyID:     h.ReplyID,
+		PublishedAt: h.PublishedAt,
+		Samples:     mapViews(samples, newEngagementSampleView),
+	})
+	return nil
+}
+
+func (a *API) rankEngagement(w http.ResponseWriter, r *http.Request) error {
+	p, err := parsePage(r)
+	if err != nil {
+		return err
+	}
+	group := repositories.GroupByHaiku
+	if by := r.URL.Query().Get("by"); by != "" {
+		group = repositories.EngagementGroup(by)
+	}
+	if !slices.Contains(repositories.EngagementGroups, group) {
+		return badRequest("by must be one of haiku, prompt, model, search_profile or arm")
+	}
+	since, err := parseTime(r, "from")
+	if err != nil {
+		return err
+	}
+
+	ranks, err := a.engagementRepo.Rank(r.Context(), nil, group, since, p.Limit)
+	if err != nil {
+		return err
+	}
+	httpserver.WriteJSON(w, r, http.StatusOK, engagementRanking{
+		By:    string(group),
+		Items: mapViews(ranks, newEngagementRankView),
+	})
+	return nil
+}
+
+func (a *API) listPosts(w http.ResponseWriter, r *http.Request) error {
+	p, err := parsePage(r)
+	if err != nil {
+		return err
+	}
+	filter := repositories.PostFilter{
+		Platform: entities.Platform(r.URL.Query().Get("platform")),
+		Query:    r.URL.Query().Get("q"),
+	}
+	if filter.CreatedFrom, err = parseTime(r, "from"); err != nil {
+		return err
+	}
+	if filter.CreatedTo, err = parseTime(r, "to"); err != nil {
+		return err
+	}
+
+	posts, total, err := a.postRepo.List(r.Context(), nil, filter, p)
+	if err != nil {
+		return err
+	}
+	httpserver.WriteJSON(w, r, http.StatusOK, page[postView]{
+		Items:  mapViews(posts, newPostView),
+		Total:  total,
+		Limit:  p.Limit,
+		Offset: p.Offset,
+	})
+	return nil
+}
+
+func (a *API) listAuthors(w http.ResponseWriter, r *http.Request) error {
+	list, err := parseList(r)
+	if err != nil {
+		return err
+	}
+	p, err := parsePage(r)
+	if err != nil {
+		return err
+	}
+
+	entries, total, err := a.authorRepo.List(r.Context(), nil, list, p)
+	if err != nil {
+		return err
+	}
+	httpserver.WriteJSON(w, r, http.StatusOK, page[authorEntryView]{
+		Items:  mapViews(entries, newAuthorEntryView),
+		Total:  total,
+		Limit:  p.Limit,
+		Offset: p.Offset,
+	})
+	return nil
+}
+
+func (a *API) addAuthor(w http.ResponseWriter, r *http.Request) error {
+	list, err := parseList(r)
+	if err != nil {
+		return err
+	}
+	var body struct {
+		Platform string `json:"platform"`
+		AuthorID string `json:"author_id"`
+		Username string `json:"username"`
+		Reason   string `json:"reason"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		return err
+	}
+
+	entry, err := a.authorSvc.Add(r.Context(), list, entities.AuthorListEntry{
+		Platform: entities.Platform(body.Platform),
+		AuthorID: body.AuthorID,
+		Username: body.Username,
+		Reason:   null.NewString(body.Reason, body.Reason != ""),
+		AddedBy:  entities.EventActorAdmin,
+	})
+	if err != nil {
+		return err
+	}
+	httpserver.WriteJSON(w, r, http.StatusOK, newAuthorEntryView(*entry))
+	return nil
+}
+
+func (a *API) removeAuthor(w http.ResponseWriter, r *http.Request) error {
+	list, err := parseList(r)
+	if err != nil {
+		return err
+	}
+	if err := a.authorSvc.Remove(r.Context(), list, entities.Platform(r.PathValue("platform")), r.PathValue("author_id")); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func parseList(r *http.Request) (entities.AuthorList, error) {
+	list := entities.AuthorList(r.PathValue("list"))
+	if slices.Contains(entities.AuthorLists, list) {
+		return list, nil
+	}
+	return "", &apiError{status: http.StatusNotFound, code: "not_found", message: fmt.Sprintf("unknown author list %q", list)}
+}
+
+func validState(state entities.HaikuState) bool {
+	for _, s := range entities.HaikuStates {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,267 @@
+package admin
+
+import (
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/guregu/null"
+)
+
+// The views below define the JSON shape of the API independently of the
+// entities, which carry no JSON tags.
+
+type haikuView struct {
+	ID       string      `json:"id"`
+	State    string      `json:"state"`
+	Attempt  int         `json:"attempt"`
+	Summary  null.String `json:"summary"`
+	Text     null.String `json:"text"`
+	PostID   string      `json:"post_id"`
+	Platform string      `json:"platform"`
+
+	ReviewedBy   null.String `json:"reviewed_by"`
+	ReviewReason null.String `json:"review_reason"`
+	ReviewedAt   null.Time   `json:"reviewed_at"`
+
+	SafetyScore  null.Float  `json:"safety_score"`
+	FilterReason null.String `json:"filter_reason"`
+
+	Priority    float64     `json:"priority"`
+	MentionID   null.String `json:"mention_id"`
+	RequestedBy null.String `json:"requested_by"`
+
+	Model       null.String `json:"model"`
+	Prompt      null.String `json:"prompt"`
+	Experiment  null.String `json:"experiment"`
+	Arm         null.String `json:"arm"`
+	Language    string      `json:"language"`
+	ReplyID     null.String `json:"reply_id"`
+	PublishedAt null.Time   `json:"published_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newHaikuView(h entities.Haiku) haikuView {
+	return haikuView{
+		ID:       h.ID,
+		State:    string(h.State),
+		Attempt:  h.Attempt,
+		Summary:  h.Summary,
+		Text:     h.Text,
+		PostID:   h.PostID,
+		Platform: string(h.Post.Platform),
+
+		ReviewedBy:   h.ReviewedBy,
+		ReviewReason: h.ReviewReason,
+		ReviewedAt:   h.ReviewedAt,
+
+		SafetyScore:  h.SafetyScore,
+		FilterReason: h.FilterReason,
+
+		Priority:    h.Priority,
+		MentionID:   h.MentionID,
+		RequestedBy: h.RequestedBy,
+
+		Model:       h.Model,
+		Prompt:      h.Prompt,
+		Experiment:  h.Experiment,
+		Arm:         h.Arm,
+		Language:    h.Language,
+		ReplyID:     h.ReplyID,
+		PublishedAt: h.PublishedAt,
+
+		CreatedAt: h.CreatedAt,
+		UpdatedAt: h.UpdatedAt,
+	}
+}
+
+type authorView struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+type postView struct {
+	ID       string     `json:"id"`
+	Platform string     `json:"platform"`
+	Author   authorView `json:"author"`
+	Text     string     `json:"text"`
+	Likes    int        `json:"likes"`
+	Shares   int        `json:"shares"`
+	Replies  int        `json:"replies"`
+	Priority float64    `json:"priority"`
+	// SearchProfile is empty for posts looked up by ID.
+	SearchProfile string `json:"search_profile"`
+	// Language is empty if it could not be told.
+	Language  string    `json:"language"`
+	CreatedAt time.Time `json:"created_at"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+func newPostView(p entities.Post) postView {
+	return postView{
+		ID:            p.ID,
+		Platform:      string(p.Platform),
+		Author:        authorView{ID: p.Author.ID, Username: p.Author.Username},
+		Text:          p.Text,
+		Likes:         p.Likes,
+		Shares:        p.Shares,
+		Replies:       p.Replies,
+		Priority:      p.Priority,
+		SearchProfile: p.SearchProfile,
+		Language:      p.Language,
+		CreatedAt:     p.CreatedAt,
+		FetchedAt:     p.FetchedAt,
+	}
+}
+
+type eventView struct {
+	ID        int64       `json:"id"`
+	FromState string      `json:"from_state,omitempty"`
+	ToState   string      `json:"to_state"`
+	Actor     string      `json:"actor"`
+	Note      null.String `json:"note"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+func newEventView(e entities.HaikuEvent) eventView {
+	return eventView{
+		ID:        e.ID,
+		FromState: string(e.FromState),
+		ToState:   string(e.ToState),
+		Actor:     e.Actor,
+		Note:      e.Note,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+type publicationView struct {
+	ID            string      `json:"id"`
+	Platform      string      `json:"platform"`
+	Status        string      `json:"status"`
+	Attempts      int         `json:"attempts"`
+	NextAttemptAt time.Time   `json:"next_attempt_at"`
+	LastError     null.String `json:"last_error"`
+	Receipt       null.String `json:"receipt"`
+	DeliveredAt   null.Time   `json:"delivered_at"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+func newPublicationView(e entities.OutboxEntry) publicationView {
+	return publicationView{
+		ID:            e.ID,
+		Platform:      string(e.Platform),
+		Status:        string(e.Status),
+		Attempts:      e.Attempts,
+		NextAttemptAt: e.NextAttemptAt,
+		LastError:     e.LastError,
+		Receipt:       e.Receipt,
+		DeliveredAt:   e.DeliveredAt,
+		CreatedAt:     e.CreatedAt,
+	}
+}
+
+type engagementView struct {
+	Likes       int `json:"likes"`
+	Shares      int `json:"shares"`
+	Replies     int `json:"replies"`
+	Impressions int `json:"impressions"`
+}
+
+func newEngagementView(e entities.Engagement) engagementView {
+	return engagementView{
+		Likes:       e.Likes,
+		Shares:      e.Shares,
+		Replies:     e.Replies,
+		Impressions: e.Impressions,
+	}
+}
+
+type engagementSampleView struct {
+	engagementView
+	SampledAt time.Time `json:"sampled_at"`
+}
+
+func newEngagementSampleView(s entities.EngagementSample) engagementSampleView {
+	return engagementSampleView{
+		engagementView: newEngagementView(s.Engagement),
+		SampledAt:      s.SampledAt,
+	}
+}
+
+// engagementSeries is the engagement of a published haiku over time.
+type engagementSeries struct {
+	HaikuID     string                 `json:"haiku_id"`
+	ReplyID     null.String            `json:"reply_id"`
+	PublishedAt null.Time              `json:"published_at"`
+	Samples     []engagementSampleView `json:"samples"`
+}
+
+// engagementRankView totals the latest engagement of the haikus sharing
+// key; score is their average interactions, shares counting double, with
+// its 95% confidence interval, and rate the interactions per impression.
+type engagementRankView struct {
+	Key    string `json:"key"`
+	Haikus int    `json:"haikus"`
+	engagementView
+	Score     float64 `json:"score"`
+	ScoreLow  float64 `json:"score_low"`
+	ScoreHigh float64 `json:"score_high"`
+	Rate      float64 `json:"rate"`
+}
+
+func newEngagementRankView(r repositories.EngagementRank) engagementRankView {
+	low, high := r.Interval()
+	return engagementRankView{
+		Key:            r.Key,
+		Haikus:         r.Haikus,
+		engagementView: newEngagementView(r.Engagement),
+		Score:          r.Score(),
+		ScoreLow:       low,
+		ScoreHigh:      high,
+		Rate:           r.Rate(),
+	}
+}
+
+type engagementRanking struct {
+	By    string               `json:"by"`
+	Items []engagementRankView `json:"items"`
+}
+
+// haikuDetail is a haiku with everything known about it.
+type authorEntryView struct {
+	Platform  string      `json:"platform"`
+	AuthorID  string      `json:"author_id"`
+	Username  string      `json:"username"`
+	Reason    null.String `json:"reason"`
+	AddedBy   string      `json:"added_by"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+func newAuthorEntryView(e entities.AuthorListEntry) authorEntryView {
+	return authorEntryView{
+		Platform:  string(e.Platform),
+		AuthorID:  e.AuthorID,
+		Username:  e.Username,
+		Reason:    e.Reason,
+		AddedBy:   e.AddedBy,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+type haikuDetail struct {
+	Haiku        haikuView         `json:"haiku"`
+	Post         postView          `json:"post"`
+	Events       []eventView       `json:"events"`
+	Publications []publicationView `json:"publications"`
+}
+
+// mapViews converts every item with view.
+func mapViews[T, V any](items []T, view func(T) V) []V {
+	views := make([]V, 0, len(items))
+	for _, item := range items {
+		views = append(views, view(item))
+	}
+	return views
+}
@@ -3,15 +3,24 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
 	"time"
 
 	"github.com/dapplux/twitter-haiku-bot/config"
+	"github.com/dapplux/twitter-haiku-bot/experiment"
+	"github.com/dapplux/twitter-haiku-bot/freshness"
 	"github.com/dapplux/twitter-haiku-bot/infrastructure/ai"
 	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/postgres"
 	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/logging"
 	"github.com/dapplux/twitter-haiku-bot/infrastructure/platforms"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/quota"
+	"github.com/dapplux/twitter-haiku-bot/language"
+	"github.com/dapplux/twitter-haiku-bot/review"
+	"github.com/dapplux/twitter-haiku-bot/safety"
 	"github.com/dapplux/twitter-haiku-bot/scheduler"
+	"github.com/dapplux/twitter-haiku-bot/scoring"
 	"github.com/dapplux/twitter-haiku-bot/services"
 )
 
@@ -21,47 +30,101 @@ func main() {
 
 	cfg, aErr := config.AutoLoad()
 	if aErr != nil {
-		log.Fatalln(aErr)
-
-		return
+		fmt.Fprintln(os.Stderr, aErr)
+		os.Exit(1)
+	}
+	// Structured logs from here on, with configured secrets scrubbed.
+	if err := logging.Setup(cfg.Log, os.Stderr, cfg.Secrets()...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
+
 	db, err := postgres.New(cfg, "up", 0)
 	if err != nil {
-		log.Fatalf("failed to connect to database: %s", err.Error())
+		slog.Error("Failed to connect to database", "error", err)
+		os.Exit(1)
 	}
 
 	// Create repositories
 	haikuRepo := repositories.NewHaikuRepository(db.DB)
 	postRepo := repositories.NewPostRepository(db.DB)
+	quotaRepo := repositories.NewQuotaRepository(db.DB)
+	outboxRepo := repositories.NewOutboxRepository(db.DB)
+	authorRepo := repositories.NewAuthorListRepository(db.DB)
 
 	// Create a UnitOfWork (or transaction manager) from the base DB
 	// (Assume you have an implementation that wraps db.DB for transactions.)
 	txMgr := repositories.NewUnitOfWork(db.DB)
+	notifier := repositories.NewNotifier(db.DB)
 
 	// Initialize Platform Providers.
 	// For example, TwitterPlatform and TelegramPlatform.
 	// For this example, assume you have a TwitterPlatform and a TelegramPlatform.
 	//twitterPlatform := platforms.NewTwitterMock()
-	fmt.Println(cfg.Twitter.APIKey, cfg.Twitter.APISecret, cfg.Twitter.APIAccessToken, cfg.Twitter.APIAccessTokenSecret)
-	twitterPlatform := platforms.NewTwitterProvider(cfg.Twitter.APIKey, cfg.Twitter.APISecret, cfg.Twitter.APIAccessToken, cfg.Twitter.APIAccessTokenSecret)
+	// Every platform and AI call is recorded against the configured quota budgets.
+	quotaTracker := quota.NewTracker(quotaRepo, quota.BudgetsFromConfig(cfg.Quota), nil)
+	languagePolicy, err := language.FromConfig(cfg.Languages)
+	if err != nil {
+		slog.Error("Failed to set up the language policy", "error", err)
+		os.Exit(1)
+	}
+	twitter := platforms.NewTwitterProvider(
+		cfg.Twitter.APIKey.Reveal(),
+		cfg.Twitter.APISecret.Reveal(),
+		cfg.Twitter.APIAccessToken.Reveal(),
+		cfg.Twitter.APIAccessTokenSecret.Reveal(),
+	)
+	// Only posts in languages that are not skipped are searched for.
+	twitter.Query = platforms.TwitterSearchQueryFor(languagePolicy.SearchLanguages())
+	twitterPlatform := platforms.NewMeteredProvider(twitter, quotaTracker, quota.ProviderTwitter)
 
 	// Initialize TextProcessor (for instance, a HuggingFace processor)
-	textProcessor := ai.NewHuggingFaceProvider(cfg.HuggingFace.APIKey)
+	textProcessor := ai.NewMeteredProcessor(ai.NewHuggingFaceProvider(cfg.HuggingFace.APIKey.Reveal()), quotaTracker, quota.ProviderHuggingFace)
 
 	// Initialize HaikuService
-	haikuSvc := services.NewHaikuService(txMgr, haikuRepo, textProcessor, twitterPlatform)
+	safetyFilter, err := safety.FromConfig(cfg.Safety)
+	if err != nil {
+		slog.Error("Failed to set up the safety filter", "error", err)
+		os.Exit(1)
+	}
+	// Posts are haikued by score, highest first, with queued work aging.
+	scorer := scoring.FromConfig(cfg.Scoring)
+	freshnessPolicy, err := freshness.FromConfig(cfg.Freshness, nil)
+	if err != nil {
+		slog.Error("Failed to set up the freshness policy", "error", err)
+		os.Exit(1)
+	}
+	// With review mode on, haikus wait for approval unless they pass the
+	// configured auto-approval rules, which check form by language.
+	reviewPolicy := review.FromConfig(cfg.Review, languagePolicy.Forms())
+	exp, err := experiment.FromConfig(cfg.Experiment)
+	if err != nil {
+		slog.Error("Failed to set up the experiment", "error", err)
+		os.Exit(1)
+	}
+	haikuSvc := services.NewHaikuService(txMgr, haikuRepo, outboxRepo, authorRepo, textProcessor, notifier, reviewPolicy, safetyFilter, scorer, freshnessPolicy, languagePolicy, exp)
 
 	// Initialize PostService
-	postSvc := services.NewPostService(postRepo, twitterPlatform)
+	postSvc := services.NewPostService(postRepo, haikuRepo, authorRepo, twitterPlatform, notifier, scorer, nil)
+
+	// Queued publications are delivered to the platform by the outbox dispatcher.
+	dispatcher := services.NewOutboxDispatcher(txMgr, outboxRepo, haikuRepo, authorRepo, twitterPlatform, notifier, cfg.Outbox.MaxAttempts, cfg.Outbox.RetryBackoff, nil)
 
 	// // Create and start the scheduler for all service functions.
-	// sched := scheduler.NewScheduler(haikuSvc, postSvc)
+	// sched := scheduler.NewScheduler(haikuSvc, postSvc, authorSvc, dispatcher, quotaTracker, cfg.Schedule)
 	// sched.Start(rootCtx)
 
 	// // Optionally, run indefinitely.
 	// select {}
+	// Haikus left claimed by a stage that never finished are retried.
+	if released, err := haikuSvc.ReleaseStale(rootCtx, cfg.Shutdown.StaleClaimAfter); err != nil {
+		slog.Error("Failed to release stale haikus", "error", err)
+	} else if released > 0 {
+		slog.Warn("Released haikus left claimed by an interrupted stage", "count", released)
+	}
+
 	for {
-		scheduler.DryRunScheduler(rootCtx, haikuSvc, postSvc)
+		scheduler.DryRunScheduler(rootCtx, haikuSvc, postSvc, dispatcher)
 		time.Sleep(1 * time.Second)
 	}
 }
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/dapplux/twitter-haiku-bot/config"
+)
+
+// configCommand implements "haiku-bot config print": it prints the effective
+// configuration, with secrets redacted by config.Secret, followed by any validation errors.
+func configCommand(args []string) {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.AutoSource(parseConfigFlags("config print", args[1:])).Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode config: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(string(out))
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "\ninvalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+}
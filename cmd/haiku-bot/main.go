@@ -1,66 +1,246 @@
 package main
 
 import (
-	"context"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
+	"regexp"
 
+	"github.com/dapplux/twitter-haiku-bot/admin"
 	"github.com/dapplux/twitter-haiku-bot/config"
+	"github.com/dapplux/twitter-haiku-bot/dashboard"
+	"github.com/dapplux/twitter-haiku-bot/experiment"
+	"github.com/dapplux/twitter-haiku-bot/freshness"
+	"github.com/dapplux/twitter-haiku-bot/health"
 	"github.com/dapplux/twitter-haiku-bot/infrastructure/ai"
 	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/postgres"
 	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/httpserver"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/logging"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/metrics"
 	"github.com/dapplux/twitter-haiku-bot/infrastructure/platforms"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/quota"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/tracing"
+	"github.com/dapplux/twitter-haiku-bot/language"
+	"github.com/dapplux/twitter-haiku-bot/lifecycle"
+	"github.com/dapplux/twitter-haiku-bot/review"
+	"github.com/dapplux/twitter-haiku-bot/safety"
 	"github.com/dapplux/twitter-haiku-bot/scheduler"
+	"github.com/dapplux/twitter-haiku-bot/scoring"
 	"github.com/dapplux/twitter-haiku-bot/services"
 )
 
+const usage = `Usage:
+  haiku-bot [run] [-config file] [-set key=value ...]
+  haiku-bot config print [-config file] [-set key=value ...]
+`
+
 func main() {
-	rootCtx, rootCancel := context.WithCancel(context.Background())
-	defer rootCancel()
+	args := os.Args[1:]
+	command := "run"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "run":
+		run(args)
+	case "config":
+		configCommand(args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
 
-	cfg, aErr := config.AutoLoad()
+// parseConfigFlags parses the shared -config/-set flags of a subcommand.
+func parseConfigFlags(name string, args []string) *config.FlagSource {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	flags := &config.FlagSource{}
+	flags.Register(fs)
+	_ = fs.Parse(args)
+
+	return flags
+}
+
+func run(args []string) {
+	cfg, aErr := config.AutoLoadWithFlags(parseConfigFlags("run", args))
 	if aErr != nil {
-		log.Fatalln(aErr)
+		fmt.Fprintln(os.Stderr, aErr)
+		os.Exit(1)
+	}
+	// Structured logs from here on, with configured secrets scrubbed.
+	if err := logging.Setup(cfg.Log, os.Stderr, cfg.Secrets()...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
-		return
+	// The lifecycle manager owns the root context and tears everything down on SIGINT/SIGTERM.
+	app := lifecycle.New(cfg.Shutdown.Timeout)
+	rootCtx := app.Context()
+
+	// Registered first so spans from every other shutdown hook are flushed.
+	shutdownTracing, err := tracing.Setup(rootCtx, cfg.Tracing, cfg.Secrets()...)
+	if err != nil {
+		fatal("Failed to set up tracing", err)
 	}
+	app.OnShutdown("tracing", shutdownTracing)
+
 	db, err := postgres.New(cfg, "up", 0)
 	if err != nil {
-		log.Fatalf("failed to connect to database: %s", err.Error())
+		fatal("Failed to connect to database", err)
 	}
+	app.OnShutdown("database", db.Close)
 
 	// Create repositories
 	haikuRepo := repositories.NewHaikuRepository(db.DB)
 	postRepo := repositories.NewPostRepository(db.DB)
+	quotaRepo := repositories.NewQuotaRepository(db.DB)
+	outboxRepo := repositories.NewOutboxRepository(db.DB)
+	authorRepo := repositories.NewAuthorListRepository(db.DB)
+	engagementRepo := repositories.NewEngagementRepository(db.DB)
 
 	// Create a UnitOfWork (or transaction manager) from the base DB
 	// (Assume you have an implementation that wraps db.DB for transactions.)
 	txMgr := repositories.NewUnitOfWork(db.DB)
+	notifier := repositories.NewNotifier(db.DB)
 
 	// Initialize Platform Providers.
 	// For example, TwitterPlatform and TelegramPlatform.
 	// For this example, assume you have a TwitterPlatform and a TelegramPlatform.
 	//twitterPlatform := platforms.NewTwitterMock()
-	fmt.Println(cfg.Twitter.APIKey, cfg.Twitter.APISecret, cfg.Twitter.APIAccessToken, cfg.Twitter.APIAccessTokenSecret)
-	twitterPlatform := platforms.NewTwitterProvider(cfg.Twitter.APIKey, cfg.Twitter.APISecret, cfg.Twitter.APIAccessToken, cfg.Twitter.APIAccessTokenSecret)
+	// Every platform and AI call is recorded against the configured quota budgets.
+	quotaTracker := quota.NewTracker(quotaRepo, quota.BudgetsFromConfig(cfg.Quota), nil)
+	// Posts are haikued in their own language, translated or skipped.
+	languagePolicy, err := language.FromConfig(cfg.Languages)
+	if err != nil {
+		fatal("Failed to set up the language policy", err)
+	}
+	var platform platforms.PlatformProvider = platforms.NewTwitterMock()
+	if cfg.Twitter.Enabled {
+		twitter := platforms.NewTwitterProvider(
+			cfg.Twitter.APIKey.Reveal(),
+			cfg.Twitter.APISecret.Reveal(),
+			cfg.Twitter.APIAccessToken.Reveal(),
+			cfg.Twitter.APIAccessTokenSecret.Reveal(),
+		)
+		// Only posts in languages that are not skipped are searched for.
+		twitter.Query = platforms.TwitterSearchQueryFor(languagePolicy.SearchLanguages())
+		platform = twitter
+	}
+	twitterPlatform := platforms.NewMeteredProvider(platform, quotaTracker, quota.ProviderTwitter)
 
 	// Initialize TextProcessor (for instance, a HuggingFace processor)
-	textProcessor := ai.NewHuggingFaceProvider(cfg.HuggingFace.APIKey)
+	textProcessor := ai.NewMeteredProcessor(ai.NewHuggingFaceProvider(cfg.HuggingFace.APIKey.Reveal()), quotaTracker, quota.ProviderHuggingFace)
 
 	// Initialize HaikuService
-	haikuSvc := services.NewHaikuService(txMgr, haikuRepo, textProcessor, twitterPlatform)
+	safetyFilter, err := safety.FromConfig(cfg.Safety)
+	if err != nil {
+		fatal("Failed to set up the safety filter", err)
+	}
+	// Posts are haikued by score, highest first, with queued work aging.
+	scorer := scoring.FromConfig(cfg.Scoring)
+	// Haikus for posts past their platform's maximum age are skipped.
+	freshnessPolicy, err := freshness.FromConfig(cfg.Freshness, nil)
+	if err != nil {
+		fatal("Failed to set up the freshness policy", err)
+	}
+	// With review mode on, haikus wait for approval unless they pass the
+	// configured auto-approval rules, which check form by language.
+	reviewPolicy := review.FromConfig(cfg.Review, languagePolicy.Forms())
+	// Haikus are split between the arms of the running experiment, if any.
+	exp, err := experiment.FromConfig(cfg.Experiment)
+	if err != nil {
+		fatal("Failed to set up the experiment", err)
+	}
+	haikuSvc := services.NewHaikuService(txMgr, haikuRepo, outboxRepo, authorRepo, textProcessor, notifier, reviewPolicy, safetyFilter, scorer, freshnessPolicy, languagePolicy, exp)
 
 	// Initialize PostService
-	postSvc := services.NewPostService(postRepo, twitterPlatform)
+	postSvc := services.NewPostService(postRepo, haikuRepo, authorRepo, twitterPlatform, notifier, scorer, nil)
+
+	// AuthorService maintains the blocklist and opts out authors replying "stop".
+	cursorRepo := repositories.NewCursorRepository(db.DB)
+	authorSvc := services.NewAuthorService(txMgr, authorRepo, outboxRepo, cursorRepo, twitterPlatform)
+
+	// MentionService haikus posts users ask for by mentioning the bot.
+	var mentionSvc *services.MentionService
+	if cfg.Mentions.Enabled {
+		mentionSvc = services.NewMentionService(haikuSvc, haikuRepo, postRepo, authorRepo, outboxRepo, cursorRepo, twitterPlatform, services.MentionPolicy{
+			Trigger:       regexp.MustCompile(cfg.Mentions.Trigger),
+			PerUserLimit:  cfg.Mentions.PerUserLimit,
+			PerUserWindow: cfg.Mentions.PerUserWindow,
+			Priority:      cfg.Mentions.Priority,
+		}, nil)
+	}
+
+	// EngagementService samples how published haikus are received.
+	engagementSvc := services.NewEngagementService(haikuRepo, engagementRepo, twitterPlatform, cfg.Engagement.TrackFor, nil)
+
+	// Queued publications are delivered to the platform by the outbox dispatcher.
+	dispatcher := services.NewOutboxDispatcher(txMgr, outboxRepo, haikuRepo, authorRepo, twitterPlatform, notifier, cfg.Outbox.MaxAttempts, cfg.Outbox.RetryBackoff, nil)
 
 	// Create and start the scheduler for all service functions.
-	sched := scheduler.NewScheduler(haikuSvc, postSvc)
+	// Singleton jobs only run on the replica holding the advisory lock;
+	// the others stay hot standbys.
+	var leader scheduler.LeaderElector
+	if cfg.Leader.Enabled {
+		elector, err := postgres.NewElector(db.DB, scheduler.SingletonJobs(), cfg.Leader.Mode == "per_job", cfg.Leader.RenewInterval)
+		if err != nil {
+			fatal("Failed to set up leader election", err)
+		}
+		go elector.Run(rootCtx)
+		app.OnShutdown("leader election", elector.Release)
+		leader = elector
+	}
+
+	sched := scheduler.NewScheduler(haikuSvc, postSvc, authorSvc, mentionSvc, engagementSvc, dispatcher, quotaTracker, cfg.Schedule, leader)
+
+	// Expose metrics before the scheduler starts so the first runs are observed.
+	// Registered after the database, the server is stopped before it closes.
+	// /readyz reports not ready until the scheduler has started.
+	if cfg.HTTP.Enabled {
+		metrics.NewPipelineCollector(haikuRepo, outboxRepo, quotaTracker).Register()
+
+		server := httpserver.New(cfg.HTTP.Addr)
+		server.Handle("/metrics", metrics.Handler())
+		health.New(db, sched, haikuRepo, quotaTracker, cfg.Health.MaxJobFailures).Register(server)
+		if cfg.Admin.Enabled {
+			admin.New(haikuSvc, authorSvc, haikuRepo, postRepo, outboxRepo, authorRepo, engagementRepo, cfg.Admin.Token).Register(server)
+		}
+		if cfg.Dashboard.Enabled {
+			dashboard.New(haikuSvc, haikuRepo, outboxRepo, cfg.Dashboard.Password).Register(server)
+		}
+		if err := server.Start(); err != nil {
+			fatal("Failed to start HTTP server", err)
+		}
+		app.OnShutdown("http server", server.Shutdown)
+	}
+
+	// Haikus left claimed by a stage that never finished are retried.
+	if released, err := haikuSvc.ReleaseStale(rootCtx, cfg.Shutdown.StaleClaimAfter); err != nil {
+		slog.Error("Failed to release stale haikus", "error", err)
+	} else if released > 0 {
+		slog.Warn("Released haikus left claimed by an interrupted stage", "count", released)
+	}
 	sched.Start(rootCtx)
+	app.OnShutdown("scheduler", sched.Stop)
+
+	// Pick up new work as soon as a stage announces it; cron polling stays as the safety net.
+	if cfg.Events.Enabled {
+		listener := postgres.NewListener(cfg, scheduler.EventChannels()...)
+		go listener.Listen(rootCtx, sched.HandleNotification)
+	}
+
+	// Run until a signal arrives, then stop the scheduler before closing the database.
+	if err := app.Wait(); err != nil {
+		fatal("Unclean shutdown", err)
+	}
+	slog.Info("Shutdown complete")
+}
 
-	// Optionally, run indefinitely.
-	select {}
-	// for {
-	// 	scheduler.DryRunScheduler(rootCtx, haikuSvc, postSvc)
-	// 	time.Sleep(1 * time.Second)
-	// }
+// fatal logs msg with err and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
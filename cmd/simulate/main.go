@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/config"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/logging"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/quota"
+	"github.com/dapplux/twitter-haiku-bot/simulation"
+)
+
+func main() {
+	days := flag.Int("days", 30, "number of days to simulate")
+	start := flag.String("start", "", "simulation start time in RFC3339 (default: today at 00:00 UTC)")
+	seed := flag.Int64("seed", 1, "random seed for the fake backends")
+	platformFailureRate := flag.Float64("platform-failure-rate", 0.01, "probability (0..1) of a platform call failing")
+	aiFailureRate := flag.Float64("ai-failure-rate", 0.02, "probability (0..1) of an AI call failing")
+	verbose := flag.Bool("v", false, "print job logs while simulating")
+	configFlags := &config.FlagSource{}
+	configFlags.Register(flag.CommandLine)
+	flag.Parse()
+
+	startTime := time.Now().UTC().Truncate(24 * time.Hour)
+	if *start != "" {
+		t, err := time.Parse(time.RFC3339, *start)
+		if err != nil {
+			fatal("Invalid -start", err)
+		}
+		startTime = t
+	}
+
+	// Budgets and schedules come from the same configuration as the real bot.
+	// Credentials are never used, so only the parts under test are validated.
+	cfg, err := config.AutoSource(configFlags).Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := errors.Join(cfg.Quota.Validate(), cfg.Schedule.Validate(), cfg.Outbox.Validate(), cfg.Log.Validate()); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+
+	// Job logs are noisy over weeks of virtual time; keep them opt-in.
+	if *verbose {
+		if err := logging.Setup(cfg.Log, os.Stderr); err != nil {
+			fatal("Invalid log configuration", err)
+		}
+	} else {
+		slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}
+
+	report, err := simulation.Run(context.Background(), simulation.Options{
+		Start:               startTime,
+		Days:                *days,
+		Seed:                *seed,
+		PlatformFailureRate: *platformFailureRate,
+		AIFailureRate:       *aiFailureRate,
+		Budgets:             quota.BudgetsFromConfig(cfg.Quota),
+		Schedule:            cfg.Schedule,
+		OutboxMaxAttempts:   cfg.Outbox.MaxAttempts,
+		OutboxRetryBackoff:  cfg.Outbox.RetryBackoff,
+		Review:              cfg.Review,
+		Safety:              cfg.Safety,
+		Mentions:            cfg.Mentions,
+		Scoring:             cfg.Scoring,
+		Freshness:           cfg.Freshness,
+		Engagement:          cfg.Engagement,
+		Experiment:          cfg.Experiment,
+		Languages:           cfg.Languages,
+	})
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	if err != nil {
+		fatal("Simulation failed", err)
+	}
+
+	if err := report.Print(os.Stdout); err != nil {
+		fatal("Failed to print report", err)
+	}
+}
+
+// fatal logs msg with err and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -7,19 +7,36 @@ import (
 	"github.com/kelseyhightower/envconfig"
 )
 
+// loadDotEnv exports variables from the optional .env files without
+// overriding ones already set in the environment.
+func loadDotEnv() {
+	_ = godotenv.Load("secrets/.env")
+	_ = godotenv.Load(".env")
+}
+
 type EnvSource struct {
 	Prefix string
 }
 
 func (es EnvSource) Load() (Config, error) {
-	_ = godotenv.Load("secrets/.env")
-	_ = godotenv.Load(".env")
+	c := Default()
+	if err := es.Apply(&c); err != nil {
+		return c, err
+	}
+
+	return c, nil
+}
+
+// Apply overrides fields of c that have an environment variable set and
+// leaves every other field untouched. Secrets may also be read from the file
+// named by the variable with a _FILE suffix, e.g. DB_PASSWORD_FILE.
+func (es EnvSource) Apply(c *Config) error {
+	loadDotEnv()
 
-	var c Config
-	pErr := envconfig.Process(es.Prefix, &c)
+	pErr := envconfig.Process(es.Prefix, c)
 	if pErr != nil {
-		return c, fmt.Errorf("envconfig.Process return error: %v", pErr)
+		return fmt.Errorf("envconfig.Process return error: %v", pErr)
 	}
 
-	return c, nil
+	return applySecretFiles(c, es.Prefix)
 }
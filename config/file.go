@@ -0,0 +1,42 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileSource reads configuration from a YAML file. Keys mirror the yaml tags
+// on Config, e.g. db.port or schedule.post_haiku.spec. Unknown keys are rejected.
+type FileSource struct {
+	Path string
+}
+
+func (fs FileSource) Load() (Config, error) {
+	c := Default()
+	if err := fs.Apply(&c); err != nil {
+		return c, err
+	}
+
+	return c, nil
+}
+
+// Apply overrides the fields present in the file.
+func (fs FileSource) Apply(c *Config) error {
+	data, err := os.ReadFile(fs.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %v", fs.Path, err)
+	}
+
+	return nil
+}
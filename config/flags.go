@@ -0,0 +1,86 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FlagSource holds command-line configuration: the config file path and
+// key=value overrides applied after every other layer.
+type FlagSource struct {
+	File      string
+	Overrides []string
+}
+
+// Register adds -config and -set to fs.
+func (f *FlagSource) Register(fs *flag.FlagSet) {
+	fs.StringVar(&f.File, "config", "", "path to a YAML config file (overrides CONFIG_FILE)")
+	fs.Func("set", "override a config key, e.g. -set schedule.post_haiku.spec='0 0 */2 * * *' (repeatable)", func(s string) error {
+		f.Overrides = append(f.Overrides, s)
+		return nil
+	})
+}
+
+// Apply sets each key=value override. Values are parsed as YAML scalars, so
+// numbers, booleans and durations work as in the config file.
+func (f *FlagSource) Apply(c *Config) error {
+	var errs []error
+	for _, o := range f.Overrides {
+		key, value, ok := strings.Cut(o, "=")
+		if !ok {
+			errs = append(errs, fmt.Errorf("-set %q: expected key=value", o))
+			continue
+		}
+
+		if err := setKey(c, strings.Split(key, "."), value); err != nil {
+			errs = append(errs, fmt.Errorf("-set %s: %v", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setKey decodes value into the field addressed by path. The value is decoded
+// from an untagged YAML node so it resolves exactly like a plain file scalar.
+func setKey(c *Config, path []string, value string) error {
+	if !hasYAMLPath(reflect.TypeOf(*c), path) {
+		return errors.New("unknown config key")
+	}
+
+	node := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	for i := len(path) - 1; i >= 0; i-- {
+		node = &yaml.Node{
+			Kind:    yaml.MappingNode,
+			Content: []*yaml.Node{{Kind: yaml.ScalarNode, Value: path[i]}, node},
+		}
+	}
+
+	return node.Decode(c)
+}
+
+// hasYAMLPath reports whether path names a leaf field through yaml tags, or
+// a key of a map field.
+func hasYAMLPath(t reflect.Type, path []string) bool {
+	if len(path) == 0 {
+		return t.Kind() != reflect.Struct
+	}
+	if t.Kind() == reflect.Map {
+		return len(path) == 1
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == path[0] {
+			return hasYAMLPath(field.Type, path[1:])
+		}
+	}
+	return false
+}
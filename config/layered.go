@@ -0,0 +1,34 @@
+package config
+
+import "sort"
+
+// Layer applies one configuration source on top of an existing Config.
+type Layer interface {
+	Apply(c *Config) error
+}
+
+// LayeredSource starts from Default and applies each layer in order, so later
+// layers override earlier ones.
+type LayeredSource struct {
+	Layers []Layer
+}
+
+func (ls LayeredSource) Load() (Config, error) {
+	c := Default()
+	for _, layer := range ls.Layers {
+		if err := layer.Apply(&c); err != nil {
+			return c, err
+		}
+	}
+
+	return c, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
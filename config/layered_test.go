@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// unsetEnv clears key for the rest of the test, restoring it afterwards.
+func unsetEnv(t *testing.T, key string) {
+	t.Helper()
+	t.Setenv(key, "")
+	os.Unsetenv(key)
+}
+
+func TestLayeredPrecedence(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		env       map[string]string
+		overrides []string
+		wantHost  string
+		wantReads int64
+	}{
+		{"defaults", "", nil, nil, "localhost", 100},
+		{"file over defaults", "db:\n  host: file-db\n", nil, nil, "file-db", 100},
+		{"env over file", "db:\n  host: file-db\n", map[string]string{"DB_HOST": "env-db"}, nil, "env-db", 100},
+		{"flag over env", "db:\n  host: file-db\n", map[string]string{"DB_HOST": "env-db"}, []string{"db.host=flag-db"}, "flag-db", 100},
+		{"layers override only what they set", "quota:\n  twitter_reads_monthly: 50\n", map[string]string{"DB_HOST": "env-db"}, nil, "env-db", 50},
+		{"flag values parse like the file", "", nil, []string{"quota.twitter_reads_monthly=75"}, "localhost", 75},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"DB_HOST", "QUOTA_TWITTER_READS_MONTHLY"} {
+				unsetEnv(t, key)
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			var layers []Layer
+			if tt.file != "" {
+				path := filepath.Join(t.TempDir(), "config.yaml")
+				if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				layers = append(layers, FileSource{Path: path})
+			}
+			layers = append(layers, EnvSource{}, &FlagSource{Overrides: tt.overrides})
+
+			c, err := LayeredSource{Layers: layers}.Load()
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if c.DB.Host != tt.wantHost {
+				t.Errorf("db.host = %q, want %q", c.DB.Host, tt.wantHost)
+			}
+			if c.Quota.TwitterReadsMonthly != tt.wantReads {
+				t.Errorf("quota.twitter_reads_monthly = %d, want %d", c.Quota.TwitterReadsMonthly, tt.wantReads)
+			}
+		})
+	}
+}
+
+func TestLayeredRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		overrides []string
+	}{
+		{"unknown file key", "db:\n  hostname: db\n", nil},
+		{"unknown flag key", "", []string{"db.hostname=db"}},
+		{"flag without value", "", []string{"db.host"}},
+		{"flag of the wrong type", "", []string{"db.port=many"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var layers []Layer
+			if tt.file != "" {
+				path := filepath.Join(t.TempDir(), "config.yaml")
+				if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				layers = append(layers, FileSource{Path: path})
+			}
+			layers = append(layers, &FlagSource{Overrides: tt.overrides})
+
+			if _, err := (LayeredSource{Layers: layers}).Load(); err == nil {
+				t.Error("Load() succeeded, want an error")
+			}
+		})
+	}
+}
@@ -1,30 +1,647 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
 type DB struct {
-	Host     string
-	Port     int
-	User     string
-	Password string
-	Name     string
-	SSLMode  string `split_words:"true"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password Secret `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"ssl_mode" split_words:"true"`
 }
 
 type Twitter struct {
-	APIBearer            string `split_words:"true"`
-	APIKey               string `split_words:"true"`
-	APISecret            string `split_words:"true"`
-	APIAccessToken       string `split_words:"true"`
-	APIAccessTokenSecret string `split_words:"true"`
+	// Enabled selects the real Twitter API; its credentials are only required when set.
+	Enabled              bool   `yaml:"enabled"`
+	APIBearer            Secret `yaml:"api_bearer" split_words:"true"`
+	APIKey               Secret `yaml:"api_key" split_words:"true"`
+	APISecret            Secret `yaml:"api_secret" split_words:"true"`
+	APIAccessToken       Secret `yaml:"api_access_token" split_words:"true"`
+	APIAccessTokenSecret Secret `yaml:"api_access_token_secret" split_words:"true"`
 }
 
 type HuggingFace struct {
-	APIKey string `split_words:"true"`
+	APIKey Secret `yaml:"api_key" split_words:"true"`
+}
+
+// Quota holds API consumption budgets. A zero value disables the budget.
+type Quota struct {
+	TwitterReadsMonthly      int64 `yaml:"twitter_reads_monthly" split_words:"true"`
+	TwitterWritesMonthly     int64 `yaml:"twitter_writes_monthly" split_words:"true"`
+	TwitterWritesDaily       int64 `yaml:"twitter_writes_daily" split_words:"true"`
+	HuggingFaceRequestsDaily int64 `yaml:"hugging_face_requests_daily" split_words:"true"`
+}
+
+// Shutdown bounds how long the bot waits for running jobs when stopping.
+type Shutdown struct {
+	Timeout time.Duration `yaml:"timeout"`
+	// StaleClaimAfter is how long a haiku may sit in a *_getting state before
+	// startup assumes its stage was interrupted and returns it to be retried.
+	StaleClaimAfter time.Duration `yaml:"stale_claim_after" split_words:"true"`
+}
+
+// Leader configures leader election between replicas.
+type Leader struct {
+	Enabled bool `yaml:"enabled"`
+	// Mode is "global" (one leader runs every singleton job) or "per_job"
+	// (each singleton job has its own lock).
+	Mode string `yaml:"mode"`
+	// RenewInterval is how often leadership is checked and standbys retry,
+	// which bounds how quickly a standby takes over.
+	RenewInterval time.Duration `yaml:"renew_interval" split_words:"true"`
+}
+
+// Events configures the LISTEN/NOTIFY pipeline triggers.
+type Events struct {
+	// Enabled starts stages as soon as work is announced instead of waiting
+	// for the next polling tick.
+	Enabled bool `yaml:"enabled"`
+}
+
+// Outbox configures delivery of queued publications.
+type Outbox struct {
+	// MaxAttempts is how many times delivery is tried before the entry and its haiku fail.
+	MaxAttempts int `yaml:"max_attempts" split_words:"true"`
+	// RetryBackoff is the delay after the first failed attempt; it doubles with
+	// every further failure, up to six hours.
+	RetryBackoff time.Duration `yaml:"retry_backoff" split_words:"true"`
+}
+
+// HTTP configures the server exposing /metrics and the health endpoints.
+type HTTP struct {
+	Enabled bool `yaml:"enabled"`
+	// Addr is the listen address, e.g. ":8080".
+	Addr string `yaml:"addr"`
+}
+
+// Health configures the readiness check.
+type Health struct {
+	// MaxJobFailures is how many consecutive failed runs of a job make the
+	// replica report itself not ready.
+	MaxJobFailures int `yaml:"max_job_failures" split_words:"true"`
+}
+
+// Review configures human review of generated haikus before publishing.
+type Review struct {
+	// Enabled holds generated haikus in pending_review until approved.
+	Enabled bool `yaml:"enabled"`
+	// AutoApproveForm approves haikus in valid 5-7-5 form without review.
+	// Every enabled auto-approval rule must pass; with none enabled every
+	// haiku waits for a reviewer.
+	AutoApproveForm bool `yaml:"auto_approve_form" split_words:"true"`
+	// AutoApproveMaxSafetyScore approves only haikus whose safety score is at
+	// most this value; 0 turns the rule off. Requires safety.enabled.
+	AutoApproveMaxSafetyScore float64 `yaml:"auto_approve_max_safety_score" split_words:"true"`
+}
+
+// Safety configures screening of posts and haikus for unsafe content.
+type Safety struct {
+	// Enabled filters posts and haikus scored at least Threshold.
+	Enabled bool `yaml:"enabled"`
+	// Threshold is the safety score, between 0 and 1, at which text is filtered.
+	Threshold float64 `yaml:"threshold"`
+	// WordlistFile adds rules to the built-in wordlist, one
+	// "<category> <score> <pattern>" per line.
+	WordlistFile string `yaml:"wordlist_file" split_words:"true"`
+}
+
+// Mentions configures haikus requested by mentioning the bot in a reply to
+// a post.
+type Mentions struct {
+	// Enabled haikus the post a matching mention replies to and publishes the
+	// haiku in the mention's thread.
+	Enabled bool `yaml:"enabled"`
+	// Trigger is a regular expression the mention's text must match.
+	Trigger string `yaml:"trigger"`
+	// PerUserLimit is how many haikus one author may request per PerUserWindow.
+	PerUserLimit  int           `yaml:"per_user_limit" split_words:"true"`
+	PerUserWindow time.Duration `yaml:"per_user_window" split_words:"true"`
+	// Priority is added to the score of requested posts so their haikus
+	// overtake those of fetched posts.
+	Priority float64 `yaml:"priority"`
+}
+
+// Scoring configures the priority fetched posts are haikued in. A post
+// scores its weighted engagement plus the weights of its topics, halved for
+// every FreshnessHalfLife of its age and divided by one plus
+// DiversityPenalty for every post of its author kept in DiversityWindow.
+type Scoring struct {
+	// Enabled scores posts; otherwise every post and haiku is taken in the
+	// order it arrived.
+	Enabled bool `yaml:"enabled"`
+	// EngagementWeight scales ln(1 + likes + 2*shares + replies).
+	EngagementWeight  float64       `yaml:"engagement_weight" split_words:"true"`
+	FreshnessHalfLife time.Duration `yaml:"freshness_half_life" split_words:"true"`
+	DiversityPenalty  float64       `yaml:"diversity_penalty" split_words:"true"`
+	DiversityWindow   time.Duration `yaml:"diversity_window" split_words:"true"`
+	// Topics maps keywords, matched as whole words regardless of case, to
+	// the weight a post mentioning them gains; negative weights demote.
+	Topics map[string]float64 `yaml:"topics"`
+	// AgingPerHour is added to the priority of queued posts and haikus for
+	// every hour they wait, so low scores are not starved forever.
+	AgingPerHour float64 `yaml:"aging_per_hour" split_words:"true"`
+}
+
+// Freshness configures how old a post may be for the bot to still reply.
+type Freshness struct {
+	// MaxAge maps platforms to the age at which their posts' haikus are
+	// skipped; platforms not listed have no limit.
+	MaxAge map[string]time.Duration `yaml:"max_age" split_words:"true"`
+}
+
+// Engagement configures how the metrics of published haikus are tracked.
+type Engagement struct {
+	// TrackFor is how long after publishing a haiku's metrics are sampled.
+	TrackFor time.Duration `yaml:"track_for" split_words:"true"`
+}
+
+// languageCode matches ISO 639-1 language codes.
+var languageCode = regexp.MustCompile(`^[a-z]{2}$`)
+
+// Languages configures how posts are haikued by the language they are
+// written in, given as ISO 639-1 codes.
+type Languages struct {
+	// Pipelines maps languages to how their posts are haikued: "native" in
+	// their own language, "translate" into English first, or "skip".
+	Pipelines map[string]string `yaml:"pipelines"`
+	// Unsupported is the pipeline of posts in any other language or whose
+	// language is unknown: "translate" or "skip".
+	Unsupported string `yaml:"unsupported"`
+	// ReadingsFile adds kanji readings to the built-in dictionary Japanese
+	// haikus are counted with, one "<kanji> <kana>" per line.
+	ReadingsFile string `yaml:"readings_file" split_words:"true"`
+}
+
+// Experiment configures an A/B experiment on how haiku text is generated.
+// Every haiku is assigned to one arm by hashing its ID with the experiment's
+// name, so an arm keeps its haikus across restarts and retries.
+type Experiment struct {
+	Enabled bool `yaml:"enabled"`
+	// Name tells experiments apart in the engagement report; renaming an
+	// experiment reshuffles its haikus between arms.
+	Name string `yaml:"name"`
+	// Arms maps arm names to their settings. They can only be set in YAML.
+	Arms map[string]Arm `yaml:"arms" ignored:"true"`
+}
+
+// Arm is one combination of generation settings under test.
+type Arm struct {
+	// Weight is the arm's share of the traffic, relative to the other arms.
+	Weight int `yaml:"weight"`
+	// Prompt and Model override the processor's prompt template and model;
+	// empty keeps its default.
+	Prompt string `yaml:"prompt"`
+	Model  string `yaml:"model"`
+	// Temperature is the sampling temperature; zero keeps the model's default.
+	Temperature float64 `yaml:"temperature"`
+	// Candidates is how many texts are generated per haiku, of which the
+	// first in 5-7-5 form is kept; zero means one.
+	Candidates int `yaml:"candidates"`
+}
+
+// Admin configures the admin API served under /admin/ by the HTTP server.
+type Admin struct {
+	Enabled bool `yaml:"enabled"`
+	// Token must be sent as "Authorization: Bearer <token>" with every request.
+	Token Secret `yaml:"token"`
+}
+
+// Dashboard configures the operations web UI served under /dashboard/ by the
+// HTTP server.
+type Dashboard struct {
+	Enabled bool `yaml:"enabled"`
+	// Password signs operators in with HTTP basic auth; the user name they
+	// give is recorded with their changes.
+	Password Secret `yaml:"password"`
+}
+
+// Log configures structured logging.
+type Log struct {
+	// Format is "text" or "json".
+	Format string `yaml:"format"`
+	// Level is the minimum level logged: debug, info, warn or error.
+	Level string `yaml:"level"`
+}
+
+// Tracing configures OpenTelemetry trace export.
+type Tracing struct {
+	// Exporter is "none", "stdout" or "otlp" (OTLP over HTTP).
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the collector's host:port for the otlp exporter. When empty
+	// the standard OTEL_EXPORTER_OTLP_* variables apply.
+	Endpoint string `yaml:"endpoint"`
+	// Insecure sends OTLP over plain HTTP instead of HTTPS.
+	Insecure bool `yaml:"insecure"`
+	// SampleRatio is the fraction of new traces recorded, from 0 to 1.
+	SampleRatio float64 `yaml:"sample_ratio" split_words:"true"`
 }
 
 type Config struct {
-	DB          DB
-	Twitter     Twitter
-	HuggingFace HuggingFace
+	DB          DB          `yaml:"db"`
+	Twitter     Twitter     `yaml:"twitter"`
+	HuggingFace HuggingFace `yaml:"huggingface"`
+	Quota       Quota       `yaml:"quota"`
+	Schedule    Schedule    `yaml:"schedule"`
+	Shutdown    Shutdown    `yaml:"shutdown"`
+	Leader      Leader      `yaml:"leader"`
+	Events      Events      `yaml:"events"`
+	Outbox      Outbox      `yaml:"outbox"`
+	HTTP        HTTP        `yaml:"http"`
+	Health      Health      `yaml:"health"`
+	Admin       Admin       `yaml:"admin"`
+	Dashboard   Dashboard   `yaml:"dashboard"`
+	Review      Review      `yaml:"review"`
+	Safety      Safety      `yaml:"safety"`
+	Mentions    Mentions    `yaml:"mentions"`
+	Scoring     Scoring     `yaml:"scoring"`
+	Freshness   Freshness   `yaml:"freshness"`
+	Engagement  Engagement  `yaml:"engagement"`
+	Experiment  Experiment  `yaml:"experiment"`
+	Languages   Languages   `yaml:"languages"`
+	Log         Log         `yaml:"log"`
+	Tracing     Tracing     `yaml:"tracing"`
+}
+
+// Default returns the configuration every layer is applied on top of.
+func Default() Config {
+	return Config{
+		DB: DB{
+			Host:    "localhost",
+			Port:    5432,
+			User:    "postgres",
+			Name:    "haiku-bot-api",
+			SSLMode: "disable",
+		},
+		Twitter: Twitter{
+			Enabled: true,
+		},
+		Quota: Quota{
+			TwitterReadsMonthly:      100,
+			TwitterWritesMonthly:     500,
+			TwitterWritesDaily:       17,
+			HuggingFaceRequestsDaily: 1000,
+		},
+		Schedule: DefaultSchedule(),
+		Shutdown: Shutdown{
+			Timeout:         30 * time.Second,
+			StaleClaimAfter: 15 * time.Minute,
+		},
+		Leader: Leader{
+			Enabled:       true,
+			Mode:          "global",
+			RenewInterval: 5 * time.Second,
+		},
+		Events: Events{
+			Enabled: true,
+		},
+		Outbox: Outbox{
+			MaxAttempts:  5,
+			RetryBackoff: time.Minute,
+		},
+		HTTP: HTTP{
+			Enabled: true,
+			Addr:    ":8080",
+		},
+		Health: Health{
+			MaxJobFailures: 3,
+		},
+		Safety: Safety{
+			Enabled:   true,
+			Threshold: 0.5,
+		},
+		Mentions: Mentions{
+			Trigger:       `(?i)\bhaiku\b`,
+			PerUserLimit:  3,
+			PerUserWindow: 24 * time.Hour,
+			Priority:      10,
+		},
+		Scoring: Scoring{
+			Enabled:           true,
+			EngagementWeight:  1,
+			FreshnessHalfLife: 12 * time.Hour,
+			DiversityPenalty:  0.5,
+			DiversityWindow:   7 * 24 * time.Hour,
+			AgingPerHour:      0.1,
+		},
+		Freshness: Freshness{
+			MaxAge: map[string]time.Duration{"twitter": 72 * time.Hour},
+		},
+		Engagement: Engagement{
+			TrackFor: 72 * time.Hour,
+		},
+		Languages: Languages{
+			Pipelines:   map[string]string{"en": "native"},
+			Unsupported: "skip",
+		},
+		Log: Log{
+			Format: "text",
+			Level:  "info",
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Validate checks the configuration for problems that would only surface at
+// runtime and reports all of them at once.
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.DB.Host == "" {
+		errs = append(errs, errors.New("db.host is required"))
+	}
+	if err := validatePort(c.DB.Port); err != nil {
+		errs = append(errs, fmt.Errorf("db.port: %w", err))
+	}
+	if c.DB.Name == "" {
+		errs = append(errs, errors.New("db.name is required"))
+	}
+	switch c.DB.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		errs = append(errs, fmt.Errorf("db.ssl_mode: unsupported mode %q", c.DB.SSLMode))
+	}
+
+	if c.Twitter.Enabled {
+		required := map[string]Secret{
+			"twitter.api_key":                 c.Twitter.APIKey,
+			"twitter.api_secret":              c.Twitter.APISecret,
+			"twitter.api_access_token":        c.Twitter.APIAccessToken,
+			"twitter.api_access_token_secret": c.Twitter.APIAccessTokenSecret,
+		}
+		for _, key := range sortedKeys(required) {
+			if required[key] == "" {
+				errs = append(errs, fmt.Errorf("%s is required when twitter.enabled is true", key))
+			}
+		}
+	}
+
+	if c.HuggingFace.APIKey == "" {
+		errs = append(errs, errors.New("huggingface.api_key is required"))
+	}
+
+	if err := c.Quota.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Schedule.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Shutdown.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("shutdown.timeout must be positive, got %s", c.Shutdown.Timeout))
+	}
+	if c.Shutdown.StaleClaimAfter <= 0 {
+		errs = append(errs, fmt.Errorf("shutdown.stale_claim_after must be positive, got %s", c.Shutdown.StaleClaimAfter))
+	}
+	if c.Leader.Enabled {
+		if c.Leader.Mode != "global" && c.Leader.Mode != "per_job" {
+			errs = append(errs, fmt.Errorf("leader.mode must be global or per_job, got %q", c.Leader.Mode))
+		}
+		if c.Leader.RenewInterval <= 0 {
+			errs = append(errs, fmt.Errorf("leader.renew_interval must be positive, got %s", c.Leader.RenewInterval))
+		}
+	}
+	if err := c.Outbox.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.HTTP.Enabled && c.HTTP.Addr == "" {
+		errs = append(errs, errors.New("http.addr is required when http.enabled is true"))
+	}
+	if c.Admin.Enabled {
+		if !c.HTTP.Enabled {
+			errs = append(errs, errors.New("admin.enabled requires http.enabled"))
+		}
+		if c.Admin.Token == "" {
+			errs = append(errs, errors.New("admin.token is required when admin.enabled is true"))
+		}
+	}
+	if c.Safety.Threshold <= 0 || c.Safety.Threshold > 1 {
+		errs = append(errs, fmt.Errorf("safety.threshold must be in (0, 1], got %v", c.Safety.Threshold))
+	}
+	if c.Review.AutoApproveMaxSafetyScore < 0 || c.Review.AutoApproveMaxSafetyScore > 1 {
+		errs = append(errs, fmt.Errorf("review.auto_approve_max_safety_score must be in [0, 1], got %v", c.Review.AutoApproveMaxSafetyScore))
+	}
+	if c.Review.AutoApproveMaxSafetyScore > 0 && !c.Safety.Enabled {
+		errs = append(errs, errors.New("review.auto_approve_max_safety_score requires safety.enabled"))
+	}
+	if c.Mentions.Enabled {
+		if err := c.Mentions.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.Scoring.Enabled {
+		if err := c.Scoring.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, platform := range sortedKeys(c.Freshness.MaxAge) {
+		if age := c.Freshness.MaxAge[platform]; age <= 0 {
+			errs = append(errs, fmt.Errorf("freshness.max_age.%s must be positive, got %s", platform, age))
+		}
+	}
+	if c.Engagement.TrackFor <= 0 {
+		errs = append(errs, fmt.Errorf("engagement.track_for must be positive, got %s", c.Engagement.TrackFor))
+	}
+	if err := c.Languages.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Experiment.Enabled {
+		if err := c.Experiment.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.Dashboard.Enabled {
+		if !c.HTTP.Enabled {
+			errs = append(errs, errors.New("dashboard.enabled requires http.enabled"))
+		}
+		if c.Dashboard.Password == "" {
+			errs = append(errs, errors.New("dashboard.password is required when dashboard.enabled is true"))
+		}
+	}
+	if c.Health.MaxJobFailures <= 0 {
+		errs = append(errs, fmt.Errorf("health.max_job_failures must be positive, got %d", c.Health.MaxJobFailures))
+	}
+	if err := c.Log.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks that no budget is negative.
+func (q Quota) Validate() error {
+	budgets := map[string]int64{
+		"quota.twitter_reads_monthly":       q.TwitterReadsMonthly,
+		"quota.twitter_writes_monthly":      q.TwitterWritesMonthly,
+		"quota.twitter_writes_daily":        q.TwitterWritesDaily,
+		"quota.hugging_face_requests_daily": q.HuggingFaceRequestsDaily,
+	}
+
+	var errs []error
+	for _, key := range sortedKeys(budgets) {
+		if budgets[key] < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %d", key, budgets[key]))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// maxOutboxAttempts bounds outbox.max_attempts; with the backoff capped at six
+// hours more attempts would only keep a failing haiku around for weeks.
+const maxOutboxAttempts = 50
+
+// Validate checks the delivery settings.
+func (o Outbox) Validate() error {
+	var errs []error
+	if o.MaxAttempts <= 0 || o.MaxAttempts > maxOutboxAttempts {
+		errs = append(errs, fmt.Errorf("outbox.max_attempts must be between 1 and %d, got %d", maxOutboxAttempts, o.MaxAttempts))
+	}
+	if o.RetryBackoff <= 0 {
+		errs = append(errs, fmt.Errorf("outbox.retry_backoff must be positive, got %s", o.RetryBackoff))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the trigger and the per-user limit.
+func (m Mentions) Validate() error {
+	var errs []error
+	if _, err := regexp.Compile(m.Trigger); err != nil {
+		errs = append(errs, fmt.Errorf("mentions.trigger: %v", err))
+	}
+	if m.PerUserLimit <= 0 {
+		errs = append(errs, fmt.Errorf("mentions.per_user_limit must be positive, got %d", m.PerUserLimit))
+	}
+	if m.PerUserWindow <= 0 {
+		errs = append(errs, fmt.Errorf("mentions.per_user_window must be positive, got %s", m.PerUserWindow))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the language codes and pipelines.
+func (l Languages) Validate() error {
+	var errs []error
+	for _, lang := range sortedKeys(l.Pipelines) {
+		if !languageCode.MatchString(lang) {
+			errs = append(errs, fmt.Errorf("languages.pipelines: %q is not an ISO 639-1 code", lang))
+		}
+		switch l.Pipelines[lang] {
+		case "native", "translate", "skip":
+		default:
+			errs = append(errs, fmt.Errorf("languages.pipelines.%s must be native, translate or skip, got %q", lang, l.Pipelines[lang]))
+		}
+	}
+	switch l.Unsupported {
+	case "translate", "skip":
+	default:
+		errs = append(errs, fmt.Errorf("languages.unsupported must be translate or skip, got %q", l.Unsupported))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the name and that the arms share some traffic with
+// sensible settings.
+func (e Experiment) Validate() error {
+	var errs []error
+	if e.Name == "" || strings.Contains(e.Name, "/") {
+		errs = append(errs, fmt.Errorf("experiment.name must be non-empty and not contain /, got %q", e.Name))
+	}
+	if len(e.Arms) == 0 {
+		errs = append(errs, errors.New("experiment.arms must not be empty"))
+	}
+
+	total := 0
+	for _, name := range sortedKeys(e.Arms) {
+		arm := e.Arms[name]
+		if name == "" || strings.Contains(name, "/") {
+			errs = append(errs, fmt.Errorf("experiment.arms: arm name must be non-empty and not contain /, got %q", name))
+		}
+		if arm.Weight < 0 {
+			errs = append(errs, fmt.Errorf("experiment.arms.%s.weight must not be negative, got %d", name, arm.Weight))
+		}
+		if arm.Temperature < 0 {
+			errs = append(errs, fmt.Errorf("experiment.arms.%s.temperature must not be negative, got %v", name, arm.Temperature))
+		}
+		if arm.Candidates < 0 {
+			errs = append(errs, fmt.Errorf("experiment.arms.%s.candidates must not be negative, got %d", name, arm.Candidates))
+		}
+		total += arm.Weight
+	}
+	if len(e.Arms) > 0 && total == 0 {
+		errs = append(errs, errors.New("experiment.arms must have a positive total weight"))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks that no weight is negative and the durations are positive.
+func (s Scoring) Validate() error {
+	weights := map[string]float64{
+		"scoring.engagement_weight": s.EngagementWeight,
+		"scoring.diversity_penalty": s.DiversityPenalty,
+		"scoring.aging_per_hour":    s.AgingPerHour,
+	}
+
+	var errs []error
+	for _, key := range sortedKeys(weights) {
+		if weights[key] < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %v", key, weights[key]))
+		}
+	}
+	if s.FreshnessHalfLife <= 0 {
+		errs = append(errs, fmt.Errorf("scoring.freshness_half_life must be positive, got %s", s.FreshnessHalfLife))
+	}
+	if s.DiversityWindow <= 0 {
+		errs = append(errs, fmt.Errorf("scoring.diversity_window must be positive, got %s", s.DiversityWindow))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the log format and level.
+func (l Log) Validate() error {
+	var errs []error
+	switch l.Format {
+	case "text", "json":
+	default:
+		errs = append(errs, fmt.Errorf("log.format must be text or json, got %q", l.Format))
+	}
+	switch strings.ToLower(l.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level must be debug, info, warn or error, got %q", l.Level))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the exporter and sample ratio.
+func (t Tracing) Validate() error {
+	var errs []error
+	switch t.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter must be none, stdout or otlp, got %q", t.Exporter))
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing.sample_ratio must be between 0 and 1, got %g", t.SampleRatio))
+	}
+	return errors.Join(errs...)
+}
+
+func validatePort(port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("invalid port %d, must be between 1 and 65535", port)
+	}
+	return nil
 }
 
 type Source interface {
@@ -32,17 +649,46 @@ type Source interface {
 }
 
 func Load(source Source) (Config, error) {
-	return source.Load()
+	c, err := source.Load()
+	if err != nil {
+		return c, err
+	}
+
+	if err := c.Validate(); err != nil {
+		return c, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	return c, nil
 }
 
-func AutoLoad() (Config, error) {
-	envSrc := EnvSource{
-		Prefix: "",
+// AutoSource layers defaults, the YAML file named by -config or CONFIG_FILE,
+// environment variables and -set flag overrides, in that order.
+// flags may be nil when the caller has no command line.
+func AutoSource(flags *FlagSource) LayeredSource {
+	loadDotEnv()
+
+	path := os.Getenv("CONFIG_FILE")
+	if flags != nil && flags.File != "" {
+		path = flags.File
 	}
-	cfg, err := Load(envSrc)
-	if err != nil {
-		return cfg, err
+
+	var layers []Layer
+	if path != "" {
+		layers = append(layers, FileSource{Path: path})
 	}
+	layers = append(layers, EnvSource{Prefix: ""})
+	if flags != nil {
+		layers = append(layers, flags)
+	}
+
+	return LayeredSource{Layers: layers}
+}
+
+func AutoLoad() (Config, error) {
+	return AutoLoadWithFlags(nil)
+}
 
-	return cfg, nil
+// AutoLoadWithFlags is AutoLoad with command-line overrides applied last.
+func AutoLoadWithFlags(flags *FlagSource) (Config, error) {
+	return Load(AutoSource(flags))
 }
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// CronParser parses six-field cron specs (with seconds) and descriptors such
+// as "@every 2m". The scheduler runs jobs with it, so specs that validate
+// here are the ones it accepts.
+var CronParser = cron.NewParser(
+	cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// Job configures one scheduled job.
+type Job struct {
+	// Spec is a cron spec with seconds, e.g. "0 0 18 * * 2", or a descriptor such as "@every 2m".
+	Spec string `yaml:"spec"`
+	// BatchSize is the number of items the job handles per run.
+	BatchSize int  `yaml:"batch_size" split_words:"true"`
+	Enabled   bool `yaml:"enabled"`
+	// Timezone is an IANA zone name the spec is evaluated in; empty means the local zone.
+	Timezone string `yaml:"timezone"`
+	// Jitter delays each run by a random duration up to this value.
+	Jitter time.Duration `yaml:"jitter"`
+}
+
+// CronSpec returns the spec with the timezone applied.
+func (j Job) CronSpec() string {
+	if j.Timezone == "" {
+		return j.Spec
+	}
+	return fmt.Sprintf("CRON_TZ=%s %s", j.Timezone, j.Spec)
+}
+
+// Validate checks the job configuration.
+func (j Job) Validate() error {
+	if !j.Enabled {
+		return nil
+	}
+
+	var errs []error
+	if j.Timezone != "" {
+		if _, err := time.LoadLocation(j.Timezone); err != nil {
+			errs = append(errs, fmt.Errorf("invalid timezone %q: %v", j.Timezone, err))
+		}
+	}
+	if _, err := CronParser.Parse(j.CronSpec()); err != nil {
+		errs = append(errs, fmt.Errorf("invalid spec %q: %v", j.Spec, err))
+	}
+	if j.BatchSize <= 0 {
+		errs = append(errs, fmt.Errorf("batch size must be positive, got %d", j.BatchSize))
+	}
+	if j.Jitter < 0 {
+		errs = append(errs, fmt.Errorf("jitter must not be negative, got %s", j.Jitter))
+	}
+	return errors.Join(errs...)
+}
+
+// Schedule configures every job run by the scheduler.
+type Schedule struct {
+	FetchPosts       Job `yaml:"fetch_posts" split_words:"true"`
+	CreateHaiku      Job `yaml:"create_haiku" split_words:"true"`
+	ProcessSummary   Job `yaml:"process_summary" split_words:"true"`
+	ProcessHaikuText Job `yaml:"process_haiku_text" split_words:"true"`
+	// SubmitForReview only has work while review mode is on.
+	SubmitForReview Job `yaml:"submit_for_review" split_words:"true"`
+	PostHaiku       Job `yaml:"post_haiku" split_words:"true"`
+	DispatchOutbox  Job `yaml:"dispatch_outbox" split_words:"true"`
+	// ProcessStopReplies opts out authors replying "stop"; BatchSize is the
+	// number of mentions read per run.
+	ProcessStopReplies Job `yaml:"process_stop_replies" split_words:"true"`
+	// ProcessMentions only has work while mentions are enabled; BatchSize is
+	// the number of mentions read per run.
+	ProcessMentions Job `yaml:"process_mentions" split_words:"true"`
+	// TrackEngagement samples the metrics of recently published haikus;
+	// BatchSize is the number of replies looked up per request.
+	TrackEngagement Job `yaml:"track_engagement" split_words:"true"`
+}
+
+// DefaultSchedule returns the schedule used when nothing is configured.
+func DefaultSchedule() Schedule {
+	return Schedule{
+		// Every Tuesday evening at 18:00.
+		FetchPosts:       Job{Spec: "0 0 18 * * 2", BatchSize: 10, Enabled: true},
+		CreateHaiku:      Job{Spec: "@every 1m", BatchSize: 1, Enabled: true},
+		ProcessSummary:   Job{Spec: "@every 2m", BatchSize: 1, Enabled: true},
+		ProcessHaikuText: Job{Spec: "@every 2m", BatchSize: 1, Enabled: true},
+		SubmitForReview:  Job{Spec: "@every 1m", BatchSize: 10, Enabled: true},
+		// At second 0, minute 0, every 3rd hour of every day.
+		PostHaiku: Job{Spec: "0 0 */3 * * *", BatchSize: 1, Enabled: true},
+		// Also triggered as soon as PostHaiku queues a publication; polling picks up retries.
+		DispatchOutbox:     Job{Spec: "@every 1m", BatchSize: 5, Enabled: true},
+		ProcessStopReplies: Job{Spec: "@every 15m", BatchSize: 20, Enabled: true},
+		ProcessMentions:    Job{Spec: "@every 5m", BatchSize: 10, Enabled: true},
+		TrackEngagement:    Job{Spec: "@every 12h", BatchSize: 100, Enabled: true},
+	}
+}
+
+// Validate checks every job and reports all problems at once.
+func (s Schedule) Validate() error {
+	jobs := []struct {
+		name string
+		job  Job
+	}{
+		{"fetch_posts", s.FetchPosts},
+		{"create_haiku", s.CreateHaiku},
+		{"process_summary", s.ProcessSummary},
+		{"process_haiku_text", s.ProcessHaikuText},
+		{"submit_for_review", s.SubmitForReview},
+		{"post_haiku", s.PostHaiku},
+		{"dispatch_outbox", s.DispatchOutbox},
+		{"process_stop_replies", s.ProcessStopReplies},
+		{"process_mentions", s.ProcessMentions},
+		{"track_engagement", s.TrackEngagement},
+	}
+
+	var errs []error
+	for _, j := range jobs {
+		if err := j.job.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("schedule.%s: %w", j.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// redacted is shown in place of a configured secret.
+const redacted = "[REDACTED]"
+
+// Secret is a configuration value that must never be printed. String,
+// GoString, JSON and YAML encodings all redact it; call Reveal to use it.
+// An empty Secret prints as empty so missing values remain visible.
+type Secret string
+
+// Reveal returns the secret value.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return fmt.Sprintf("config.Secret(%q)", s.String())
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+// secretFields maps the environment variable of every secret to its field.
+func (c *Config) secretFields() map[string]*Secret {
+	return map[string]*Secret{
+		"DB_PASSWORD":                     &c.DB.Password,
+		"TWITTER_API_BEARER":              &c.Twitter.APIBearer,
+		"TWITTER_API_KEY":                 &c.Twitter.APIKey,
+		"TWITTER_API_SECRET":              &c.Twitter.APISecret,
+		"TWITTER_API_ACCESS_TOKEN":        &c.Twitter.APIAccessToken,
+		"TWITTER_API_ACCESS_TOKEN_SECRET": &c.Twitter.APIAccessTokenSecret,
+		"HUGGINGFACE_API_KEY":             &c.HuggingFace.APIKey,
+		"ADMIN_TOKEN":                     &c.Admin.Token,
+		"DASHBOARD_PASSWORD":              &c.Dashboard.Password,
+	}
+}
+
+// Secrets returns every distinct configured secret value, for scrubbing logs.
+// Longer values come first so a secret that contains another is replaced
+// whole rather than leaking the part around the shorter match.
+func (c Config) Secrets() []string {
+	var values []string
+	for _, s := range c.secretFields() {
+		if *s != "" {
+			values = append(values, s.Reveal())
+		}
+	}
+
+	slices.SortFunc(values, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a, b)
+	})
+	return slices.Compact(values)
+}
+
+// applySecretFiles reads secrets from files named by <VAR>_FILE variables,
+// as mounted by Docker and Kubernetes secrets. A trailing newline is dropped.
+func applySecretFiles(c *Config, prefix string) error {
+	for key, field := range c.secretFields() {
+		if prefix != "" {
+			key = strings.ToUpper(prefix) + "_" + key
+		}
+
+		path, ok := os.LookupEnv(key + "_FILE")
+		if !ok || path == "" {
+			continue
+		}
+		if _, both := os.LookupEnv(key); both {
+			return fmt.Errorf("both %s and %s_FILE are set", key, key)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s_FILE: %v", key, err)
+		}
+		*field = Secret(strings.TrimRight(string(data), "\r\n"))
+	}
+	return nil
+}
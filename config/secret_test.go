@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSecretsOrder(t *testing.T) {
+	var c Config
+	c.DB.Password = "abc"
+	c.Twitter.APIKey = "abcdef"
+	c.Twitter.APISecret = "abcdef"
+	c.HuggingFace.APIKey = "xyz"
+	c.Twitter.APIBearer = "a-much-longer-token"
+
+	want := []string{"a-much-longer-token", "abcdef", "abc", "xyz"}
+	for i := 0; i < 10; i++ {
+		if got := c.Secrets(); !slices.Equal(got, want) {
+			t.Fatalf("Secrets() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    Secret
+		wantErr bool
+	}{
+		{"plain variable", map[string]string{"DB_PASSWORD": "from-env"}, "from-env", false},
+		{"file variable", map[string]string{"DB_PASSWORD_FILE": write("password", "from-file\n")}, "from-file", false},
+		{"only the trailing newline is dropped", map[string]string{"DB_PASSWORD_FILE": write("spaced", " spaced \r\n")}, " spaced ", false},
+		{"both set", map[string]string{"DB_PASSWORD": "from-env", "DB_PASSWORD_FILE": write("both", "from-file")}, "", true},
+		{"missing file", map[string]string{"DB_PASSWORD_FILE": filepath.Join(dir, "missing")}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"DB_PASSWORD", "DB_PASSWORD_FILE"} {
+				unsetEnv(t, key)
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			c, err := EnvSource{}.Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && c.DB.Password != tt.want {
+				t.Errorf("db.password = %q, want %q", c.DB.Password.Reveal(), tt.want.Reveal())
+			}
+		})
+	}
+}
+
+func TestSecretRedacted(t *testing.T) {
+	s := Secret("hunter2")
+	for name, got := range map[string]string{
+		"String":   s.String(),
+		"GoString": s.GoString(),
+	} {
+		if got == "hunter2" || !strings.Contains(got, redacted) {
+			t.Errorf("%s() = %q, want it redacted", name, got)
+		}
+	}
+	if got := Secret("").String(); got != "" {
+		t.Errorf("empty String() = %q, want empty", got)
+	}
+}
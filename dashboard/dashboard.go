@@ -0,0 +1,186 @@
+// Package dashboard serves a small web UI for watching the pipeline and
+// fixing individual haikus without a database client.
+package dashboard
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/config"
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/httpserver"
+	"github.com/dapplux/twitter-haiku-bot/services"
+	"gorm.io/gorm"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+//go:embed static
+var staticFS embed.FS
+
+// pageSize is the number of haikus listed per page.
+const pageSize = 50
+
+// Dashboard serves the operations UI under /dashboard/. Operators sign in
+// with HTTP basic auth: any user name, which is recorded with their changes,
+// and the configured password.
+//
+//	GET  /dashboard/                       haikus by state: state, offset
+//	GET  /dashboard/haikus/{id}            a haiku with its post, history and publications
+//	POST /dashboard/haikus/{id}/retry      put a failed haiku back into the pipeline
+//	POST /dashboard/haikus/{id}/edit       replace the text: text, reason
+//	POST /dashboard/haikus/{id}/fail       mark it failed: reason
+//	POST /dashboard/haikus/{id}/approve    approve it while under review: reason
+//	POST /dashboard/haikus/{id}/reject     reject it while under review: reason
+type Dashboard struct {
+	haikuSvc   *services.HaikuService
+	haikuRepo  repositories.HaikuRepository
+	outboxRepo repositories.OutboxRepository
+	password   config.Secret
+	csrfToken  string
+	pages      map[string]*template.Template
+}
+
+// New creates the dashboard. Changes go through haikuSvc so they follow the
+// same locking and history rules as the pipeline.
+func New(haikuSvc *services.HaikuService, haikuRepo repositories.HaikuRepository, outboxRepo repositories.OutboxRepository, password config.Secret) *Dashboard {
+	// Browsers resend basic auth credentials with cross-site form posts, so
+	// every form carries a token other sites cannot know.
+	mac := hmac.New(sha256.New, []byte(password.Reveal()))
+	mac.Write([]byte("dashboard csrf"))
+
+	pages := make(map[string]*template.Template)
+	for _, name := range []string{"list.html", "haiku.html"} {
+		pages[name] = template.Must(template.New("layout.html").Funcs(funcs).ParseFS(templateFS, "templates/layout.html", "templates/"+name))
+	}
+
+	return &Dashboard{
+		haikuSvc:   haikuSvc,
+		haikuRepo:  haikuRepo,
+		outboxRepo: outboxRepo,
+		password:   password,
+		csrfToken:  hex.EncodeToString(mac.Sum(nil)),
+		pages:      pages,
+	}
+}
+
+// Register adds the dashboard to mux.
+func (d *Dashboard) Register(mux httpserver.Mux) {
+	routes := map[string]handlerFunc{
+		"GET /dashboard/{$}":                  d.list,
+		"GET /dashboard/haikus/{id}":          d.haiku,
+		"POST /dashboard/haikus/{id}/retry":   d.retry,
+		"POST /dashboard/haikus/{id}/edit":    d.edit,
+		"POST /dashboard/haikus/{id}/fail":    d.fail,
+		"POST /dashboard/haikus/{id}/approve": d.approve,
+		"POST /dashboard/haikus/{id}/reject":  d.reject,
+	}
+	for pattern, h := range routes {
+		mux.Handle(pattern, d.authenticate(h))
+	}
+
+	static, _ := fs.Sub(staticFS, "static")
+	mux.Handle("GET /dashboard/static/", http.StripPrefix("/dashboard/static/", http.FileServerFS(static)))
+}
+
+// handlerFunc is a handler whose error is rendered as an error page.
+type handlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// authenticate asks for basic auth credentials, checks the CSRF token of
+// form posts and serves the rest with h.
+func (d *Dashboard) authenticate(h handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user == "" || subtle.ConstantTimeCompare([]byte(password), []byte(d.password.Reveal())) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="haiku dashboard", charset="UTF-8"`)
+			http.Error(w, "Sign in with your name and the dashboard password.", http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodPost && subtle.ConstantTimeCompare([]byte(r.PostFormValue("csrf")), []byte(d.csrfToken)) != 1 {
+			http.Error(w, "The form has expired; reload the page and try again.", http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Content-Security-Policy", "default-src 'self'")
+		w.Header().Set("X-Frame-Options", "DENY")
+		if err := h(w, r); err != nil {
+			d.writeError(w, r, err)
+		}
+	})
+}
+
+// operator returns the name the request was signed in with.
+func operator(r *http.Request) string {
+	user, _, _ := r.BasicAuth()
+	return user
+}
+
+// render executes a page template into a buffer first, so a failing template
+// does not leave a half-written page.
+func (d *Dashboard) render(w http.ResponseWriter, r *http.Request, page string, data any) error {
+	var buf bytes.Buffer
+	if err := d.pages[page].Execute(&buf, data); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// writeError maps err to a status code and writes it. Unexpected errors are
+// logged and reported without their details.
+func (d *Dashboard) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Not found.", http.StatusNotFound)
+	default:
+		slog.ErrorContext(r.Context(), "Dashboard request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+		http.Error(w, "Something went wrong; see the bot's logs.", http.StatusInternalServerError)
+	}
+}
+
+var funcs = template.FuncMap{
+	"time": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format("2006-01-02 15:04:05")
+	},
+	"canRetry": func(h entities.Haiku) bool {
+		return h.State == entities.HaikuStateFailed
+	},
+	"canEdit": func(h entities.Haiku) bool {
+		switch h.State {
+		case entities.HaikuStateHaikuTextGot, entities.HaikuStatePendingReview, entities.HaikuStateApproved,
+			entities.HaikuStateFailed, entities.HaikuStateCancelled, entities.HaikuStateFiltered, entities.HaikuStateSkippedStale, entities.HaikuStateSkippedLanguage:
+			return true
+		}
+		return false
+	},
+	"canFail": func(h entities.Haiku) bool {
+		switch h.State {
+		case entities.HaikuStateComenting, entities.HaikuStateDone, entities.HaikuStateFailed,
+			entities.HaikuStateCancelled, entities.HaikuStateRejected, entities.HaikuStateFiltered, entities.HaikuStateSkippedStale, entities.HaikuStateSkippedLanguage:
+			return false
+		}
+		return true
+	},
+	"canApprove": func(h entities.Haiku) bool {
+		return h.State == entities.HaikuStatePendingReview || h.State == entities.HaikuStateRejected
+	},
+	"canReject": func(h entities.Haiku) bool {
+		return h.State == entities.HaikuStatePendingReview || h.State == entities.HaikuStateApproved
+	},
+}
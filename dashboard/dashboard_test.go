@@ -0,0 +1,61 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAuthenticate(t *testing.T) {
+	d := New(nil, nil, nil, "open sesame")
+	other := New(nil, nil, nil, "another password")
+
+	tests := []struct {
+		name       string
+		method     string
+		user       string
+		password   string
+		csrf       string
+		wantStatus int
+	}{
+		{"page without credentials", http.MethodGet, "", "", "", http.StatusUnauthorized},
+		{"page with a wrong password", http.MethodGet, "ann", "guess", "", http.StatusUnauthorized},
+		{"page without a name", http.MethodGet, "", "open sesame", "", http.StatusUnauthorized},
+		{"page signed in", http.MethodGet, "ann", "open sesame", "", http.StatusOK},
+		{"form with its token", http.MethodPost, "ann", "open sesame", d.csrfToken, http.StatusOK},
+		{"form without a token", http.MethodPost, "ann", "open sesame", "", http.StatusForbidden},
+		{"form with a forged token", http.MethodPost, "ann", "open sesame", strings.Repeat("0", len(d.csrfToken)), http.StatusForbidden},
+		{"form with another dashboard's token", http.MethodPost, "ann", "open sesame", other.csrfToken, http.StatusForbidden},
+		{"form without credentials", http.MethodPost, "", "", d.csrfToken, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			served := false
+			h := d.authenticate(func(w http.ResponseWriter, r *http.Request) error {
+				served = true
+				return nil
+			})
+
+			form := url.Values{}
+			if tt.csrf != "" {
+				form.Set("csrf", tt.csrf)
+			}
+			req := httptest.NewRequest(tt.method, "/dashboard/haikus/h1/retry", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.user != "" || tt.password != "" {
+				req.SetBasicAuth(tt.user, tt.password)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if served != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("handler served = %v for status %d", served, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/dapplux/twitter-haiku-bot/services"
+)
+
+// stateTab links to the haikus in one state.
+type stateTab struct {
+	State  entities.HaikuState
+	Count  int64
+	Active bool
+}
+
+type listPage struct {
+	Tabs    []stateTab
+	State   entities.HaikuState
+	Total   int64
+	Haikus  []entities.Haiku
+	PrevURL string
+	NextURL string
+}
+
+func (d *Dashboard) list(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	state := entities.HaikuState(r.URL.Query().Get("state"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	offset = max(offset, 0)
+
+	counts, err := d.haikuRepo.CountByState(ctx, nil)
+	if err != nil {
+		return err
+	}
+	page := listPage{State: state}
+	for _, s := range entities.HaikuStates {
+		page.Tabs = append(page.Tabs, stateTab{State: s, Count: counts[s], Active: s == state})
+	}
+
+	haikus, total, err := d.haikuRepo.List(ctx, nil, repositories.HaikuFilter{State: state}, repositories.Page{Limit: pageSize, Offset: offset})
+	if err != nil {
+		return err
+	}
+	page.Haikus, page.Total = haikus, total
+	if offset > 0 {
+		page.PrevURL = listURL(state, max(offset-pageSize, 0))
+	}
+	if int64(offset+pageSize) < total {
+		page.NextURL = listURL(state, offset+pageSize)
+	}
+	return d.render(w, r, "list.html", page)
+}
+
+func listURL(state entities.HaikuState, offset int) string {
+	q := url.Values{}
+	if state != "" {
+		q.Set("state", string(state))
+	}
+	if offset > 0 {
+		q.Set("offset", strconv.Itoa(offset))
+	}
+	if len(q) == 0 {
+		return "/dashboard/"
+	}
+	return "/dashboard/?" + q.Encode()
+}
+
+type haikuPage struct {
+	Haiku        entities.Haiku
+	Events       []entities.HaikuEvent
+	Publications []entities.OutboxEntry
+	Operator     string
+	CSRF         string
+	// Notice and Error report the outcome of the last action.
+	Notice string
+	Error  string
+}
+
+func (d *Dashboard) haiku(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	h, err := d.haikuRepo.FindByID(ctx, nil, r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("haiku %s: %w", r.PathValue("id"), err)
+	}
+	events, err := d.haikuRepo.FindEvents(ctx, nil, h.ID)
+	if err != nil {
+		return err
+	}
+	entries, err := d.outboxRepo.FindByHaikuID(ctx, nil, h.ID)
+	if err != nil {
+		return err
+	}
+
+	return d.render(w, r, "haiku.html", haikuPage{
+		Haiku:        *h,
+		Events:       events,
+		Publications: entries,
+		Operator:     operator(r),
+		CSRF:         d.csrfToken,
+		Notice:       r.URL.Query().Get("notice"),
+		Error:        r.URL.Query().Get("error"),
+	})
+}
+
+func (d *Dashboard) retry(w http.ResponseWriter, r *http.Request) error {
+	_, err := d.haikuSvc.Retry(r.Context(), r.PathValue("id"))
+	return afterAction(w, r, "Haiku put back into the pipeline.", err)
+}
+
+func (d *Dashboard) edit(w http.ResponseWriter, r *http.Request) error {
+	_, err := d.haikuSvc.EditText(r.Context(), r.PathValue("id"), r.PostFormValue("text"), operator(r), r.PostFormValue("reason"))
+	return afterAction(w, r, "Text saved.", err)
+}
+
+func (d *Dashboard) fail(w http.ResponseWriter, r *http.Request) error {
+	reason := r.PostFormValue("reason")
+	if reason != "" {
+		reason += " (" + operator(r) + ")"
+	}
+	_, err := d.haikuSvc.Fail(r.Context(), r.PathValue("id"), reason)
+	return afterAction(w, r, "Haiku marked failed.", err)
+}
+
+func (d *Dashboard) approve(w http.ResponseWriter, r *http.Request) error {
+	_, err := d.haikuSvc.Approve(r.Context(), r.PathValue("id"), operator(r), r.PostFormValue("reason"))
+	return afterAction(w, r, "Haiku approved.", err)
+}
+
+func (d *Dashboard) reject(w http.ResponseWriter, r *http.Request) error {
+	_, err := d.haikuSvc.Reject(r.Context(), r.PathValue("id"), operator(r), r.PostFormValue("reason"))
+	return afterAction(w, r, "Haiku rejected.", err)
+}
+
+// afterAction redirects back to the haiku, reporting notice or, if the change
+// was refused, why. Other errors are passed on.
+func afterAction(w http.ResponseWriter, r *http.Request, notice string, err error) error {
+	q := url.Values{}
+	switch {
+	case err == nil:
+		q.Set("notice", notice)
+	case errors.Is(err, services.ErrInvalidState), errors.Is(err, services.ErrInvalidInput):
+		q.Set("error", err.Error())
+	default:
+		return err
+	}
+
+	http.Redirect(w, r, "/dashboard/haikus/"+url.PathEscape(r.PathValue("id"))+"?"+q.Encode(), http.StatusSeeOther)
+	return nil
+}
@@ -0,0 +1,44 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/guregu/null"
+)
+
+// AuthorList names a list of authors the bot must not reply to.
+type AuthorList string
+
+const (
+	// AuthorListBlocklist holds authors blocked by operators.
+	AuthorListBlocklist AuthorList = "blocklist"
+	// AuthorListOptOut holds authors who asked the bot to leave them alone.
+	AuthorListOptOut AuthorList = "optout"
+)
+
+// AuthorLists lists every author list.
+var AuthorLists = []AuthorList{AuthorListBlocklist, AuthorListOptOut}
+
+// Table returns the table holding the list's entries.
+func (l AuthorList) Table() string {
+	return "author_" + string(l)
+}
+
+// Who added authors to a list, besides admins.
+const (
+	// AddedByReply marks authors who opted out by replying to a haiku.
+	AddedByReply = "reply"
+)
+
+// AuthorListEntry is an author on a blocklist or opt-out list, keyed by
+// platform and author ID; usernames can change.
+type AuthorListEntry struct {
+	Platform Platform `gorm:"primaryKey"`
+	AuthorID string   `gorm:"primaryKey"`
+	// Username is the author's username when they were added, for display.
+	Username string
+	Reason   null.String
+	// AddedBy is EventActorAdmin or AddedByReply.
+	AddedBy   string
+	CreatedAt time.Time
+}
@@ -0,0 +1,11 @@
+package entities
+
+import "time"
+
+// Cursor remembers how far a reader got through a platform feed, such as
+// the ID of the newest mention processed.
+type Cursor struct {
+	Name      string `gorm:"primaryKey"`
+	Value     string
+	UpdatedAt time.Time
+}
@@ -0,0 +1,32 @@
+package entities
+
+import "time"
+
+// Engagement is the public metrics of a post at one point in time.
+type Engagement struct {
+	Likes       int
+	Shares      int
+	Replies     int
+	Impressions int
+}
+
+// Interactions weighs shares double, like the scores posts are ranked by.
+func (e Engagement) Interactions() int {
+	return e.Likes + 2*e.Shares + e.Replies
+}
+
+// EngagementSample records the engagement of a published haiku's reply when
+// it was sampled. Samples of one haiku form its engagement time series.
+type EngagementSample struct {
+	ID         int64 `gorm:"primaryKey"`
+	HaikuID    string
+	Platform   Platform
+	ReplyID    string
+	Engagement `gorm:"embedded"`
+	SampledAt  time.Time
+}
+
+// TableName overrides GORM's pluralized default.
+func (EngagementSample) TableName() string {
+	return "engagement_samples"
+}
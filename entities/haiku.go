@@ -7,12 +7,48 @@ import (
 )
 
 type Haiku struct {
-	ID        string `gorm:"primaryKey"`
-	State     HaikuState
-	Summary   null.String
-	Text      null.String
-	PostID    string
-	Post      Post `gorm:"foreignKey:PostID"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID    string `gorm:"primaryKey"`
+	State HaikuState
+	// Attempt counts how often the current stage has been started; it is
+	// reset when a stage completes.
+	Attempt int
+	Summary null.String
+	Text    null.String
+	PostID  string
+	Post    Post `gorm:"foreignKey:PostID"`
+	// TraceContext is the W3C traceparent of the haiku's trace, which every
+	// stage continues.
+	TraceContext null.String
+	// ReviewedBy, ReviewReason and ReviewedAt record the last review
+	// decision; ReviewedBy is "auto" for haikus approved by rules.
+	ReviewedBy   null.String
+	ReviewReason null.String
+	ReviewedAt   null.Time
+	// SafetyScore is the highest safety score of the post and haiku text
+	// screened so far; FilterReason says why a filtered haiku was stopped.
+	SafetyScore  null.Float
+	FilterReason null.String
+	// Priority orders haikus waiting in the same state; higher goes first.
+	Priority float64
+	// MentionID is the mention that requested the haiku, which it is
+	// published as a reply to; RequestedBy is the mention's author ID.
+	MentionID   null.String
+	RequestedBy null.String
+	// Model and Prompt name what generated the text, so engagement can be
+	// attributed to them.
+	Model  null.String
+	Prompt null.String
+	// Experiment and Arm name the experiment arm the text was generated
+	// under, if an experiment was running.
+	Experiment null.String
+	Arm        null.String
+	// Language is the ISO 639-1 code of the language the haiku is written
+	// in: its post's, or English if the post is translated first.
+	Language string
+	// ReplyID is the platform ID of the published reply, whose engagement
+	// is tracked; PublishedAt is when it was delivered.
+	ReplyID     null.String
+	PublishedAt null.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
 }
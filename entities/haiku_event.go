@@ -0,0 +1,28 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/guregu/null"
+)
+
+// Actors recorded on haiku events.
+const (
+	EventActorPipeline = "pipeline"
+	EventActorAdmin    = "admin"
+)
+
+// HaikuEvent records a change to a haiku: a state transition, or an edit
+// that leaves the state as it was.
+type HaikuEvent struct {
+	ID      int64 `gorm:"primaryKey"`
+	HaikuID string
+	// FromState is empty for the event that created the haiku.
+	FromState HaikuState
+	ToState   HaikuState
+	// Actor is who made the change, EventActorPipeline or EventActorAdmin.
+	Actor string
+	// Note explains the change, e.g. the error that failed the haiku.
+	Note      null.String
+	CreatedAt time.Time
+}
@@ -13,11 +13,41 @@ const (
 	HaikuStateSummaryGot       = "summary_got"
 	HaikuStateHaikuTextGetting = "haiku_text_getting"
 	HaikuStateHaikuTextGot     = "haiku_text_got"
+	HaikuStatePendingReview    = "pending_review"
+	HaikuStateApproved         = "approved"
 	HaikuStateComenting        = "comenting"
 	HaikuStateDone             = "done"
 	HaikuStateFailed           = "failed"
+	HaikuStateCancelled        = "cancelled"
+	HaikuStateRejected         = "rejected"
+	HaikuStateFiltered         = "filtered"
+	// HaikuStateSkippedStale haikus were dropped because their post grew too
+	// old to reply to.
+	HaikuStateSkippedStale = "skipped_stale"
+	// HaikuStateSkippedLanguage haikus were dropped because their post is in
+	// a language the bot does not haiku.
+	HaikuStateSkippedLanguage = "skipped_language"
 )
 
+// HaikuStates lists every state in pipeline order.
+var HaikuStates = []HaikuState{
+	HaikuStateCreated,
+	HaikuStateSummaryGetting,
+	HaikuStateSummaryGot,
+	HaikuStateHaikuTextGetting,
+	HaikuStateHaikuTextGot,
+	HaikuStatePendingReview,
+	HaikuStateApproved,
+	HaikuStateComenting,
+	HaikuStateDone,
+	HaikuStateFailed,
+	HaikuStateCancelled,
+	HaikuStateRejected,
+	HaikuStateFiltered,
+	HaikuStateSkippedStale,
+	HaikuStateSkippedLanguage,
+}
+
 // Scan for HaikuState
 func (hs *HaikuState) Scan(value interface{}) error {
 	if value == nil {
@@ -0,0 +1,17 @@
+package entities
+
+import "time"
+
+// Mention is a post that mentions the bot's account, such as a reply to one
+// of its haikus.
+type Mention struct {
+	ID     string
+	Author Author
+	Text   string
+	// InReplyToID is the post this one replies to; empty if it is not a reply.
+	InReplyToID string
+	// ConversationID is the post that started the thread.
+	ConversationID string
+	Platform       Platform
+	CreatedAt      time.Time
+}
@@ -0,0 +1,87 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/guregu/null"
+)
+
+type OutboxStatus string
+
+const (
+	// OutboxStatusPending entries are waiting for their first or next delivery attempt.
+	OutboxStatusPending OutboxStatus = "pending"
+	// OutboxStatusDelivered entries were accepted by the platform; Receipt holds the reply ID.
+	OutboxStatusDelivered OutboxStatus = "delivered"
+	// OutboxStatusFailed entries ran out of attempts.
+	OutboxStatusFailed OutboxStatus = "failed"
+	// OutboxStatusCancelled entries belong to a haiku cancelled before delivery.
+	OutboxStatusCancelled OutboxStatus = "cancelled"
+)
+
+// Scan for OutboxStatus
+func (s *OutboxStatus) Scan(value interface{}) error {
+	if value == nil {
+		*s = ""
+		return nil
+	}
+	str, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("failed to scan OutboxStatus: invalid type %T", value)
+	}
+	*s = OutboxStatus(str)
+	return nil
+}
+
+// Value for OutboxStatus
+func (s OutboxStatus) Value() (driver.Value, error) {
+	return string(s), nil
+}
+
+// OutboxEntry is a request to publish a haiku to a platform. It is written in
+// the same transaction as the haiku's state change and delivered afterwards.
+type OutboxEntry struct {
+	ID string `gorm:"primaryKey"`
+	// DedupeKey identifies the publication; at most one entry exists per key.
+	DedupeKey string
+	HaikuID   string
+	Platform  Platform
+	// TargetID is the platform post the haiku replies to.
+	TargetID string
+	Message  string
+	Status   OutboxStatus
+	Attempts int
+	// NextAttemptAt is when the entry is due; left zero, it is due on creation.
+	NextAttemptAt time.Time `gorm:"default:now()"`
+	LastError     null.String
+	// Receipt is the platform ID of the published reply.
+	Receipt     null.String
+	DeliveredAt null.Time
+	// TraceContext is copied from the haiku so delivery joins its trace.
+	TraceContext null.String
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// TableName overrides GORM's pluralized default.
+func (OutboxEntry) TableName() string {
+	return "outbox"
+}
+
+// OutboxDelivery records one delivery attempt of an OutboxEntry.
+type OutboxDelivery struct {
+	ID          int64 `gorm:"primaryKey"`
+	OutboxID    string
+	Attempt     int
+	Succeeded   bool
+	Receipt     null.String
+	Error       null.String
+	AttemptedAt time.Time
+}
+
+// PublishDedupeKey is the dedupe key for publishing haiku haikuID to platform.
+func PublishDedupeKey(haikuID string, platform Platform) string {
+	return "publish:" + haikuID + ":" + string(platform)
+}
@@ -11,6 +11,9 @@ const (
 	PlatformTwitter Platform = "twitter"
 )
 
+// Platforms lists every supported platform.
+var Platforms = []Platform{PlatformTwitter}
+
 // Scan for Platform
 func (p *Platform) Scan(value interface{}) error {
 	if value == nil {
@@ -3,12 +3,24 @@ package entities
 import "time"
 
 type Post struct {
-	ID        string `gorm:"primaryKey"`
-	Author    Author `gorm:"type:jsonb"`
-	Text      string
-	Likes     int
-	Shares    int
-	Replies   int
-	Platform  Platform
+	ID       string `gorm:"primaryKey"`
+	Author   Author `gorm:"type:jsonb"`
+	Text     string
+	Likes    int
+	Shares   int
+	Replies  int
+	Platform Platform
+	// Priority is the post's score when it was fetched; its haiku starts
+	// with the same priority.
+	Priority float64
+	// SearchProfile names the search that found the post; it is empty for
+	// posts looked up by ID, such as requested ones.
+	SearchProfile string
+	// Language is the ISO 639-1 code of the language the post is written
+	// in, as reported by the platform or detected on ingest; it is empty if
+	// unknown.
+	Language  string
 	CreatedAt time.Time
+	// FetchedAt is when the post was stored; left zero, it is set on insert.
+	FetchedAt time.Time `gorm:"default:now()"`
 }
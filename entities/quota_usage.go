@@ -0,0 +1,18 @@
+package entities
+
+import "time"
+
+// QuotaUsage is the amount of a provider resource consumed during a period.
+// Period is a calendar key such as "2025-02" (monthly) or "2025-02-11" (daily).
+type QuotaUsage struct {
+	Provider  string `gorm:"primaryKey"`
+	Resource  string `gorm:"primaryKey"`
+	Period    string `gorm:"primaryKey"`
+	Used      int64
+	UpdatedAt time.Time
+}
+
+// TableName overrides GORM's pluralized default.
+func (QuotaUsage) TableName() string {
+	return "quota_usage"
+}
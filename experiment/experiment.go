@@ -0,0 +1,94 @@
+// Package experiment splits haikus between the arms of an A/B experiment on
+// how their text is generated.
+package experiment
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+
+	"github.com/dapplux/twitter-haiku-bot/config"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/ai"
+)
+
+// Arm is one combination of generation settings under test.
+type Arm struct {
+	Name string
+	// Weight is the arm's share of the traffic, relative to the other arms.
+	Weight  int
+	Options ai.HaikuOptions
+	// Candidates is how many texts are generated per haiku, at least one.
+	Candidates int
+}
+
+// Experiment assigns haikus to arms by hashing their IDs with its name, so
+// a haiku lands in the same arm every time it is asked.
+type Experiment struct {
+	name  string
+	arms  []Arm
+	total int
+}
+
+// New creates an experiment from its arms, which must have a positive
+// total weight.
+func New(name string, arms []Arm) *Experiment {
+	e := &Experiment{name: name, arms: arms}
+	for _, arm := range arms {
+		e.total += arm.Weight
+	}
+	return e
+}
+
+// FromConfig builds the experiment configured by cfg, or returns nil if no
+// experiment is running.
+func FromConfig(cfg config.Experiment) (*Experiment, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	prompts := ai.HaikuPrompts()
+	names := make([]string, 0, len(cfg.Arms))
+	for name := range cfg.Arms {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	arms := make([]Arm, 0, len(names))
+	for _, name := range names {
+		a := cfg.Arms[name]
+		if a.Prompt != "" && !slices.Contains(prompts, a.Prompt) {
+			return nil, fmt.Errorf("experiment.arms.%s.prompt: unknown prompt %q, want one of %v", name, a.Prompt, prompts)
+		}
+		arms = append(arms, Arm{
+			Name:       name,
+			Weight:     a.Weight,
+			Options:    ai.HaikuOptions{Prompt: a.Prompt, Model: a.Model, Temperature: a.Temperature},
+			Candidates: max(a.Candidates, 1),
+		})
+	}
+	return New(cfg.Name, arms), nil
+}
+
+// Name identifies the experiment on the haikus it assigns.
+func (e *Experiment) Name() string {
+	return e.name
+}
+
+// Assign returns the arm of the haiku with the given ID. It returns false
+// for a nil experiment, which assigns nothing.
+func (e *Experiment) Assign(haikuID string) (Arm, bool) {
+	if e == nil || e.total <= 0 {
+		return Arm{}, false
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(e.name + "/" + haikuID))
+	n := int(h.Sum64() % uint64(e.total))
+	for _, arm := range e.arms {
+		if n < arm.Weight {
+			return arm, true
+		}
+		n -= arm.Weight
+	}
+	return Arm{}, false
+}
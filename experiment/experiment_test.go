@@ -0,0 +1,109 @@
+package experiment
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/dapplux/twitter-haiku-bot/config"
+)
+
+func TestAssignDeterministic(t *testing.T) {
+	arms := []Arm{{Name: "control", Weight: 1}, {Name: "imagery", Weight: 1}}
+	// A restarted bot builds the experiment anew and must agree.
+	e, restarted := New("prompts", arms), New("prompts", arms)
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("haiku-%d", i)
+		first, ok := e.Assign(id)
+		if !ok {
+			t.Fatalf("Assign(%q) assigned no arm", id)
+		}
+		if again, _ := e.Assign(id); again.Name != first.Name {
+			t.Fatalf("Assign(%q) = %s, then %s", id, first.Name, again.Name)
+		}
+		if again, _ := restarted.Assign(id); again.Name != first.Name {
+			t.Fatalf("Assign(%q) = %s, after restart %s", id, first.Name, again.Name)
+		}
+	}
+}
+
+func TestAssignFollowsWeights(t *testing.T) {
+	tests := []struct {
+		name string
+		arms []Arm
+	}{
+		{"even", []Arm{{Name: "a", Weight: 1}, {Name: "b", Weight: 1}}},
+		{"three to one", []Arm{{Name: "a", Weight: 3}, {Name: "b", Weight: 1}}},
+		{"three arms", []Arm{{Name: "a", Weight: 5}, {Name: "b", Weight: 3}, {Name: "c", Weight: 2}}},
+		{"zero weight arm", []Arm{{Name: "a", Weight: 1}, {Name: "b", Weight: 0}}},
+	}
+	const haikus = 20000
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New("split", tt.arms)
+			counts := make(map[string]int)
+			for i := 0; i < haikus; i++ {
+				arm, ok := e.Assign(fmt.Sprintf("%08x-haiku", i))
+				if !ok {
+					t.Fatalf("Assign assigned no arm")
+				}
+				counts[arm.Name]++
+			}
+
+			total := 0
+			for _, arm := range tt.arms {
+				total += arm.Weight
+			}
+			for _, arm := range tt.arms {
+				want := float64(arm.Weight) / float64(total)
+				got := float64(counts[arm.Name]) / haikus
+				if math.Abs(got-want) > 0.02 {
+					t.Errorf("arm %s got %.3f of haikus, want %.3f", arm.Name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAssignNothing(t *testing.T) {
+	var off *Experiment
+	if _, ok := off.Assign("haiku-1"); ok {
+		t.Error("nil experiment assigned an arm")
+	}
+	if _, ok := New("empty", nil).Assign("haiku-1"); ok {
+		t.Error("experiment without arms assigned an arm")
+	}
+	if _, ok := New("weightless", []Arm{{Name: "a"}}).Assign("haiku-1"); ok {
+		t.Error("experiment without weight assigned an arm")
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	e, err := FromConfig(config.Experiment{
+		Enabled: true,
+		Name:    "prompts",
+		Arms: map[string]config.Arm{
+			"imagery": {Weight: 1, Prompt: "imagery", Candidates: 3},
+			"control": {Weight: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("FromConfig failed: %v", err)
+	}
+	if e.Name() != "prompts" || len(e.arms) != 2 {
+		t.Fatalf("FromConfig() = %+v, want experiment prompts with 2 arms", e)
+	}
+	if e.arms[0].Name != "control" || e.arms[0].Candidates != 1 {
+		t.Errorf("first arm = %+v, want control with 1 candidate", e.arms[0])
+	}
+	if e.arms[1].Name != "imagery" || e.arms[1].Candidates != 3 || e.arms[1].Options.Prompt != "imagery" {
+		t.Errorf("second arm = %+v, want imagery with 3 candidates", e.arms[1])
+	}
+
+	if _, err := FromConfig(config.Experiment{Enabled: true, Arms: map[string]config.Arm{"a": {Weight: 1, Prompt: "nope"}}}); err == nil {
+		t.Error("FromConfig accepted an unknown prompt")
+	}
+	if e, err := FromConfig(config.Experiment{}); e != nil || err != nil {
+		t.Errorf("FromConfig() = %v, %v for a disabled experiment, want nil, nil", e, err)
+	}
+}
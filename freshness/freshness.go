@@ -0,0 +1,62 @@
+// Package freshness decides which posts are too old to reply to.
+package freshness
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/config"
+	"github.com/dapplux/twitter-haiku-bot/entities"
+)
+
+// Policy skips posts older than the maximum age set for their platform.
+type Policy struct {
+	maxAge map[entities.Platform]time.Duration
+	now    func() time.Time
+}
+
+// NewPolicy creates a policy from the maximum post age per platform;
+// platforms without one have no limit. now may be nil to use wall-clock time.
+func NewPolicy(maxAge map[entities.Platform]time.Duration, now func() time.Time) *Policy {
+	if now == nil {
+		now = time.Now
+	}
+	return &Policy{maxAge: maxAge, now: now}
+}
+
+// FromConfig builds the policy configured by cfg, or returns nil if no
+// platform has a maximum age.
+func FromConfig(cfg config.Freshness, now func() time.Time) (*Policy, error) {
+	if len(cfg.MaxAge) == 0 {
+		return nil, nil
+	}
+
+	maxAge := make(map[entities.Platform]time.Duration, len(cfg.MaxAge))
+	for name, age := range cfg.MaxAge {
+		platform := entities.Platform(name)
+		if !slices.Contains(entities.Platforms, platform) {
+			return nil, fmt.Errorf("freshness.max_age: unknown platform %q", name)
+		}
+		maxAge[platform] = age
+	}
+	return NewPolicy(maxAge, now), nil
+}
+
+// Check returns why post is too old to reply to, or an empty string if it
+// may still be. A nil policy passes every post, as do posts whose creation
+// time is unknown.
+func (p *Policy) Check(post entities.Post) string {
+	if p == nil || post.CreatedAt.IsZero() {
+		return ""
+	}
+	limit, ok := p.maxAge[post.Platform]
+	if !ok {
+		return ""
+	}
+
+	if age := p.now().Sub(post.CreatedAt); age > limit {
+		return fmt.Sprintf("post is %s old, over the %s limit for %s", age.Round(time.Minute), limit, post.Platform)
+	}
+	return ""
+}
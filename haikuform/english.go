@@ -0,0 +1,105 @@
+package haikuform
+
+import (
+	"strings"
+	"unicode"
+)
+
+// digitSyllables holds the syllables of each digit read out on its own.
+var digitSyllables = [10]int{2, 1, 1, 1, 1, 1, 1, 2, 1, 1}
+
+// englishExceptions overrides the heuristic for common words it gets wrong.
+var englishExceptions = map[string]int{
+	"every":    2,
+	"evening":  2,
+	"fire":     1,
+	"hour":     1,
+	"our":      1,
+	"poem":     2,
+	"quiet":    2,
+	"science":  2,
+	"business": 2,
+	"create":   2,
+	"created":  3,
+	"creates":  2,
+	"idea":     3,
+	"area":     3,
+	"being":    2,
+	"going":    2,
+	"doing":    2,
+	"seeing":   2,
+	"really":   2,
+	"software": 2,
+}
+
+// EnglishCounter counts English syllables with spelling heuristics: one per
+// group of vowels, less silent endings, with a small table of exceptions.
+// It is right for most everyday words and off by one on some, which is
+// acceptable for a form check.
+type EnglishCounter struct{}
+
+// Count returns the syllables in line. Digits are read one by one. Every
+// word gets a count, so it never fails.
+func (EnglishCounter) Count(line string) (int, error) {
+	total := 0
+	for _, word := range strings.FieldsFunc(line, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	}) {
+		total += englishWordSyllables(strings.ToLower(word))
+	}
+	return total, nil
+}
+
+func englishWordSyllables(word string) int {
+	var letters []rune
+	digits := 0
+	for _, r := range word {
+		switch {
+		case r >= '0' && r <= '9':
+			digits += digitSyllables[r-'0']
+		case unicode.IsLetter(r):
+			letters = append(letters, r)
+		}
+	}
+	if len(letters) == 0 {
+		return digits
+	}
+
+	w := string(letters)
+	if n, ok := englishExceptions[w]; ok {
+		return digits + n
+	}
+
+	count := 0
+	prevVowel := false
+	for i, r := range letters {
+		// A leading y is a consonant, as in "yes".
+		vowel := strings.ContainsRune("aeiou", r) || (r == 'y' && i > 0)
+		if vowel && !prevVowel {
+			count++
+		}
+		prevVowel = vowel
+	}
+
+	n := len(letters)
+	switch {
+	case n > 2 && strings.HasSuffix(w, "le") && !isVowel(letters[n-3]),
+		n > 3 && strings.HasSuffix(w, "les") && !isVowel(letters[n-4]):
+		// "table", "little": the final "le" is a syllable of its own.
+	case strings.HasSuffix(w, "e") && !strings.HasSuffix(w, "ee"):
+		// Silent final e, as in "code".
+		count--
+	case n > 3 && strings.HasSuffix(w, "ed") && !strings.ContainsRune("td", letters[n-3]):
+		// "flowed" but not "wanted".
+		count--
+	case n > 3 && strings.HasSuffix(w, "es") && !strings.ContainsRune("sxzgc", letters[n-3]) && !strings.HasSuffix(w, "hes"):
+		// "codes" but not "boxes" or "wishes".
+		count--
+	}
+
+	return digits + max(count, 1)
+}
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("aeiouy", r)
+}
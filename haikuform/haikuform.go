@@ -0,0 +1,99 @@
+// Package haikuform checks that generated text has the shape of a haiku:
+// three lines of 5, 7 and 5 sound units. What a unit is depends on the
+// language, so counting is delegated to a Counter, e.g. English syllables
+// or Japanese morae.
+package haikuform
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Classic is the 5-7-5 pattern.
+var Classic = []int{5, 7, 5}
+
+// Validator checks a text's form, returning a *FormError if it does not fit
+// or an error wrapping ErrUncountable if its form cannot be told.
+type Validator interface {
+	Validate(text string) error
+}
+
+// Counter counts the sound units of one line: syllables, morae or the like.
+// It fails with an error wrapping ErrUncountable on a word it cannot count.
+type Counter interface {
+	Count(line string) (int, error)
+}
+
+// ErrUncountable is returned for text with words whose sound units are not
+// known, e.g. kanji missing from the dictionary. Its form cannot be checked.
+var ErrUncountable = errors.New("form cannot be checked")
+
+// FormError reports a text whose lines do not follow the pattern.
+type FormError struct {
+	// Got holds the count of every non-empty line.
+	Got  []int
+	Want []int
+}
+
+func (e *FormError) Error() string {
+	return fmt.Sprintf("lines count %s, want %s", joinCounts(e.Got), joinCounts(e.Want))
+}
+
+func joinCounts(counts []int) string {
+	parts := make([]string, len(counts))
+	for i, c := range counts {
+		parts[i] = strconv.Itoa(c)
+	}
+	return strings.Join(parts, "-")
+}
+
+// FormValidator validates texts against a pattern of per-line counts.
+type FormValidator struct {
+	counter Counter
+	pattern []int
+}
+
+// NewValidator creates a validator of the Classic pattern counting with counter.
+func NewValidator(counter Counter) *FormValidator {
+	return &FormValidator{counter: counter, pattern: Classic}
+}
+
+// Validate counts every non-empty line of text. Blank lines are ignored.
+func (v *FormValidator) Validate(text string) error {
+	var got []int
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n, err := v.counter.Count(line)
+		if err != nil {
+			return err
+		}
+		got = append(got, n)
+	}
+
+	if len(got) != len(v.pattern) {
+		return &FormError{Got: got, Want: v.pattern}
+	}
+	for i := range got {
+		if got[i] != v.pattern[i] {
+			return &FormError{Got: got, Want: v.pattern}
+		}
+	}
+	return nil
+}
+
+// ByLanguage holds a validator for each language whose form can be checked,
+// by ISO 639-1 code.
+type ByLanguage map[string]Validator
+
+// Validators returns the validators of every language with a built-in
+// counter, reading Japanese kanji with dict.
+func Validators(dict Dictionary) ByLanguage {
+	return ByLanguage{
+		"en": NewValidator(EnglishCounter{}),
+		"ja": NewValidator(NewJapaneseCounter(dict)),
+	}
+}
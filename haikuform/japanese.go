@@ -0,0 +1,165 @@
+package haikuform
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+//go:embed readings.txt
+var defaultReadings string
+
+// smallKana are the small kana of yōon and similar sounds, which share the
+// mora of the kana before them. The small っ is not among them: it is a
+// mora of its own.
+const smallKana = "ゃゅょぁぃぅぇぉゎャュョァィゥェォヮｬｭｮｧｨｩｪｫ"
+
+// Dictionary gives the kana reading of words written in kanji.
+type Dictionary interface {
+	Reading(word string) (string, bool)
+}
+
+// Readings is a Dictionary backed by a map from kanji words to readings.
+type Readings map[string]string
+
+// Reading returns the reading of word, which must match an entry exactly.
+func (r Readings) Reading(word string) (string, bool) {
+	reading, ok := r[word]
+	return reading, ok
+}
+
+// DefaultReadings returns the built-in readings, which cover common haiku
+// vocabulary.
+func DefaultReadings() Readings {
+	readings, err := ParseReadings(strings.NewReader(defaultReadings))
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in readings: %v", err))
+	}
+	return readings
+}
+
+// LoadReadings reads readings from a file in the format of ParseReadings.
+func LoadReadings(path string) (Readings, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	readings, err := ParseReadings(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return readings, nil
+}
+
+// ParseReadings reads one reading per line as "<kanji> <kana>", e.g.
+//
+//	古池 ふるいけ
+//
+// Blank lines and lines starting with # are ignored.
+func ParseReadings(r io.Reader) (Readings, error) {
+	readings := make(Readings)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want <kanji> <kana>", n)
+		}
+		for _, r := range fields[1] {
+			if !isKana(r) {
+				return nil, fmt.Errorf("line %d: reading %q is not kana", n, fields[1])
+			}
+		}
+		readings[fields[0]] = fields[1]
+	}
+	return readings, scanner.Err()
+}
+
+// JapaneseCounter counts Japanese morae, the on of haiku. Every kana is
+// one mora, including ん, the small っ and the long-vowel mark ー, except
+// small kana such as the ゃ of きゃ, which share the mora before them.
+// Kanji are counted by their reading in a dictionary, longest word first; a
+// kanji it lacks cannot be counted, as its reading may be of any length.
+// Latin letters, digits and punctuation are not counted.
+type JapaneseCounter struct {
+	dict Dictionary
+}
+
+// NewJapaneseCounter creates a counter reading kanji with dict.
+func NewJapaneseCounter(dict Dictionary) JapaneseCounter {
+	return JapaneseCounter{dict: dict}
+}
+
+// Count returns the morae in line. It fails with ErrUncountable on a kanji
+// the dictionary cannot read.
+func (c JapaneseCounter) Count(line string) (int, error) {
+	total := 0
+	var kanji []rune
+	for _, r := range line {
+		if unicode.Is(unicode.Han, r) {
+			kanji = append(kanji, r)
+			continue
+		}
+		n, err := c.countKanji(kanji)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+		kanji = kanji[:0]
+		total += kanaMorae(string(r))
+	}
+	n, err := c.countKanji(kanji)
+	if err != nil {
+		return 0, err
+	}
+	return total + n, nil
+}
+
+// countKanji counts a run of kanji by the longest words the dictionary can
+// read, from left to right.
+func (c JapaneseCounter) countKanji(run []rune) (int, error) {
+	total := 0
+	for len(run) > 0 {
+		n := len(run)
+		for ; n > 0; n-- {
+			if reading, ok := c.dict.Reading(string(run[:n])); ok {
+				total += kanaMorae(reading)
+				break
+			}
+		}
+		if n == 0 {
+			return 0, fmt.Errorf("%w: no reading for %q", ErrUncountable, string(run[0]))
+		}
+		run = run[n:]
+	}
+	return total, nil
+}
+
+// kanaMorae counts the morae of the kana in s, ignoring everything else.
+func kanaMorae(s string) int {
+	n := 0
+	for _, r := range s {
+		if isKana(r) && !strings.ContainsRune(smallKana, r) {
+			n++
+		}
+	}
+	return n
+}
+
+// isKana reports whether r is hiragana, katakana or the long-vowel mark.
+func isKana(r rune) bool {
+	if r == 'ー' {
+		return true
+	}
+	return unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r)
+}
@@ -0,0 +1,125 @@
+package haikuform
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJapaneseCounterCount(t *testing.T) {
+	defaults := DefaultReadings()
+	nested := Readings{"今": "いま", "日": "ひ", "今日": "きょう"}
+
+	tests := []struct {
+		name string
+		dict Dictionary
+		line string
+		want int
+	}{
+		{"kanji and kana", defaults, "古池や", 5},
+		{"okurigana", defaults, "蛙飛び込む", 7},
+		{"particle", defaults, "水の音", 5},
+		{"small kana share a mora", defaults, "きょう", 2},
+		{"small tsu is a mora", defaults, "がっこう", 4},
+		{"long vowel mark is a mora", defaults, "ラーメン", 4},
+		{"n is a mora", defaults, "さんぽ", 3},
+		{"small katakana", defaults, "ティー", 2},
+		{"latin and punctuation ignored", defaults, "OK、です。", 2},
+		{"longest word first", nested, "今日", 2},
+		{"shorter words after longest fails", nested, "日今", 3},
+		{"empty", defaults, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewJapaneseCounter(tt.dict).Count(tt.line)
+			if err != nil {
+				t.Fatalf("Count(%q) failed: %v", tt.line, err)
+			}
+			if got != tt.want {
+				t.Errorf("Count(%q) = %d, want %d", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJapaneseCounterUnknownKanji(t *testing.T) {
+	tests := []string{"猫", "古池の猫", "猫や"}
+	for _, line := range tests {
+		t.Run(line, func(t *testing.T) {
+			_, err := NewJapaneseCounter(DefaultReadings()).Count(line)
+			if !errors.Is(err, ErrUncountable) {
+				t.Errorf("Count(%q) error = %v, want ErrUncountable", line, err)
+			}
+		})
+	}
+}
+
+func TestJapaneseValidator(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		wantForm bool
+		wantErr  error
+	}{
+		{name: "classic", text: "古池や\n蛙飛び込む\n水の音"},
+		{name: "blank lines ignored", text: "\n古池や\n\n蛙飛び込む\n水の音\n"},
+		{name: "off form", text: "古池や\n水の音\n水の音", wantForm: true},
+		{name: "two lines", text: "古池や\n蛙飛び込む", wantForm: true},
+		{name: "unknown kanji", text: "古池や\n猫飛び込む\n水の音", wantErr: ErrUncountable},
+	}
+	v := NewValidator(NewJapaneseCounter(DefaultReadings()))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(tt.text)
+			var formErr *FormError
+			switch {
+			case tt.wantForm:
+				if !errors.As(err, &formErr) {
+					t.Errorf("Validate() error = %v, want a *FormError", err)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Errorf("Validate() failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseReadings(t *testing.T) {
+	got, err := ParseReadings(strings.NewReader("# comment\n\n古池 ふるいけ\n  蛙 かわず  \n"))
+	if err != nil {
+		t.Fatalf("ParseReadings failed: %v", err)
+	}
+	want := Readings{"古池": "ふるいけ", "蛙": "かわず"}
+	if len(got) != len(want) {
+		t.Fatalf("ParseReadings = %v, want %v", got, want)
+	}
+	for word, reading := range want {
+		if got[word] != reading {
+			t.Errorf("reading of %s = %q, want %q", word, got[word], reading)
+		}
+	}
+}
+
+func TestParseReadingsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"missing reading", "古池 ふるいけ\n蛙\n", "line 2"},
+		{"extra field", "古池 ふる いけ\n", "line 1"},
+		{"reading not kana", "古池 furuike\n", "not kana"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseReadings(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseReadings error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
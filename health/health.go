@@ -0,0 +1,205 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/postgres"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/httpserver"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/quota"
+	"github.com/dapplux/twitter-haiku-bot/scheduler"
+)
+
+// checkTimeout bounds the database queries made by one request.
+const checkTimeout = 5 * time.Second
+
+// Handler serves the probes and the status page of one replica:
+//
+//   - /healthz answers as long as the process serves HTTP (liveness).
+//   - /readyz checks the database, the schema version, the scheduler and
+//     the recent runs of every job, and answers 503 if any check fails.
+//   - /status reports the job schedule, queue depths and remaining quota.
+type Handler struct {
+	db             *postgres.Database
+	sched          *scheduler.Scheduler
+	haikuRepo      repositories.HaikuRepository
+	tracker        *quota.Tracker
+	maxJobFailures int
+}
+
+// New creates a handler. A job is reported as failing once maxJobFailures
+// of its runs in a row have failed.
+func New(db *postgres.Database, sched *scheduler.Scheduler, haikuRepo repositories.HaikuRepository, tracker *quota.Tracker, maxJobFailures int) *Handler {
+	return &Handler{
+		db:             db,
+		sched:          sched,
+		haikuRepo:      haikuRepo,
+		tracker:        tracker,
+		maxJobFailures: maxJobFailures,
+	}
+}
+
+// Register adds /healthz, /readyz and /status to mux.
+func (h *Handler) Register(mux httpserver.Mux) {
+	mux.Handle("GET /healthz", http.HandlerFunc(h.healthz))
+	mux.Handle("GET /readyz", http.HandlerFunc(h.readyz))
+	mux.Handle("GET /status", http.HandlerFunc(h.status))
+}
+
+// Check is the outcome of one readiness check.
+type Check struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	// LastSuccess is set on job checks once the job has succeeded.
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+}
+
+type readiness struct {
+	Status string  `json:"status"`
+	Checks []Check `json:"checks"`
+}
+
+func (h *Handler) healthz(w http.ResponseWriter, r *http.Request) {
+	httpserver.WriteJSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *Handler) readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	checks := []Check{
+		newCheck("database", h.db.Ping(ctx)),
+		newCheck("migrations", h.checkMigrations(ctx)),
+		newCheck("scheduler", h.checkScheduler()),
+	}
+	checks = append(checks, h.checkJobs()...)
+
+	result := readiness{Status: "ok", Checks: checks}
+	code := http.StatusOK
+	for _, c := range checks {
+		if !c.OK {
+			result.Status = "unavailable"
+			code = http.StatusServiceUnavailable
+			break
+		}
+	}
+	httpserver.WriteJSON(w, r, code, result)
+}
+
+func newCheck(name string, err error) Check {
+	c := Check{Name: name, OK: err == nil}
+	if err != nil {
+		c.Error = err.Error()
+	}
+	return c
+}
+
+// checkMigrations fails unless every embedded migration has been applied cleanly.
+func (h *Handler) checkMigrations(ctx context.Context) error {
+	want, err := postgres.LatestMigration()
+	if err != nil {
+		return err
+	}
+	got, dirty, err := h.db.MigrationVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("migration %d failed and left the schema dirty", got)
+	}
+	if got < want {
+		return fmt.Errorf("schema is at version %d, expected %d", got, want)
+	}
+	return nil
+}
+
+func (h *Handler) checkScheduler() error {
+	if !h.sched.Started() {
+		return fmt.Errorf("scheduler is not running")
+	}
+	return nil
+}
+
+// checkJobs reports one check per job. Jobs that have not run yet, e.g.
+// singleton jobs on a standby replica, pass.
+func (h *Handler) checkJobs() []Check {
+	var checks []Check
+	for _, job := range h.sched.JobStatuses() {
+		c := Check{Name: "job " + job.Name, OK: true}
+		if !job.LastSuccess.IsZero() {
+			last := job.LastSuccess
+			c.LastSuccess = &last
+		}
+		if job.ConsecutiveFailures >= h.maxJobFailures {
+			c.OK = false
+			c.Error = fmt.Sprintf("%d runs in a row failed, last: %s", job.ConsecutiveFailures, job.LastError)
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// Status is the body of /status. Sections whose query failed are left empty
+// and the failure is listed in Errors.
+type Status struct {
+	Time             time.Time             `json:"time"`
+	SchedulerStarted bool                  `json:"scheduler_started"`
+	Jobs             []scheduler.JobStatus `json:"jobs"`
+	QueueDepth       map[string]int64      `json:"queue_depth,omitempty"`
+	Quota            []QuotaStatus         `json:"quota,omitempty"`
+	Errors           []string              `json:"errors,omitempty"`
+}
+
+// QuotaStatus is the consumption of one budget in its current period.
+type QuotaStatus struct {
+	Provider  string `json:"provider"`
+	Resource  string `json:"resource"`
+	Period    string `json:"period"`
+	PeriodKey string `json:"period_key"`
+	Used      int64  `json:"used"`
+	Limit     int64  `json:"limit"`
+	Remaining int64  `json:"remaining"`
+}
+
+func (h *Handler) status(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	status := Status{
+		Time:             time.Now().UTC(),
+		SchedulerStarted: h.sched.Started(),
+		Jobs:             h.sched.JobStatuses(),
+	}
+
+	if counts, err := h.haikuRepo.CountByState(ctx, nil); err != nil {
+		status.Errors = append(status.Errors, fmt.Sprintf("queue depth: %v", err))
+	} else {
+		status.QueueDepth = make(map[string]int64, len(entities.HaikuStates))
+		for _, state := range entities.HaikuStates {
+			status.QueueDepth[string(state)] = counts[state]
+		}
+	}
+
+	if statuses, err := h.tracker.Statuses(ctx); err != nil {
+		status.Errors = append(status.Errors, fmt.Sprintf("quota: %v", err))
+	} else {
+		for _, s := range statuses {
+			status.Quota = append(status.Quota, QuotaStatus{
+				Provider:  s.Provider,
+				Resource:  s.Resource,
+				Period:    string(s.Period),
+				PeriodKey: s.PeriodKey,
+				Used:      s.Used,
+				Limit:     s.Limit,
+				Remaining: s.Remaining(),
+			})
+		}
+	}
+
+	httpserver.WriteJSON(w, r, http.StatusOK, status)
+}
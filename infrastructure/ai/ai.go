@@ -1,6 +1,46 @@
 package ai
 
+import "context"
+
+// TextProcessor writes summaries and haikus. Languages are given as ISO
+// 639-1 codes; an empty one means English.
 type TextProcessor interface {
-	GenerateSummary(text string) (string, error)
-	GenerateHaiku(summary string) (string, error)
+	// GenerateSummary summarizes text written in language.
+	GenerateSummary(ctx context.Context, text, language string) (string, error)
+	GenerateHaiku(ctx context.Context, summary string, opts HaikuOptions) (string, error)
+	// Translate translates text from one language to another; from may be
+	// empty if the source language is unknown.
+	Translate(ctx context.Context, text, from, to string) (string, error)
+}
+
+// HaikuOptions tunes one haiku generation. Empty fields keep the
+// processor's default prompt template, model and temperature.
+type HaikuOptions struct {
+	Prompt      string
+	Model       string
+	Temperature float64
+	// Language is the language the haiku is written in.
+	Language string
+}
+
+// Description names the model and prompt template a TextProcessor writes
+// haikus with.
+type Description struct {
+	Model  string
+	Prompt string
+}
+
+// Describer is implemented by TextProcessors that can name their model and
+// prompt.
+type Describer interface {
+	Describe(opts HaikuOptions) Description
+}
+
+// Describe returns the description of what p writes haikus with under
+// opts, or an empty one if p cannot name its model and prompt.
+func Describe(p TextProcessor, opts HaikuOptions) Description {
+	if d, ok := p.(Describer); ok {
+		return d.Describe(opts)
+	}
+	return Description{}
 }
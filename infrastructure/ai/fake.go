@@ -0,0 +1,131 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+)
+
+// FakeStats counts calls made against a FakeProcessor.
+type FakeStats struct {
+	SummaryCalls      int
+	SummaryFailures   int
+	HaikuCalls        int
+	HaikuFailures     int
+	TranslateCalls    int
+	TranslateFailures int
+}
+
+// fakeHaikus are the canned haikus by prompt template; other prompts get
+// the first.
+var fakeHaikus = map[string]string{
+	"":         "Code flows like water\nThrough the servers in the night\nBugs drift out to sea",
+	"imagery":  "A lone cursor blinks\non the screen's pale morning glow\ncoffee growing cold",
+	"seasonal": "Autumn commits fall\nleaves of old branches merging\ninto the main tree",
+}
+
+// fakeNativeHaikus are the canned haikus of languages other than English,
+// which every prompt shares.
+var fakeNativeHaikus = map[string]string{
+	"ja": "古池や\n蛙飛び込む\n水の音",
+}
+
+// fakeOffForm is what the fake returns when sampling goes astray.
+const fakeOffForm = "Servers hum all night while the deploy keeps running\nand nobody sleeps"
+
+// FakeProcessor is a TextProcessor that produces canned output without
+// calling a model. It is deterministic for a given seed.
+type FakeProcessor struct {
+	mu          sync.Mutex
+	rand        *rand.Rand
+	failureRate float64
+	stats       FakeStats
+}
+
+// NewFakeProcessor creates a fake AI backend. failureRate (0..1) is the
+// probability that any call returns an error.
+func NewFakeProcessor(seed int64, failureRate float64) *FakeProcessor {
+	return &FakeProcessor{
+		rand:        rand.New(rand.NewSource(seed)),
+		failureRate: failureRate,
+	}
+}
+
+// GenerateSummary returns the first sentence of the text.
+func (fp *FakeProcessor) GenerateSummary(ctx context.Context, text, language string) (string, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	fp.stats.SummaryCalls++
+	if fp.rand.Float64() < fp.failureRate {
+		fp.stats.SummaryFailures++
+		return "", fmt.Errorf("error in summarization: simulated failure")
+	}
+
+	summary := text
+	if i := strings.IndexAny(text, ".。"); i >= 0 {
+		summary = text[:i]
+	}
+	return strings.TrimSpace(summary), nil
+}
+
+// GenerateHaiku returns the canned haiku of the prompt template. With a
+// temperature above zero it misses the 5-7-5 form with probability
+// temperature/2.
+func (fp *FakeProcessor) GenerateHaiku(ctx context.Context, summary string, opts HaikuOptions) (string, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	fp.stats.HaikuCalls++
+	if fp.rand.Float64() < fp.failureRate {
+		fp.stats.HaikuFailures++
+		return "", fmt.Errorf("error in haiku generation: simulated failure")
+	}
+
+	if opts.Temperature > 0 && fp.rand.Float64() < opts.Temperature/2 {
+		return fakeOffForm, nil
+	}
+	if haiku, ok := fakeNativeHaikus[opts.Language]; ok {
+		return haiku, nil
+	}
+	if haiku, ok := fakeHaikus[opts.Prompt]; ok {
+		return haiku, nil
+	}
+	return fakeHaikus[""], nil
+}
+
+// Translate marks the text as translated without changing it.
+func (fp *FakeProcessor) Translate(ctx context.Context, text, from, to string) (string, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	fp.stats.TranslateCalls++
+	if fp.rand.Float64() < fp.failureRate {
+		fp.stats.TranslateFailures++
+		return "", fmt.Errorf("error in translation: simulated failure")
+	}
+
+	return fmt.Sprintf("[%s→%s] %s", from, to, text), nil
+}
+
+// Describe names the fake model and prompt, or those chosen by opts.
+func (fp *FakeProcessor) Describe(opts HaikuOptions) Description {
+	desc := Description{Model: "fake", Prompt: "fake"}
+	if opts.Model != "" {
+		desc.Model = opts.Model
+	}
+	if opts.Prompt != "" {
+		desc.Prompt = opts.Prompt
+	}
+	return desc
+}
+
+// Stats returns a snapshot of the call counters.
+func (fp *FakeProcessor) Stats() FakeStats {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	return fp.stats
+}
@@ -2,23 +2,86 @@ package ai
 
 import (
 	"bytes"
+	"cmp"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/metrics"
 	"github.com/dapplux/twitter-haiku-bot/infrastructure/transport"
 )
 
 // Hugging Face API rate limits and endpoints.
 const (
 	huggingFaceMaxRequestsPerMinute = 10 // Free-tier limit
-	summaryAPI                      = "https://api-inference.huggingface.co/models/google/pegasus-xsum"
-	haikuAPI                        = "https://api-inference.huggingface.co/models/mistralai/Mistral-7B-Instruct-v0.2"
+	modelsAPI                       = "https://api-inference.huggingface.co/models/"
+	summaryAPI                      = modelsAPI + "google/pegasus-xsum"
+	haikuModel                      = "mistralai/Mistral-7B-Instruct-v0.2"
+	// haikuPrompt names the default prompt template of GenerateHaiku.
+	haikuPrompt = "strict-575"
+	// multilingualSummaryAPI summarizes posts in languages other than English.
+	multilingualSummaryAPI = modelsAPI + "csebuetnlp/mT5_multilingual_XLSum"
+	// translationModel is the Opus-MT model for a language pair; "mul"
+	// stands for any source language.
+	translationModel = "Helsinki-NLP/opus-mt-%s-%s"
 )
 
+// haikuPrompts holds the prompt templates GenerateHaiku can use, by name.
+// Give a template a new name whenever its text changes, so engagement is
+// not attributed across versions.
+// The second verb takes the instruction of the haiku's language.
+var haikuPrompts = map[string]string{
+	"strict-575": `Generate a haiku in a strict 5-7-5 syllable format based on the following summary:
+	"%s"
+	%sReturn only the haiku and nothing else.<RequestEnd>`,
+	"imagery": `Write a haiku in a strict 5-7-5 syllable format that captures one concrete image from the following summary:
+	"%s"
+	%sReturn only the haiku and nothing else.<RequestEnd>`,
+	"seasonal": `Write a traditional haiku in a strict 5-7-5 syllable format with a seasonal word, inspired by the following summary:
+	"%s"
+	%sReturn only the haiku and nothing else.<RequestEnd>`,
+}
+
+// haikuLanguages holds the instruction added to the prompt for each
+// language haikus can be written in.
+var haikuLanguages = map[string]string{
+	"en": "",
+	"ja": "Write the haiku in Japanese, counting on (morae) instead of syllables.\n\t",
+	"ko": "Write the haiku in Korean.\n\t",
+	"zh": "Write the haiku in Chinese.\n\t",
+	"vi": "Write the haiku in Vietnamese.\n\t",
+	"es": "Write the haiku in Spanish.\n\t",
+	"fr": "Write the haiku in French.\n\t",
+	"de": "Write the haiku in German.\n\t",
+	"pt": "Write the haiku in Portuguese.\n\t",
+	"it": "Write the haiku in Italian.\n\t",
+}
+
+// HaikuLanguages returns the languages haikus can be written in, sorted.
+func HaikuLanguages() []string {
+	langs := make([]string, 0, len(haikuLanguages))
+	for lang := range haikuLanguages {
+		langs = append(langs, lang)
+	}
+	slices.Sort(langs)
+	return langs
+}
+
+// HaikuPrompts returns the names of the prompt templates, sorted.
+func HaikuPrompts() []string {
+	names := make([]string, 0, len(haikuPrompts))
+	for name := range haikuPrompts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
 // HuggingFaceProvider handles AI interactions via Hugging Face API.
 type HuggingFaceProvider struct {
 	AuthToken string
@@ -29,41 +92,91 @@ type HuggingFaceProvider struct {
 func NewHuggingFaceProvider(authToken string) *HuggingFaceProvider {
 	rateLimitedTransport := transport.NewRateLimitTransport(
 		float64(huggingFaceMaxRequestsPerMinute)/60, // 10 requests per 60 seconds
-		http.DefaultTransport,
+		transport.NewLatencyTransport(observeModelCall, http.DefaultTransport),
 	)
+	metrics.RegisterRateLimiter("huggingface", rateLimitedTransport.Tokens)
 
 	return &HuggingFaceProvider{
 		AuthToken: authToken,
-		Client:    &http.Client{Transport: rateLimitedTransport},
+		Client:    &http.Client{Transport: transport.NewTracingTransport(modelSpanName, rateLimitedTransport)},
 	}
 }
 
-// GenerateSummary uses Pegasus-XSum to summarize text.
-func (hf *HuggingFaceProvider) GenerateSummary(text string) (string, error) {
-	payload := map[string]string{"inputs": text}
-	summary, err := hf.callHuggingFaceModel(summaryAPI, payload)
+// observeModelCall records inference latency by model, e.g. "google/pegasus-xsum".
+func observeModelCall(r *http.Request, code string, elapsed time.Duration) {
+	model := strings.TrimPrefix(r.URL.Path, "/models/")
+	metrics.AICallDuration.WithLabelValues(model, code).Observe(elapsed.Seconds())
+}
+
+// modelSpanName names inference spans by model, e.g. "huggingface google/pegasus-xsum".
+func modelSpanName(r *http.Request) string {
+	return "huggingface " + strings.TrimPrefix(r.URL.Path, "/models/")
+}
+
+// GenerateSummary uses Pegasus-XSum to summarize English text and
+// mT5-XLSum for other languages.
+func (hf *HuggingFaceProvider) GenerateSummary(ctx context.Context, text, language string) (string, error) {
+	apiURL := summaryAPI
+	if language != "" && language != "en" {
+		apiURL = multilingualSummaryAPI
+	}
+	payload := map[string]any{"inputs": text}
+	summary, err := hf.callHuggingFaceModel(ctx, apiURL, payload)
 	if err != nil {
 		return "", fmt.Errorf("error in summarization: %v", err)
 	}
 	return strings.TrimSpace(summary), nil
 }
 
-// GenerateHaiku converts a summary into a haiku using Mistral-Small-24B-Instruct-2501.
-func (hf *HuggingFaceProvider) GenerateHaiku(summary string) (string, error) {
-	prompt := fmt.Sprintf(`Generate a haiku in a strict 5-7-5 syllable format based on the following summary:
-	"%s"
-	Return only the haiku and nothing else.<RequestEnd>`, summary)
+// GenerateHaiku converts a summary into a haiku using the model and prompt
+// template chosen by opts, Mistral-7B-Instruct with "strict-575" by default.
+func (hf *HuggingFaceProvider) GenerateHaiku(ctx context.Context, summary string, opts HaikuOptions) (string, error) {
+	desc := hf.Describe(opts)
+	template, ok := haikuPrompts[desc.Prompt]
+	if !ok {
+		return "", fmt.Errorf("error in haiku generation: unknown prompt %q", desc.Prompt)
+	}
+	instruction, ok := haikuLanguages[cmp.Or(opts.Language, "en")]
+	if !ok {
+		return "", fmt.Errorf("error in haiku generation: unsupported language %q", opts.Language)
+	}
 
-	payload := map[string]string{"inputs": prompt}
-	haiku, err := hf.callHuggingFaceModel(haikuAPI, payload)
+	payload := map[string]any{"inputs": fmt.Sprintf(template, summary, instruction)}
+	if opts.Temperature > 0 {
+		payload["parameters"] = map[string]any{"temperature": opts.Temperature}
+	}
+	haiku, err := hf.callHuggingFaceModel(ctx, modelsAPI+desc.Model, payload)
 	if err != nil {
 		return "", fmt.Errorf("error in haiku generation: %v", err)
 	}
 	return extractHaiku(haiku), nil
 }
 
+// Translate uses the Opus-MT model of the language pair.
+func (hf *HuggingFaceProvider) Translate(ctx context.Context, text, from, to string) (string, error) {
+	apiURL := modelsAPI + fmt.Sprintf(translationModel, cmp.Or(from, "mul"), to)
+	payload := map[string]any{"inputs": text}
+	translation, err := hf.callHuggingFaceModel(ctx, apiURL, payload)
+	if err != nil {
+		return "", fmt.Errorf("error in translation: %v", err)
+	}
+	return strings.TrimSpace(translation), nil
+}
+
+// Describe names the haiku model and prompt template used under opts.
+func (hf *HuggingFaceProvider) Describe(opts HaikuOptions) Description {
+	desc := Description{Model: haikuModel, Prompt: haikuPrompt}
+	if opts.Model != "" {
+		desc.Model = opts.Model
+	}
+	if opts.Prompt != "" {
+		desc.Prompt = opts.Prompt
+	}
+	return desc
+}
+
 // callHuggingFaceModel makes a POST request to the Hugging Face API with retry logic.
-func (hf *HuggingFaceProvider) callHuggingFaceModel(apiURL string, payload map[string]string) (string, error) {
+func (hf *HuggingFaceProvider) callHuggingFaceModel(ctx context.Context, apiURL string, payload map[string]any) (string, error) {
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
 		return "", err
@@ -74,7 +187,7 @@ func (hf *HuggingFaceProvider) callHuggingFaceModel(apiURL string, payload map[s
 	var lastErr error
 
 	for i := 0; i <= maxRetries; i++ {
-		req, err := http.NewRequest(http.MethodPost, apiURL, bytes.NewBuffer(payloadBytes))
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewBuffer(payloadBytes))
 		if err != nil {
 			return "", err
 		}
@@ -91,7 +204,6 @@ func (hf *HuggingFaceProvider) callHuggingFaceModel(apiURL string, payload map[s
 			if readErr != nil {
 				lastErr = readErr
 			} else {
-				fmt.Println("Raw API Response:", string(bodyBytes))
 				// Handle transient errors
 				if resp.StatusCode == http.StatusTooManyRequests {
 					return "", fmt.Errorf("rate limit exceeded, try again later")
@@ -104,13 +216,16 @@ func (hf *HuggingFaceProvider) callHuggingFaceModel(apiURL string, payload map[s
 					// Parse JSON response.
 					var arrayResponse []map[string]interface{}
 					if err := json.Unmarshal(bodyBytes, &arrayResponse); err == nil && len(arrayResponse) > 0 {
-						// Try both possible keys.
+						// Try every possible key.
 						if generatedText, ok := arrayResponse[0]["summary_text"].(string); ok {
 							return generatedText, nil
 						}
 						if generatedText, ok := arrayResponse[0]["generated_text"].(string); ok {
 							return generatedText, nil
 						}
+						if translatedText, ok := arrayResponse[0]["translation_text"].(string); ok {
+							return translatedText, nil
+						}
 						lastErr = fmt.Errorf("unexpected response format: %s", string(bodyBytes))
 					} else {
 						lastErr = fmt.Errorf("could not parse response: %s", string(bodyBytes))
@@ -120,7 +235,11 @@ func (hf *HuggingFaceProvider) callHuggingFaceModel(apiURL string, payload map[s
 		}
 
 		if i < maxRetries {
-			time.Sleep(backoff)
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(backoff):
+			}
 			backoff *= 2
 		}
 	}
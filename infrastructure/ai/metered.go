@@ -0,0 +1,57 @@
+package ai
+
+import (
+	"context"
+
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/quota"
+)
+
+// MeteredProcessor wraps a TextProcessor and records every call against a quota.Meter.
+// Calls that would exceed a budget are refused before reaching the model;
+// admitted calls count against it whether or not they succeed.
+type MeteredProcessor struct {
+	next     TextProcessor
+	meter    quota.Meter
+	provider string
+}
+
+// NewMeteredProcessor wraps next, recording usage under the given provider name.
+func NewMeteredProcessor(next TextProcessor, meter quota.Meter, provider string) *MeteredProcessor {
+	return &MeteredProcessor{
+		next:     next,
+		meter:    meter,
+		provider: provider,
+	}
+}
+
+// GenerateSummary reserves one request.
+func (mp *MeteredProcessor) GenerateSummary(ctx context.Context, text, language string) (string, error) {
+	if err := mp.meter.Reserve(ctx, mp.provider, quota.ResourceRequests, 1); err != nil {
+		return "", err
+	}
+
+	return mp.next.GenerateSummary(ctx, text, language)
+}
+
+// GenerateHaiku reserves one request.
+func (mp *MeteredProcessor) GenerateHaiku(ctx context.Context, summary string, opts HaikuOptions) (string, error) {
+	if err := mp.meter.Reserve(ctx, mp.provider, quota.ResourceRequests, 1); err != nil {
+		return "", err
+	}
+
+	return mp.next.GenerateHaiku(ctx, summary, opts)
+}
+
+// Translate reserves one request.
+func (mp *MeteredProcessor) Translate(ctx context.Context, text, from, to string) (string, error) {
+	if err := mp.meter.Reserve(ctx, mp.provider, quota.ResourceRequests, 1); err != nil {
+		return "", err
+	}
+
+	return mp.next.Translate(ctx, text, from, to)
+}
+
+// Describe passes on the description of the wrapped processor.
+func (mp *MeteredProcessor) Describe(opts HaikuOptions) Description {
+	return Describe(mp.next, opts)
+}
@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"gorm.io/gorm"
+)
+
+type authorKey struct {
+	platform entities.Platform
+	authorID string
+}
+
+type authorListRepositoryImpl struct {
+	store *Store
+}
+
+// NewAuthorListRepository creates an AuthorListRepository backed by the store.
+func NewAuthorListRepository(store *Store) repositories.AuthorListRepository {
+	return &authorListRepositoryImpl{store: store}
+}
+
+// Add puts an author on a list unless they are on it already.
+func (r *authorListRepositoryImpl) Add(ctx context.Context, tx *gorm.DB, list entities.AuthorList, entry *entities.AuthorListEntry) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	entries := r.store.authors[list]
+	if entries == nil {
+		entries = make(map[authorKey]entities.AuthorListEntry)
+		r.store.authors[list] = entries
+	}
+	key := authorKey{entry.Platform, entry.AuthorID}
+	if _, ok := entries[key]; ok {
+		return false, nil
+	}
+
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = r.store.now()
+	}
+	entries[key] = *entry
+	return true, nil
+}
+
+// Remove takes an author off a list.
+func (r *authorListRepositoryImpl) Remove(ctx context.Context, tx *gorm.DB, list entities.AuthorList, platform entities.Platform, authorID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key := authorKey{platform, authorID}
+	if _, ok := r.store.authors[list][key]; !ok {
+		return fmt.Errorf("author %s/%s is not on the %s: %w", platform, authorID, list, gorm.ErrRecordNotFound)
+	}
+	delete(r.store.authors[list], key)
+	return nil
+}
+
+// List returns a page of a list, newest first.
+func (r *authorListRepositoryImpl) List(ctx context.Context, tx *gorm.DB, list entities.AuthorList, page repositories.Page) ([]entities.AuthorListEntry, int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	entries := make([]entities.AuthorListEntry, 0, len(r.store.authors[list]))
+	for _, e := range r.store.authors[list] {
+		entries = append(entries, e)
+	}
+	sortAuthorEntries(entries)
+	return paginate(newestFirst(entries, func(e entities.AuthorListEntry) time.Time { return e.CreatedAt }), page), int64(len(entries)), nil
+}
+
+// FindExcluded returns which of authorIDs are on any list.
+func (r *authorListRepositoryImpl) FindExcluded(ctx context.Context, tx *gorm.DB, platform entities.Platform, authorIDs []string) (map[string]entities.AuthorList, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	excluded := make(map[string]entities.AuthorList)
+	for _, list := range entities.AuthorLists {
+		for _, id := range authorIDs {
+			if _, ok := r.store.authors[list][authorKey{platform, id}]; ok {
+				excluded[id] = list
+			}
+		}
+	}
+	return excluded, nil
+}
+
+// sortAuthorEntries orders entries by creation time, then key, oldest first.
+func sortAuthorEntries(entries []entities.AuthorListEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		if a.Platform != b.Platform {
+			return a.Platform < b.Platform
+		}
+		return a.AuthorID < b.AuthorID
+	})
+}
@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"gorm.io/gorm"
+)
+
+type cursorRepositoryImpl struct {
+	store *Store
+}
+
+// NewCursorRepository creates a CursorRepository backed by the store.
+func NewCursorRepository(store *Store) repositories.CursorRepository {
+	return &cursorRepositoryImpl{store: store}
+}
+
+// Get returns the value of a cursor, or "" if it was never set.
+func (r *cursorRepositoryImpl) Get(ctx context.Context, tx *gorm.DB, name string) (string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.cursors[name], nil
+}
+
+// Set stores the value of a cursor.
+func (r *cursorRepositoryImpl) Set(ctx context.Context, tx *gorm.DB, name, value string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.cursors[name] = value
+	return nil
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"gorm.io/gorm"
+)
+
+type engagementRepositoryImpl struct {
+	store *Store
+}
+
+// NewEngagementRepository creates an EngagementRepository backed by the store.
+func NewEngagementRepository(store *Store) repositories.EngagementRepository {
+	return &engagementRepositoryImpl{store: store}
+}
+
+// AddSamples appends the samples, numbering them like a BIGSERIAL.
+func (r *engagementRepositoryImpl) AddSamples(ctx context.Context, tx *gorm.DB, samples []entities.EngagementSample) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range samples {
+		samples[i].ID = int64(len(r.store.samples) + 1)
+		if samples[i].SampledAt.IsZero() {
+			samples[i].SampledAt = r.store.now()
+		}
+		r.store.samples = append(r.store.samples, samples[i])
+	}
+	return nil
+}
+
+// FindByHaikuID returns the samples of a haiku in insertion order.
+func (r *engagementRepositoryImpl) FindByHaikuID(ctx context.Context, tx *gorm.DB, haikuID string) ([]entities.EngagementSample, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var samples []entities.EngagementSample
+	for _, s := range r.store.samples {
+		if s.HaikuID == haikuID {
+			samples = append(samples, s)
+		}
+	}
+	return samples, nil
+}
+
+// Rank sums the latest sample of each haiku by the group's key.
+func (r *engagementRepositoryImpl) Rank(ctx context.Context, tx *gorm.DB, group repositories.EngagementGroup, since time.Time, limit int) ([]repositories.EngagementRank, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	latest := make(map[string]entities.EngagementSample)
+	for _, s := range r.store.samples {
+		if prev, ok := latest[s.HaikuID]; !ok || !s.SampledAt.Before(prev.SampledAt) {
+			latest[s.HaikuID] = s
+		}
+	}
+
+	byKey := make(map[string]*repositories.EngagementRank)
+	for haikuID, s := range latest {
+		h := r.store.haikus[haikuID]
+		if !h.PublishedAt.Valid || h.PublishedAt.Time.Before(since) {
+			continue
+		}
+
+		var key string
+		switch group {
+		case repositories.GroupByHaiku:
+			key = h.ID
+		case repositories.GroupByPrompt:
+			key = h.Prompt.String
+		case repositories.GroupByModel:
+			key = h.Model.String
+		case repositories.GroupBySearchProfile:
+			key = r.store.posts[h.PostID].SearchProfile
+		case repositories.GroupByArm:
+			if h.Experiment.Valid {
+				key = h.Experiment.String + "/" + h.Arm.String
+			}
+		default:
+			return nil, fmt.Errorf("unknown engagement group %q", group)
+		}
+
+		rank, ok := byKey[key]
+		if !ok {
+			rank = &repositories.EngagementRank{Key: key}
+			byKey[key] = rank
+		}
+		rank.Haikus++
+		rank.Likes += s.Likes
+		rank.Shares += s.Shares
+		rank.Replies += s.Replies
+		rank.Impressions += s.Impressions
+		rank.SquaredInteractions += int64(s.Interactions()) * int64(s.Interactions())
+	}
+
+	ranks := make([]repositories.EngagementRank, 0, len(byKey))
+	for _, rank := range byKey {
+		ranks = append(ranks, *rank)
+	}
+	sort.Slice(ranks, func(i, j int) bool {
+		if ranks[i].Score() != ranks[j].Score() {
+			return ranks[i].Score() > ranks[j].Score()
+		}
+		return ranks[i].Key < ranks[j].Key
+	})
+	if len(ranks) > limit {
+		ranks = ranks[:limit]
+	}
+	return ranks, nil
+}
@@ -0,0 +1,296 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"gorm.io/gorm"
+)
+
+type haikuRepositoryImpl struct {
+	store *Store
+}
+
+// NewHaikuRepository creates a HaikuRepository backed by the store.
+func NewHaikuRepository(store *Store) repositories.HaikuRepository {
+	return &haikuRepositoryImpl{store: store}
+}
+
+// Create inserts a new Haiku record into the store.
+func (r *haikuRepositoryImpl) Create(ctx context.Context, tx *gorm.DB, haiku *entities.Haiku) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.haikus[haiku.ID]; exists {
+		return fmt.Errorf("duplicate key value violates unique constraint: haiku %s", haiku.ID)
+	}
+	if haiku.MentionID.Valid {
+		for _, h := range r.store.haikus {
+			if h.MentionID == haiku.MentionID {
+				return fmt.Errorf("duplicate key value violates unique constraint: mention %s", haiku.MentionID.String)
+			}
+		}
+	}
+
+	now := r.store.now()
+	haiku.CreatedAt = now
+	haiku.UpdatedAt = now
+	r.store.haikus[haiku.ID] = *haiku
+	return nil
+}
+
+// FindByID retrieves a Haiku by its ID with its post attached.
+func (r *haikuRepositoryImpl) FindByID(ctx context.Context, tx *gorm.DB, id string) (*entities.Haiku, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	h, ok := r.store.haikus[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.store.withPost(h), nil
+}
+
+// FindByIDForUpdate retrieves a Haiku by its ID. Row locking is provided by
+// the store's UnitOfWork, which serializes transactions.
+func (r *haikuRepositoryImpl) FindByIDForUpdate(ctx context.Context, tx *gorm.DB, id string) (*entities.Haiku, error) {
+	return r.FindByID(ctx, tx, id)
+}
+
+// Save persists the haiku.
+func (r *haikuRepositoryImpl) Save(ctx context.Context, tx *gorm.DB, haiku *entities.Haiku) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	haiku.UpdatedAt = r.store.now()
+	if haiku.CreatedAt.IsZero() {
+		haiku.CreatedAt = haiku.UpdatedAt
+	}
+
+	h := *haiku
+	h.Post = entities.Post{}
+	r.store.haikus[h.ID] = h
+	return nil
+}
+
+// FindNextUnprocessedPost returns the post without a haiku that has the
+// highest aged priority, the oldest among equals.
+func (r *haikuRepositoryImpl) FindNextUnprocessedPost(ctx context.Context, agingPerHour float64) (*entities.Post, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	processed := make(map[string]bool, len(r.store.haikus))
+	for _, h := range r.store.haikus {
+		processed[h.PostID] = true
+	}
+
+	now := r.store.now()
+	var next *entities.Post
+	var best float64
+	for _, p := range r.store.sortedPosts() {
+		if processed[p.ID] {
+			continue
+		}
+		if priority := agedPriority(p.Priority, p.FetchedAt, now, agingPerHour); next == nil || priority > best {
+			next, best = &p, priority
+		}
+	}
+	if next == nil {
+		return nil, fmt.Errorf("no unprocessed post found: %w", gorm.ErrRecordNotFound)
+	}
+	return next, nil
+}
+
+// FindNextByState returns the haiku in the given state that has the highest
+// aged priority, the oldest among equals, with its post attached.
+func (r *haikuRepositoryImpl) FindNextByState(ctx context.Context, tx *gorm.DB, state entities.HaikuState, agingPerHour float64) (*entities.Haiku, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := r.store.now()
+	var next *entities.Haiku
+	var best float64
+	for _, h := range r.store.sortedHaikus() {
+		if h.State != state {
+			continue
+		}
+		if priority := agedPriority(h.Priority, h.CreatedAt, now, agingPerHour); next == nil || priority > best {
+			next, best = &h, priority
+		}
+	}
+	if next == nil {
+		return nil, fmt.Errorf("no haiku found with state %s: %w", state, gorm.ErrRecordNotFound)
+	}
+	return r.store.withPost(*next), nil
+}
+
+// agedPriority adds agingPerHour to priority for every hour since queued.
+func agedPriority(priority float64, queued, now time.Time, agingPerHour float64) float64 {
+	return priority + agingPerHour*now.Sub(queued).Hours()
+}
+
+// CountByAuthorsSince counts the haikus of the authors' posts created since
+// the given time.
+func (r *haikuRepositoryImpl) CountByAuthorsSince(ctx context.Context, tx *gorm.DB, platform entities.Platform, authorIDs []string, since time.Time) (map[string]int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	wanted := make(map[string]bool, len(authorIDs))
+	for _, id := range authorIDs {
+		wanted[id] = true
+	}
+	counts := make(map[string]int)
+	for _, h := range r.store.haikus {
+		p := r.store.posts[h.PostID]
+		if p.Platform == platform && wanted[p.Author.ID] && !h.CreatedAt.Before(since) {
+			counts[p.Author.ID]++
+		}
+	}
+	return counts, nil
+}
+
+// FindByPostID returns the newest haiku of the post.
+func (r *haikuRepositoryImpl) FindByPostID(ctx context.Context, tx *gorm.DB, postID string) (*entities.Haiku, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var newest *entities.Haiku
+	for _, h := range r.store.sortedHaikus() {
+		if h.PostID == postID {
+			newest = &h
+		}
+	}
+	if newest == nil {
+		return nil, fmt.Errorf("failed to find haiku of post %s: %w", postID, gorm.ErrRecordNotFound)
+	}
+	return newest, nil
+}
+
+// FindByMentionID returns the haiku requested by the mention.
+func (r *haikuRepositoryImpl) FindByMentionID(ctx context.Context, tx *gorm.DB, mentionID string) (*entities.Haiku, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, h := range r.store.haikus {
+		if h.MentionID.Valid && h.MentionID.String == mentionID {
+			return &h, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to find haiku requested by mention %s: %w", mentionID, gorm.ErrRecordNotFound)
+}
+
+// CountRequestedSince counts the author's requests, whatever became of them.
+func (r *haikuRepositoryImpl) CountRequestedSince(ctx context.Context, tx *gorm.DB, authorID string, since time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var count int64
+	for _, h := range r.store.haikus {
+		if h.RequestedBy.Valid && h.RequestedBy.String == authorID && !h.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// FindPublishedSince returns the haikus with a reply published since the
+// given time, ordered by publication.
+func (r *haikuRepositoryImpl) FindPublishedSince(ctx context.Context, tx *gorm.DB, since time.Time) ([]entities.Haiku, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var published []entities.Haiku
+	for _, h := range r.store.sortedHaikus() {
+		if h.ReplyID.Valid && h.PublishedAt.Valid && !h.PublishedAt.Time.Before(since) {
+			published = append(published, *r.store.withPost(h))
+		}
+	}
+	sort.SliceStable(published, func(i, j int) bool {
+		return published[i].PublishedAt.Time.Before(published[j].PublishedAt.Time)
+	})
+	return published, nil
+}
+
+// FindStale returns the haikus in state not updated since before, oldest first.
+func (r *haikuRepositoryImpl) FindStale(ctx context.Context, tx *gorm.DB, state entities.HaikuState, before time.Time) ([]entities.Haiku, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var stale []entities.Haiku
+	for _, h := range r.store.sortedHaikus() {
+		if h.State == state && h.UpdatedAt.Before(before) {
+			stale = append(stale, h)
+		}
+	}
+	sort.SliceStable(stale, func(i, j int) bool {
+		return stale[i].UpdatedAt.Before(stale[j].UpdatedAt)
+	})
+	return stale, nil
+}
+
+// CountByState returns the number of haikus in each state.
+func (r *haikuRepositoryImpl) CountByState(ctx context.Context, tx *gorm.DB) (map[entities.HaikuState]int64, error) {
+	counts := make(map[entities.HaikuState]int64)
+	for state, n := range r.store.CountByState() {
+		counts[state] = int64(n)
+	}
+	return counts, nil
+}
+
+// List returns the matching haikus, newest first, with their posts attached.
+func (r *haikuRepositoryImpl) List(ctx context.Context, tx *gorm.DB, filter repositories.HaikuFilter, page repositories.Page) ([]entities.Haiku, int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var matched []entities.Haiku
+	for _, h := range r.store.sortedHaikus() {
+		h = *r.store.withPost(h)
+		if filter.State != "" && h.State != filter.State {
+			continue
+		}
+		if filter.Platform != "" && h.Post.Platform != filter.Platform {
+			continue
+		}
+		if !filter.CreatedFrom.IsZero() && h.CreatedAt.Before(filter.CreatedFrom) {
+			continue
+		}
+		if !filter.CreatedTo.IsZero() && !h.CreatedAt.Before(filter.CreatedTo) {
+			continue
+		}
+		matched = append(matched, h)
+	}
+
+	matched = newestFirst(matched, func(h entities.Haiku) time.Time { return h.CreatedAt })
+	return paginate(matched, page), int64(len(matched)), nil
+}
+
+// AddEvent appends the event, assigning the next ID.
+func (r *haikuRepositoryImpl) AddEvent(ctx context.Context, tx *gorm.DB, event *entities.HaikuEvent) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	event.ID = int64(len(r.store.events) + 1)
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = r.store.now()
+	}
+	r.store.events = append(r.store.events, *event)
+	return nil
+}
+
+// FindEvents returns the events of a haiku in insertion order.
+func (r *haikuRepositoryImpl) FindEvents(ctx context.Context, tx *gorm.DB, haikuID string) ([]entities.HaikuEvent, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var events []entities.HaikuEvent
+	for _, e := range r.store.events {
+		if e.HaikuID == haikuID {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
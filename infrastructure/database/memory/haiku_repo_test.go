@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAgedPriority(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		priority float64
+		queued   time.Time
+		aging    float64
+		want     float64
+	}{
+		{"just queued", 3, now, 0.5, 3},
+		{"gains aging per hour", 3, now.Add(-4 * time.Hour), 0.5, 5},
+		{"no aging keeps priority", 3, now.Add(-4 * time.Hour), 0, 3},
+		{"fractions of an hour count", 0, now.Add(-30 * time.Minute), 1, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := agedPriority(tt.priority, tt.queued, now, tt.aging); got != tt.want {
+				t.Errorf("agedPriority() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgedPriorityOvertakes(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	waiting := agedPriority(1, now.Add(-10*time.Hour), now, 0.5)
+	fresh := agedPriority(5, now, now, 0.5)
+	if waiting <= fresh {
+		t.Errorf("aged priority %v of a long-queued item, want above %v of a fresh one", waiting, fresh)
+	}
+}
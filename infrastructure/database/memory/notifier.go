@@ -0,0 +1,26 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"gorm.io/gorm"
+)
+
+type notifierImpl struct {
+	store *Store
+}
+
+// NewNotifier creates a Notifier that counts notifications per channel
+// instead of delivering them. Simulations drive jobs from the clock alone.
+func NewNotifier(store *Store) repositories.Notifier {
+	return &notifierImpl{store: store}
+}
+
+func (n *notifierImpl) Notify(ctx context.Context, tx *gorm.DB, channel, payload string) error {
+	n.store.mu.Lock()
+	defer n.store.mu.Unlock()
+
+	n.store.notifications[channel]++
+	return nil
+}
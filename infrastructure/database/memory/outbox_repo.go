@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/guregu/null"
+	"gorm.io/gorm"
+)
+
+type outboxRepositoryImpl struct {
+	store *Store
+}
+
+// NewOutboxRepository creates an OutboxRepository backed by the store.
+func NewOutboxRepository(store *Store) repositories.OutboxRepository {
+	return &outboxRepositoryImpl{store: store}
+}
+
+// Create inserts the entry unless its dedupe key is already taken.
+func (r *outboxRepositoryImpl) Create(ctx context.Context, tx *gorm.DB, entry *entities.OutboxEntry) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, e := range r.store.outbox {
+		if e.DedupeKey == entry.DedupeKey {
+			return nil
+		}
+	}
+
+	now := r.store.now()
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	if entry.NextAttemptAt.IsZero() {
+		entry.NextAttemptAt = now
+	}
+	r.store.outbox[entry.ID] = *entry
+	return nil
+}
+
+// FindDueForUpdate returns the pending entry with the earliest due attempt.
+// Locking is provided by the store's UnitOfWork, which serializes transactions.
+func (r *outboxRepositoryImpl) FindDueForUpdate(ctx context.Context, tx *gorm.DB, now time.Time) (*entities.OutboxEntry, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var due *entities.OutboxEntry
+	for _, e := range r.store.outbox {
+		if e.Status != entities.OutboxStatusPending || e.NextAttemptAt.After(now) {
+			continue
+		}
+		if due == nil || e.NextAttemptAt.Before(due.NextAttemptAt) ||
+			(e.NextAttemptAt.Equal(due.NextAttemptAt) && e.ID < due.ID) {
+			e := e
+			due = &e
+		}
+	}
+	if due == nil {
+		return nil, fmt.Errorf("no outbox entry due: %w", gorm.ErrRecordNotFound)
+	}
+	return due, nil
+}
+
+// Save persists the entry.
+func (r *outboxRepositoryImpl) Save(ctx context.Context, tx *gorm.DB, entry *entities.OutboxEntry) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	entry.UpdatedAt = r.store.now()
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = entry.UpdatedAt
+	}
+	r.store.outbox[entry.ID] = *entry
+	return nil
+}
+
+// AddDelivery appends the delivery attempt.
+func (r *outboxRepositoryImpl) AddDelivery(ctx context.Context, tx *gorm.DB, delivery *entities.OutboxDelivery) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delivery.ID = int64(len(r.store.deliveries) + 1)
+	r.store.deliveries = append(r.store.deliveries, *delivery)
+	return nil
+}
+
+// FindByHaikuID returns the entries for a haiku.
+func (r *outboxRepositoryImpl) FindByHaikuID(ctx context.Context, tx *gorm.DB, haikuID string) ([]entities.OutboxEntry, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var entries []entities.OutboxEntry
+	for _, e := range r.store.outbox {
+		if e.HaikuID == haikuID {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CreatedAt.Before(entries[j].CreatedAt) })
+	return entries, nil
+}
+
+// FindByReceipt returns the entry whose publication has the given receipt.
+func (r *outboxRepositoryImpl) FindByReceipt(ctx context.Context, tx *gorm.DB, platform entities.Platform, receipt string) (*entities.OutboxEntry, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, e := range r.store.outbox {
+		if e.Platform == platform && e.Receipt.Valid && e.Receipt.String == receipt {
+			return &e, nil
+		}
+	}
+	return nil, fmt.Errorf("no outbox entry with receipt %s: %w", receipt, gorm.ErrRecordNotFound)
+}
+
+// FindDeliveries returns the attempts for an entry.
+func (r *outboxRepositoryImpl) FindDeliveries(ctx context.Context, tx *gorm.DB, outboxID string) ([]entities.OutboxDelivery, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var deliveries []entities.OutboxDelivery
+	for _, d := range r.store.deliveries {
+		if d.OutboxID == outboxID {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
+
+// LastDeliveredAt returns the latest delivery time.
+func (r *outboxRepositoryImpl) LastDeliveredAt(ctx context.Context, tx *gorm.DB) (null.Time, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var last null.Time
+	for _, e := range r.store.outbox {
+		if e.DeliveredAt.Valid && (!last.Valid || e.DeliveredAt.Time.After(last.Time)) {
+			last = e.DeliveredAt
+		}
+	}
+	return last, nil
+}
@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"gorm.io/gorm"
+)
+
+type postRepositoryImpl struct {
+	store *Store
+}
+
+// NewPostRepository creates a PostRepository backed by the store.
+func NewPostRepository(store *Store) repositories.PostRepository {
+	return &postRepositoryImpl{store: store}
+}
+
+// Create inserts a new Post record into the store.
+func (r *postRepositoryImpl) Create(ctx context.Context, tx *gorm.DB, post *entities.Post) error {
+	return r.SaveBatch(ctx, tx, []entities.Post{*post})
+}
+
+// SaveBatch inserts multiple Post records. Like a single INSERT, it fails
+// without writing anything if any ID already exists.
+func (r *postRepositoryImpl) SaveBatch(ctx context.Context, tx *gorm.DB, posts []entities.Post) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, p := range posts {
+		if _, exists := r.store.posts[p.ID]; exists {
+			return fmt.Errorf("duplicate key value violates unique constraint: post %s", p.ID)
+		}
+	}
+
+	for _, p := range posts {
+		if p.CreatedAt.IsZero() {
+			p.CreatedAt = r.store.now()
+		}
+		if p.FetchedAt.IsZero() {
+			p.FetchedAt = r.store.now()
+		}
+		r.store.posts[p.ID] = p
+	}
+	return nil
+}
+
+// FindByID retrieves a Post by its ID.
+func (r *postRepositoryImpl) FindByID(ctx context.Context, tx *gorm.DB, id string) (*entities.Post, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	post, ok := r.store.posts[id]
+	if !ok {
+		return nil, fmt.Errorf("failed to find post with id %s: %w", id, gorm.ErrRecordNotFound)
+	}
+	return &post, nil
+}
+
+// List returns the matching posts, newest first. Unlike ILIKE, the query is
+// matched literally.
+func (r *postRepositoryImpl) List(ctx context.Context, tx *gorm.DB, filter repositories.PostFilter, page repositories.Page) ([]entities.Post, int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	query := strings.ToLower(filter.Query)
+	var matched []entities.Post
+	for _, p := range r.store.sortedPosts() {
+		if filter.Platform != "" && p.Platform != filter.Platform {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(p.Text), query) &&
+			!strings.Contains(strings.ToLower(p.Author.Username), query) {
+			continue
+		}
+		if !filter.CreatedFrom.IsZero() && p.CreatedAt.Before(filter.CreatedFrom) {
+			continue
+		}
+		if !filter.CreatedTo.IsZero() && !p.CreatedAt.Before(filter.CreatedTo) {
+			continue
+		}
+		matched = append(matched, p)
+	}
+
+	matched = newestFirst(matched, func(p entities.Post) time.Time { return p.CreatedAt })
+	return paginate(matched, page), int64(len(matched)), nil
+}
@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"gorm.io/gorm"
+)
+
+type quotaKey struct {
+	provider, resource, period string
+}
+
+type quotaRepositoryImpl struct {
+	store *Store
+}
+
+// NewQuotaRepository creates a QuotaRepository backed by the store.
+func NewQuotaRepository(store *Store) repositories.QuotaRepository {
+	return &quotaRepositoryImpl{store: store}
+}
+
+// Increment adds amount to the counter.
+func (r *quotaRepositoryImpl) Increment(ctx context.Context, tx *gorm.DB, provider, resource, period string, amount int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.quota[quotaKey{provider, resource, period}] += amount
+	return nil
+}
+
+// Reserve adds amount to the counter unless it would exceed limit.
+func (r *quotaRepositoryImpl) Reserve(ctx context.Context, tx *gorm.DB, provider, resource, period string, amount, limit int64) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key := quotaKey{provider, resource, period}
+	if r.store.quota[key]+amount > limit {
+		return false, nil
+	}
+	r.store.quota[key] += amount
+	return true, nil
+}
+
+// FindUsage returns the consumed amount for a period.
+func (r *quotaRepositoryImpl) FindUsage(ctx context.Context, tx *gorm.DB, provider, resource, period string) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.quota[quotaKey{provider, resource, period}], nil
+}
@@ -0,0 +1,178 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+)
+
+// Store keeps posts, haikus and their events, quota counters, the outbox,
+// author lists, cursors and engagement samples in process memory. It backs the repository
+// implementations in this package and is meant for simulations, not production.
+type Store struct {
+	mu     sync.Mutex
+	txMu   sync.Mutex
+	now    func() time.Time
+	posts  map[string]entities.Post
+	haikus map[string]entities.Haiku
+	quota  map[quotaKey]int64
+	outbox map[string]entities.OutboxEntry
+
+	authors map[entities.AuthorList]map[authorKey]entities.AuthorListEntry
+	cursors map[string]string
+
+	deliveries    []entities.OutboxDelivery
+	events        []entities.HaikuEvent
+	samples       []entities.EngagementSample
+	notifications map[string]int
+}
+
+// NewStore creates an empty Store. now supplies timestamps for new rows;
+// pass time.Now for wall-clock time or a virtual clock for simulations.
+func NewStore(now func() time.Time) *Store {
+	if now == nil {
+		now = time.Now
+	}
+
+	return &Store{
+		now:    now,
+		posts:  make(map[string]entities.Post),
+		haikus: make(map[string]entities.Haiku),
+		quota:  make(map[quotaKey]int64),
+		outbox: make(map[string]entities.OutboxEntry),
+
+		authors: make(map[entities.AuthorList]map[authorKey]entities.AuthorListEntry),
+		cursors: make(map[string]string),
+
+		notifications: make(map[string]int),
+	}
+}
+
+// CountByState returns the number of haikus in each state.
+func (s *Store) CountByState() map[entities.HaikuState]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[entities.HaikuState]int)
+	for _, h := range s.haikus {
+		counts[h.State]++
+	}
+	return counts
+}
+
+// CountOutboxByStatus returns the number of outbox entries in each status.
+func (s *Store) CountOutboxByStatus() map[entities.OutboxStatus]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[entities.OutboxStatus]int)
+	for _, e := range s.outbox {
+		counts[e.Status]++
+	}
+	return counts
+}
+
+// AuthorListCounts returns the number of authors on each list.
+func (s *Store) AuthorListCounts() map[entities.AuthorList]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[entities.AuthorList]int, len(s.authors))
+	for list, entries := range s.authors {
+		counts[list] = len(entries)
+	}
+	return counts
+}
+
+// DeliveryCount returns the number of logged delivery attempts.
+func (s *Store) DeliveryCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.deliveries)
+}
+
+// Notifications returns how many notifications were sent per channel.
+func (s *Store) Notifications() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int, len(s.notifications))
+	for channel, n := range s.notifications {
+		counts[channel] = n
+	}
+	return counts
+}
+
+// PostCount returns the number of stored posts.
+func (s *Store) PostCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.posts)
+}
+
+// withPost attaches the stored post to a haiku copy, mirroring GORM's Preload.
+// The caller must hold s.mu.
+func (s *Store) withPost(h entities.Haiku) *entities.Haiku {
+	h.Post = s.posts[h.PostID]
+	return &h
+}
+
+// sortedHaikus returns haikus ordered by creation time, then ID for stability.
+// The caller must hold s.mu.
+func (s *Store) sortedHaikus() []entities.Haiku {
+	haikus := make([]entities.Haiku, 0, len(s.haikus))
+	for _, h := range s.haikus {
+		haikus = append(haikus, h)
+	}
+
+	sort.Slice(haikus, func(i, j int) bool {
+		if haikus[i].CreatedAt.Equal(haikus[j].CreatedAt) {
+			return haikus[i].ID < haikus[j].ID
+		}
+		return haikus[i].CreatedAt.Before(haikus[j].CreatedAt)
+	})
+	return haikus
+}
+
+// sortedPosts returns posts ordered by creation time, then ID for stability.
+// The caller must hold s.mu.
+func (s *Store) sortedPosts() []entities.Post {
+	posts := make([]entities.Post, 0, len(s.posts))
+	for _, p := range s.posts {
+		posts = append(posts, p)
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].ID < posts[j].ID
+		}
+		return posts[i].CreatedAt.Before(posts[j].CreatedAt)
+	})
+	return posts
+}
+
+// newestFirst reverses items sorted oldest first. Ties keep ascending IDs,
+// mirroring ORDER BY created_at DESC, id.
+func newestFirst[T any](items []T, createdAt func(T) time.Time) []T {
+	sort.SliceStable(items, func(i, j int) bool {
+		return createdAt(items[i]).After(createdAt(items[j]))
+	})
+	return items
+}
+
+// paginate returns the window of items selected by page.
+func paginate[T any](items []T, page repositories.Page) []T {
+	if page.Offset >= len(items) {
+		return nil
+	}
+	items = items[page.Offset:]
+	if page.Limit > 0 && page.Limit < len(items) {
+		items = items[:page.Limit]
+	}
+	return items
+}
@@ -0,0 +1,23 @@
+package memory
+
+import (
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"gorm.io/gorm"
+)
+
+type unitOfWorkImpl struct {
+	store *Store
+}
+
+// NewUnitOfWork returns a UnitOfWork that serializes transactions on the store.
+// Changes are applied immediately; a failing transaction is not rolled back.
+func NewUnitOfWork(store *Store) repositories.UnitOfWork {
+	return &unitOfWorkImpl{store: store}
+}
+
+func (u *unitOfWorkImpl) Transaction(fn func(tx *gorm.DB) error) error {
+	u.store.txMu.Lock()
+	defer u.store.txMu.Unlock()
+
+	return fn(nil)
+}
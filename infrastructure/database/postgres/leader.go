@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GlobalLock is the lock name used when one lock covers every job.
+const GlobalLock = "global"
+
+// Elector elects a leader among replicas with session-level Postgres advisory
+// locks. Locks are held on one dedicated connection: if this process dies its
+// session ends, Postgres releases the locks, and a standby acquires them on its next renewal.
+type Elector struct {
+	db       *sql.DB
+	perJob   bool
+	names    []string
+	interval time.Duration
+
+	mu   sync.RWMutex
+	conn *sql.Conn
+	held map[string]bool
+}
+
+// NewElector creates an Elector. With perJob each name in jobs gets its own
+// lock, so leadership can be split across replicas; otherwise a single
+// GlobalLock covers all of them. interval is how often locks are renewed or
+// retried, which bounds how long a takeover takes.
+func NewElector(db *gorm.DB, jobs []string, perJob bool, interval time.Duration) (*Elector, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sql.DB instance: %v", err)
+	}
+
+	names := []string{GlobalLock}
+	if perJob {
+		names = jobs
+	}
+
+	return &Elector{
+		db:       sqlDB,
+		perJob:   perJob,
+		names:    names,
+		interval: interval,
+		held:     make(map[string]bool),
+	}, nil
+}
+
+// Run renews and acquires locks every interval until ctx is cancelled.
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		e.renew(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// IsLeader reports whether this replica may run the job.
+func (e *Elector) IsLeader(job string) bool {
+	name := GlobalLock
+	if e.perJob {
+		name = job
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.held[name]
+}
+
+// renew checks that the lock session is still alive and tries to take any
+// lock not yet held. A dead session drops leadership of every lock.
+func (e *Elector) renew(ctx context.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn != nil {
+		if err := e.conn.PingContext(ctx); err != nil {
+			slog.Warn("Leader election session lost, releasing leadership", "error", err)
+			e.dropLocked()
+		}
+	}
+
+	if e.conn == nil {
+		conn, err := e.db.Conn(ctx)
+		if err != nil {
+			slog.Warn("Leader election could not get a connection", "error", err)
+			return
+		}
+		e.conn = conn
+	}
+
+	for _, name := range e.names {
+		if e.held[name] {
+			continue
+		}
+
+		var acquired bool
+		if err := e.conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockKey(name)).Scan(&acquired); err != nil {
+			slog.Warn("Leader election failed to try lock", "lock", name, "error", err)
+			continue
+		}
+		if acquired {
+			slog.Info("Acquired leadership", "lock", name)
+			e.held[name] = true
+		}
+	}
+}
+
+// Release gives up every lock and closes the lock session. It matches the
+// lifecycle shutdown hook signature.
+func (e *Elector) Release(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn == nil {
+		return nil
+	}
+
+	_, err := e.conn.ExecContext(ctx, "SELECT pg_advisory_unlock_all()")
+	for name := range e.held {
+		slog.Info("Released leadership", "lock", name)
+	}
+	e.dropLocked()
+	if err != nil {
+		return fmt.Errorf("failed to release advisory locks: %v", err)
+	}
+	return nil
+}
+
+// dropLocked forgets all locks and discards the session. The connection is
+// closed rather than returned to the pool, where it would keep holding any
+// locks. The caller must hold e.mu.
+func (e *Elector) dropLocked() {
+	if e.conn != nil {
+		_ = e.conn.Raw(func(any) error { return driver.ErrBadConn })
+		_ = e.conn.Close()
+		e.conn = nil
+	}
+	e.held = make(map[string]bool)
+}
+
+// lockKey maps a lock name to a stable 64-bit advisory lock key.
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("twitter-haiku-bot:" + name))
+	return int64(h.Sum64())
+}
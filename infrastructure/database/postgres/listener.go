@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dapplux/twitter-haiku-bot/config"
+)
+
+// Listener receives Postgres NOTIFY messages on a dedicated connection
+// outside the GORM pool, reconnecting with backoff when it is lost.
+type Listener struct {
+	cfg      config.Config
+	channels []string
+}
+
+// NewListener creates a Listener for the given channels.
+func NewListener(cfg config.Config, channels ...string) *Listener {
+	return &Listener{
+		cfg:      cfg,
+		channels: channels,
+	}
+}
+
+// Listen calls handle for every notification until ctx is cancelled.
+// After each (re)connect it also calls handle once per channel with an empty
+// payload, because notifications sent while disconnected are lost.
+func (l *Listener) Listen(ctx context.Context, handle func(channel, payload string)) {
+	backoff := time.Second
+	for {
+		err := l.listen(ctx, handle, func() { backoff = time.Second })
+		if ctx.Err() != nil {
+			return
+		}
+
+		slog.Warn("Listener disconnected, reconnecting", "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+// listen runs one connection until it fails. connected is called once all
+// channels are subscribed.
+func (l *Listener) listen(ctx context.Context, handle func(channel, payload string), connected func()) error {
+	conn, err := pgx.Connect(ctx, dsn(l.cfg))
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	for _, channel := range l.channels {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", channel, err)
+		}
+	}
+	connected()
+	slog.Info("Listening for notifications", "channels", l.channels)
+
+	for _, channel := range l.channels {
+		handle(channel, "")
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		handle(n.Channel, n.Payload)
+	}
+}
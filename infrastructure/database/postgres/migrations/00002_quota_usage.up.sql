@@ -0,0 +1,8 @@
+CREATE TABLE quota_usage (
+    provider TEXT NOT NULL,
+    resource TEXT NOT NULL,
+    period TEXT NOT NULL,
+    used BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT now(),
+    PRIMARY KEY (provider, resource, period)
+);
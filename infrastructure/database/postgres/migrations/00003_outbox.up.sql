@@ -0,0 +1,38 @@
+CREATE TYPE outbox_status AS ENUM (
+    'pending',
+    'delivered',
+    'failed'
+);
+
+CREATE TABLE outbox (
+    id TEXT PRIMARY KEY,
+    dedupe_key TEXT NOT NULL UNIQUE,
+    haiku_id TEXT NOT NULL,
+    platform platform NOT NULL,
+    target_id TEXT NOT NULL,
+    message TEXT NOT NULL,
+    status outbox_status NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT now(),
+    last_error TEXT,
+    receipt TEXT,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT now(),
+    updated_at TIMESTAMP DEFAULT now(),
+    FOREIGN KEY (haiku_id) REFERENCES haikus(id)
+);
+
+CREATE TABLE outbox_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    outbox_id TEXT NOT NULL,
+    attempt INT NOT NULL,
+    succeeded BOOLEAN NOT NULL,
+    receipt TEXT,
+    error TEXT,
+    attempted_at TIMESTAMP DEFAULT now(),
+    FOREIGN KEY (outbox_id) REFERENCES outbox(id)
+);
+
+CREATE INDEX idx_outbox_pending ON outbox(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_outbox_haiku_id ON outbox(haiku_id);
+CREATE INDEX idx_outbox_deliveries_outbox_id ON outbox_deliveries(outbox_id);
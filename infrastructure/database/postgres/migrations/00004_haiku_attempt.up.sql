@@ -0,0 +1 @@
+ALTER TABLE haikus ADD COLUMN attempt INT NOT NULL DEFAULT 0;
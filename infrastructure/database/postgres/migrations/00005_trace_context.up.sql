@@ -0,0 +1,2 @@
+ALTER TABLE haikus ADD COLUMN trace_context TEXT;
+ALTER TABLE outbox ADD COLUMN trace_context TEXT;
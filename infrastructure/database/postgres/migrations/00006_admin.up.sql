@@ -0,0 +1,15 @@
+ALTER TYPE haiku_state ADD VALUE 'cancelled';
+ALTER TYPE outbox_status ADD VALUE 'cancelled';
+
+CREATE TABLE haiku_events (
+    id BIGSERIAL PRIMARY KEY,
+    haiku_id TEXT NOT NULL REFERENCES haikus(id),
+    from_state TEXT NOT NULL DEFAULT '',
+    to_state TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    note TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_haiku_events_haiku_id ON haiku_events(haiku_id, id);
+CREATE INDEX idx_haikus_state_created_at ON haikus(state, created_at);
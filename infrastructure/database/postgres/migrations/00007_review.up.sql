@@ -0,0 +1,7 @@
+ALTER TYPE haiku_state ADD VALUE 'pending_review';
+ALTER TYPE haiku_state ADD VALUE 'approved';
+ALTER TYPE haiku_state ADD VALUE 'rejected';
+
+ALTER TABLE haikus ADD COLUMN reviewed_by TEXT;
+ALTER TABLE haikus ADD COLUMN review_reason TEXT;
+ALTER TABLE haikus ADD COLUMN reviewed_at TIMESTAMP;
@@ -0,0 +1,4 @@
+ALTER TYPE haiku_state ADD VALUE 'filtered';
+
+ALTER TABLE haikus ADD COLUMN safety_score DOUBLE PRECISION;
+ALTER TABLE haikus ADD COLUMN filter_reason TEXT;
@@ -0,0 +1,19 @@
+CREATE TABLE author_blocklist (
+    platform platform NOT NULL,
+    author_id TEXT NOT NULL,
+    username TEXT NOT NULL DEFAULT '',
+    reason TEXT,
+    added_by TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (platform, author_id)
+);
+
+CREATE TABLE author_optout (LIKE author_blocklist INCLUDING ALL);
+
+CREATE TABLE cursors (
+    name TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_outbox_receipt ON outbox(platform, receipt);
@@ -0,0 +1,6 @@
+ALTER TABLE haikus ADD COLUMN priority INT NOT NULL DEFAULT 0;
+ALTER TABLE haikus ADD COLUMN mention_id TEXT;
+ALTER TABLE haikus ADD COLUMN requested_by TEXT;
+
+CREATE UNIQUE INDEX idx_haikus_mention_id ON haikus(mention_id);
+CREATE INDEX idx_haikus_requested_by ON haikus(requested_by, created_at) WHERE requested_by IS NOT NULL;
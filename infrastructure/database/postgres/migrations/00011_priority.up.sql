@@ -0,0 +1,5 @@
+ALTER TABLE posts ADD COLUMN priority DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE haikus ALTER COLUMN priority TYPE DOUBLE PRECISION;
+
+CREATE INDEX idx_posts_author_id ON posts((author->>'ID'));
+ALTER TABLE posts ADD COLUMN fetched_at TIMESTAMP NOT NULL DEFAULT now();
@@ -0,0 +1 @@
+ALTER TYPE haiku_state ADD VALUE 'skipped_stale';
@@ -0,0 +1,26 @@
+ALTER TABLE posts ADD COLUMN search_profile TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE haikus ADD COLUMN model TEXT;
+ALTER TABLE haikus ADD COLUMN prompt TEXT;
+ALTER TABLE haikus ADD COLUMN reply_id TEXT;
+ALTER TABLE haikus ADD COLUMN published_at TIMESTAMP;
+
+UPDATE haikus SET reply_id = outbox.receipt, published_at = outbox.delivered_at
+FROM outbox
+WHERE outbox.haiku_id = haikus.id AND outbox.status = 'delivered';
+
+CREATE INDEX idx_haikus_published_at ON haikus(published_at) WHERE reply_id IS NOT NULL;
+
+CREATE TABLE engagement_samples (
+    id BIGSERIAL PRIMARY KEY,
+    haiku_id TEXT NOT NULL REFERENCES haikus(id),
+    platform platform NOT NULL,
+    reply_id TEXT NOT NULL,
+    likes INT NOT NULL DEFAULT 0,
+    shares INT NOT NULL DEFAULT 0,
+    replies INT NOT NULL DEFAULT 0,
+    impressions INT NOT NULL DEFAULT 0,
+    sampled_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_engagement_samples_haiku_id ON engagement_samples(haiku_id, sampled_at);
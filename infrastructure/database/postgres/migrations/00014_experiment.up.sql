@@ -0,0 +1,2 @@
+ALTER TABLE haikus ADD COLUMN experiment TEXT;
+ALTER TABLE haikus ADD COLUMN arm TEXT;
@@ -0,0 +1,6 @@
+ALTER TYPE haiku_state ADD VALUE 'skipped_language';
+
+ALTER TABLE posts ADD COLUMN language TEXT NOT NULL DEFAULT '';
+
+-- Every haiku so far was written in English.
+ALTER TABLE haikus ADD COLUMN language TEXT NOT NULL DEFAULT 'en';
@@ -1,12 +1,16 @@
 package postgres
 
 import (
+	"context"
 	"embed"
 	"fmt"
-	"log"
+	"log/slog"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dapplux/twitter-haiku-bot/config"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/tracing"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
@@ -37,17 +41,81 @@ func New(cfg config.Config, direction string, version int) (*Database, error) {
 	return &Database{DB: db}, nil
 }
 
-// connectDB initializes the PostgreSQL connection with GORM
-func connectDB(cfg config.Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf(
+// Close closes the connection pool. It matches the lifecycle shutdown hook signature.
+func (d *Database) Close(ctx context.Context) error {
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get sql.DB instance: %v", err)
+	}
+
+	return sqlDB.Close()
+}
+
+// Ping checks that the database can be reached.
+func (d *Database) Ping(ctx context.Context) error {
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get sql.DB instance: %v", err)
+	}
+
+	return sqlDB.PingContext(ctx)
+}
+
+// MigrationVersion returns the schema version recorded by golang-migrate and
+// whether a migration failed half-way, leaving the schema dirty.
+func (d *Database) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	var row struct {
+		Version int64
+		Dirty   bool
+	}
+	err := d.DB.WithContext(ctx).Raw("SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&row).Error
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read migration version: %v", err)
+	}
+
+	return uint(row.Version), row.Dirty, nil
+}
+
+// LatestMigration returns the highest version among the embedded migrations,
+// which the schema is expected to be at after New.
+func LatestMigration() (uint, error) {
+	files, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return 0, fmt.Errorf("could not read embedded migrations: %v", err)
+	}
+
+	var latest uint
+	for _, file := range files {
+		prefix, _, ok := strings.Cut(file.Name(), "_")
+		if !ok {
+			continue
+		}
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		latest = max(latest, uint(version))
+	}
+	return latest, nil
+}
+
+// dsn builds the keyword/value connection string shared by GORM and the listener.
+func dsn(cfg config.Config) string {
+	return fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=UTC",
-		cfg.DB.Host, cfg.DB.User, cfg.DB.Password, cfg.DB.Name, cfg.DB.Port, cfg.DB.SSLMode,
+		cfg.DB.Host, cfg.DB.User, cfg.DB.Password.Reveal(), cfg.DB.Name, cfg.DB.Port, cfg.DB.SSLMode,
 	)
+}
 
-	db, err := gorm.Open(pgsql.Open(dsn), &gorm.Config{})
+// connectDB initializes the PostgreSQL connection with GORM
+func connectDB(cfg config.Config) (*gorm.DB, error) {
+	db, err := gorm.Open(pgsql.Open(dsn(cfg)), &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to install tracing plugin: %v", err)
+	}
 
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -107,13 +175,13 @@ func runMigrations(db *gorm.DB, direction string, version int) error {
 	}
 
 	if err == migrate.ErrNoChange {
-		log.Println("No new migrations to apply.")
+		slog.Info("No new migrations to apply")
 		return nil
 	} else if err != nil {
 		return fmt.Errorf("migration failed: %v", err)
 	}
 
-	log.Println("Migrations applied successfully.")
+	slog.Info("Migrations applied successfully")
 	return nil
 }
 
@@ -121,12 +189,11 @@ func runMigrations(db *gorm.DB, direction string, version int) error {
 func ListEmbeddedFiles() {
 	files, err := migrationFiles.ReadDir("migrations")
 	if err != nil {
-		log.Println("Failed to read embedded migrations:", err)
+		slog.Error("Failed to read embedded migrations", "error", err)
 		return
 	}
 
-	log.Println("Embedded migration files:")
 	for _, file := range files {
-		log.Println("-", file.Name())
+		slog.Info("Embedded migration file", "name", file.Name())
 	}
 }
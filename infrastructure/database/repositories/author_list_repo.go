@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/tracing"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AuthorListRepository stores the blocklist and the opt-out list.
+type AuthorListRepository interface {
+	// Add puts an author on a list. An author already on it keeps their
+	// original entry, and added reports false.
+	Add(ctx context.Context, tx *gorm.DB, list entities.AuthorList, entry *entities.AuthorListEntry) (added bool, err error)
+	// Remove takes an author off a list; it fails with gorm.ErrRecordNotFound
+	// if they are not on it.
+	Remove(ctx context.Context, tx *gorm.DB, list entities.AuthorList, platform entities.Platform, authorID string) error
+	// List returns a page of a list, newest first, and its length.
+	List(ctx context.Context, tx *gorm.DB, list entities.AuthorList, page Page) ([]entities.AuthorListEntry, int64, error)
+	// FindExcluded returns which of authorIDs are on any list, and the list
+	// each was found on.
+	FindExcluded(ctx context.Context, tx *gorm.DB, platform entities.Platform, authorIDs []string) (map[string]entities.AuthorList, error)
+}
+
+type authorListRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (r authorListRepositoryImpl) getDB(tx *gorm.DB) *gorm.DB {
+	if tx == nil {
+		return r.db
+	}
+
+	return tx
+}
+
+// NewAuthorListRepository creates a new instance of AuthorListRepository.
+func NewAuthorListRepository(db *gorm.DB) AuthorListRepository {
+	return &authorListRepositoryImpl{db: db}
+}
+
+func (r *authorListRepositoryImpl) Add(ctx context.Context, tx *gorm.DB, list entities.AuthorList, entry *entities.AuthorListEntry) (bool, error) {
+	ctx, span := tracing.StartRepository(ctx, "AuthorListRepository.Add")
+	defer span.End()
+
+	db := r.getDB(tx)
+
+	result := db.WithContext(ctx).
+		Table(list.Table()).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(entry)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to add author to %s: %w", list, result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *authorListRepositoryImpl) Remove(ctx context.Context, tx *gorm.DB, list entities.AuthorList, platform entities.Platform, authorID string) error {
+	ctx, span := tracing.StartRepository(ctx, "AuthorListRepository.Remove")
+	defer span.End()
+
+	db := r.getDB(tx)
+
+	result := db.WithContext(ctx).
+		Table(list.Table()).
+		Where("platform = ? AND author_id = ?", platform, authorID).
+		Delete(&entities.AuthorListEntry{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove author from %s: %w", list, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("author %s/%s is not on the %s: %w", platform, authorID, list, gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+func (r *authorListRepositoryImpl) List(ctx context.Context, tx *gorm.DB, list entities.AuthorList, page Page) ([]entities.AuthorListEntry, int64, error) {
+	ctx, span := tracing.StartRepository(ctx, "AuthorListRepository.List")
+	defer span.End()
+
+	db := r.getDB(tx)
+
+	query := db.WithContext(ctx).Table(list.Table()).Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count %s: %w", list, err)
+	}
+
+	var entries []entities.AuthorListEntry
+	err := query.
+		Order("created_at DESC, platform, author_id").
+		Limit(page.Limit).
+		Offset(page.Offset).
+		Find(&entries).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list %s: %w", list, err)
+	}
+	return entries, total, nil
+}
+
+func (r *authorListRepositoryImpl) FindExcluded(ctx context.Context, tx *gorm.DB, platform entities.Platform, authorIDs []string) (map[string]entities.AuthorList, error) {
+	ctx, span := tracing.StartRepository(ctx, "AuthorListRepository.FindExcluded")
+	defer span.End()
+
+	db := r.getDB(tx)
+
+	excluded := make(map[string]entities.AuthorList)
+	if len(authorIDs) == 0 {
+		return excluded, nil
+	}
+	for _, list := range entities.AuthorLists {
+		var ids []string
+		err := db.WithContext(ctx).
+			Table(list.Table()).
+			Where("platform = ? AND author_id IN ?", platform, authorIDs).
+			Pluck("author_id", &ids).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up %s: %w", list, err)
+		}
+		for _, id := range ids {
+			excluded[id] = list
+		}
+	}
+	return excluded, nil
+}
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/tracing"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CursorRepository stores named positions in platform feeds.
+type CursorRepository interface {
+	// Get returns the value of a cursor, or "" if it was never set.
+	Get(ctx context.Context, tx *gorm.DB, name string) (string, error)
+	// Set stores the value of a cursor.
+	Set(ctx context.Context, tx *gorm.DB, name, value string) error
+}
+
+type cursorRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (r cursorRepositoryImpl) getDB(tx *gorm.DB) *gorm.DB {
+	if tx == nil {
+		return r.db
+	}
+
+	return tx
+}
+
+// NewCursorRepository creates a new instance of CursorRepository.
+func NewCursorRepository(db *gorm.DB) CursorRepository {
+	return &cursorRepositoryImpl{db: db}
+}
+
+func (r *cursorRepositoryImpl) Get(ctx context.Context, tx *gorm.DB, name string) (string, error) {
+	ctx, span := tracing.StartRepository(ctx, "CursorRepository.Get")
+	defer span.End()
+
+	db := r.getDB(tx)
+
+	var cursor entities.Cursor
+	err := db.WithContext(ctx).Where("name = ?", name).First(&cursor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return cursor.Value, err
+}
+
+func (r *cursorRepositoryImpl) Set(ctx context.Context, tx *gorm.DB, name, value string) error {
+	ctx, span := tracing.StartRepository(ctx, "CursorRepository.Set")
+	defer span.End()
+
+	db := r.getDB(tx)
+
+	return db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"value": value, "updated_at": gorm.Expr("now()")}),
+		}).
+		Create(&entities.Cursor{Name: name, Value: value}).Error
+}
@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/tracing"
+	"gorm.io/gorm"
+)
+
+// EngagementGroup is what published haikus are grouped by when ranking
+// their engagement.
+type EngagementGroup string
+
+const (
+	GroupByHaiku         EngagementGroup = "haiku"
+	GroupByPrompt        EngagementGroup = "prompt"
+	GroupByModel         EngagementGroup = "model"
+	GroupBySearchProfile EngagementGroup = "search_profile"
+	// GroupByArm keys haikus by "<experiment>/<arm>".
+	GroupByArm EngagementGroup = "arm"
+)
+
+// EngagementGroups lists every group engagement can be ranked by.
+var EngagementGroups = []EngagementGroup{GroupByHaiku, GroupByPrompt, GroupByModel, GroupBySearchProfile, GroupByArm}
+
+// engagementKeys are the SQL expressions grouping haikus, joined with their
+// posts, for each EngagementGroup.
+var engagementKeys = map[EngagementGroup]string{
+	GroupByHaiku:         "haikus.id",
+	GroupByPrompt:        "COALESCE(haikus.prompt, '')",
+	GroupByModel:         "COALESCE(haikus.model, '')",
+	GroupBySearchProfile: "posts.search_profile",
+	GroupByArm:           "COALESCE(haikus.experiment || '/' || haikus.arm, '')",
+}
+
+// EngagementRank sums the latest engagement of the haikus sharing Key, e.g.
+// a prompt; haikus without a value share the empty key.
+type EngagementRank struct {
+	Key    string
+	Haikus int
+	entities.Engagement
+	// SquaredInteractions sums the square of each haiku's interactions,
+	// which the spread of Score is computed from.
+	SquaredInteractions int64
+}
+
+// Score is the average interactions per haiku, which groups are ranked by.
+func (r EngagementRank) Score() float64 {
+	if r.Haikus == 0 {
+		return 0
+	}
+	return float64(r.Interactions()) / float64(r.Haikus)
+}
+
+// Interval returns the 95% confidence interval of Score by the normal
+// approximation. The spread of a single haiku is unknown, so its interval
+// is the score itself. Interactions are never negative, so neither is the
+// low bound.
+func (r EngagementRank) Interval() (low, high float64) {
+	mean := r.Score()
+	if r.Haikus < 2 {
+		return mean, mean
+	}
+	n := float64(r.Haikus)
+	variance := (float64(r.SquaredInteractions) - n*mean*mean) / (n - 1)
+	margin := 1.96 * math.Sqrt(max(variance, 0)/n)
+	return max(mean-margin, 0), mean + margin
+}
+
+// Rate is the interactions per impression, or zero without impressions.
+func (r EngagementRank) Rate() float64 {
+	if r.Impressions == 0 {
+		return 0
+	}
+	return float64(r.Interactions()) / float64(r.Impressions)
+}
+
+// EngagementRepository stores the engagement time series of published haikus.
+type EngagementRepository interface {
+	// AddSamples appends samples to their haikus' time series.
+	AddSamples(ctx context.Context, tx *gorm.DB, samples []entities.EngagementSample) error
+	// FindByHaikuID returns the samples of a haiku, oldest first.
+	FindByHaikuID(ctx context.Context, tx *gorm.DB, haikuID string) ([]entities.EngagementSample, error)
+	// Rank groups the haikus published since the given time and returns up
+	// to limit groups, highest score first. Each haiku counts with its
+	// latest sample; haikus never sampled are left out.
+	Rank(ctx context.Context, tx *gorm.DB, group EngagementGroup, since time.Time, limit int) ([]EngagementRank, error)
+}
+
+type engagementRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (r engagementRepositoryImpl) getDB(tx *gorm.DB) *gorm.DB {
+	if tx == nil {
+		return r.db
+	}
+
+	return tx
+}
+
+// NewEngagementRepository creates a new instance of EngagementRepository.
+func NewEngagementRepository(db *gorm.DB) EngagementRepository {
+	return &engagementRepositoryImpl{db: db}
+}
+
+// AddSamples inserts the samples in one statement.
+func (r *engagementRepositoryImpl) AddSamples(ctx context.Context, tx *gorm.DB, samples []entities.EngagementSample) error {
+	ctx, span := tracing.StartRepository(ctx, "EngagementRepository.AddSamples")
+	defer span.End()
+
+	if len(samples) == 0 {
+		return nil
+	}
+	db := r.getDB(tx)
+
+	return db.WithContext(ctx).Create(&samples).Error
+}
+
+// FindByHaikuID returns the time series of a haiku.
+func (r *engagementRepositoryImpl) FindByHaikuID(ctx context.Context, tx *gorm.DB, haikuID string) ([]entities.EngagementSample, error) {
+	ctx, span := tracing.StartRepository(ctx, "EngagementRepository.FindByHaikuID")
+	defer span.End()
+
+	var samples []entities.EngagementSample
+	db := r.getDB(tx)
+
+	err := db.WithContext(ctx).
+		Where("haiku_id = ?", haikuID).
+		Order("sampled_at ASC").
+		Find(&samples).Error
+	return samples, err
+}
+
+// Rank picks each haiku's latest sample with DISTINCT ON, then sums them by
+// the group's key.
+func (r *engagementRepositoryImpl) Rank(ctx context.Context, tx *gorm.DB, group EngagementGroup, since time.Time, limit int) ([]EngagementRank, error) {
+	ctx, span := tracing.StartRepository(ctx, "EngagementRepository.Rank")
+	defer span.End()
+
+	key, ok := engagementKeys[group]
+	if !ok {
+		return nil, fmt.Errorf("unknown engagement group %q", group)
+	}
+	var rows []struct {
+		Key         string
+		Haikus      int
+		Likes       int
+		Shares      int
+		Replies     int
+		Impressions int
+		Squares     int64
+	}
+	db := r.getDB(tx)
+
+	err := db.WithContext(ctx).Raw(fmt.Sprintf(`
+		WITH latest AS (
+			SELECT DISTINCT ON (haiku_id) haiku_id, likes, shares, replies, impressions
+			FROM engagement_samples
+			ORDER BY haiku_id, sampled_at DESC
+		)
+		SELECT %s AS key, COUNT(*) AS haikus,
+			SUM(latest.likes) AS likes, SUM(latest.shares) AS shares,
+			SUM(latest.replies) AS replies, SUM(latest.impressions) AS impressions,
+			SUM(POWER(latest.likes + 2 * latest.shares + latest.replies, 2))::bigint AS squares
+		FROM latest
+		JOIN haikus ON haikus.id = latest.haiku_id
+		JOIN posts ON posts.id = haikus.post_id
+		WHERE haikus.published_at >= ?
+		GROUP BY 1
+		ORDER BY (SUM(latest.likes) + 2 * SUM(latest.shares) + SUM(latest.replies))::float / COUNT(*) DESC, 1
+		LIMIT ?`, key), since, limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank engagement by %s: %w", group, err)
+	}
+
+	ranks := make([]EngagementRank, 0, len(rows))
+	for _, row := range rows {
+		ranks = append(ranks, EngagementRank{
+			Key:    row.Key,
+			Haikus: row.Haikus,
+			Engagement: entities.Engagement{
+				Likes:       row.Likes,
+				Shares:      row.Shares,
+				Replies:     row.Replies,
+				Impressions: row.Impressions,
+			},
+			SquaredInteractions: row.Squares,
+		})
+	}
+	return ranks, nil
+}
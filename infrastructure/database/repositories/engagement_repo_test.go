@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+)
+
+func TestEngagementRankScore(t *testing.T) {
+	tests := []struct {
+		name      string
+		rank      EngagementRank
+		wantScore float64
+		wantRate  float64
+	}{
+		{"no haikus", EngagementRank{}, 0, 0},
+		{"shares weigh double", EngagementRank{Haikus: 2, Engagement: entities.Engagement{Likes: 4, Shares: 2, Replies: 2, Impressions: 100}}, 5, 0.1},
+		{"no impressions", EngagementRank{Haikus: 1, Engagement: entities.Engagement{Likes: 3}}, 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rank.Score(); got != tt.wantScore {
+				t.Errorf("Score() = %v, want %v", got, tt.wantScore)
+			}
+			if got := tt.rank.Rate(); got != tt.wantRate {
+				t.Errorf("Rate() = %v, want %v", got, tt.wantRate)
+			}
+		})
+	}
+}
+
+func TestEngagementRankInterval(t *testing.T) {
+	tests := []struct {
+		name      string
+		haikus    int
+		likes     int
+		squared   int64
+		low, high float64
+	}{
+		{"no haikus", 0, 0, 0, 0, 0},
+		{"one haiku", 1, 3, 9, 3, 3},
+		{"no spread", 2, 6, 18, 3, 3},
+		{"spread", 4, 8, 64, 0, 2 + 1.96*2},
+		{"narrow spread", 4, 40, 404, 10 - 1.96*0.5773502691896258, 10 + 1.96*0.5773502691896258},
+		{"squares below the mean", 3, 9, 0, 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rank := EngagementRank{Haikus: tt.haikus, Engagement: entities.Engagement{Likes: tt.likes}, SquaredInteractions: tt.squared}
+			low, high := rank.Interval()
+			if math.IsNaN(low) || math.IsNaN(high) {
+				t.Fatalf("Interval() = (%v, %v), want no NaN", low, high)
+			}
+			if low < 0 || low > high {
+				t.Fatalf("Interval() = (%v, %v), want 0 <= low <= high", low, high)
+			}
+			if math.Abs(low-tt.low) > 1e-9 || math.Abs(high-tt.high) > 1e-9 {
+				t.Errorf("Interval() = (%v, %v), want (%v, %v)", low, high, tt.low, tt.high)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+)
+
+// Page selects a window of a sorted result set.
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// HaikuFilter narrows a haiku listing. Zero fields match every haiku.
+type HaikuFilter struct {
+	State    entities.HaikuState
+	Platform entities.Platform
+	// CreatedFrom is inclusive, CreatedTo exclusive.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+}
+
+// PostFilter narrows a post listing. Zero fields match every post.
+type PostFilter struct {
+	Platform entities.Platform
+	// Query matches posts whose text or author username contains it, ignoring case.
+	Query string
+	// CreatedFrom is inclusive, CreatedTo exclusive.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+}
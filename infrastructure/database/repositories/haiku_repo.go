@@ -3,8 +3,10 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/tracing"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -16,11 +18,42 @@ type HaikuRepository interface {
 	FindByIDForUpdate(ctx context.Context, tx *gorm.DB, id string) (*entities.Haiku, error)
 	// Saves a haiku (within the given transaction)
 	Save(ctx context.Context, tx *gorm.DB, haiku *entities.Haiku) error
-	FindOldestUnprocessedPost(ctx context.Context) (*entities.Post, error)
+	// FindNextUnprocessedPost returns the post without a haiku that has the
+	// highest priority once agingPerHour is added for every hour since it
+	// was fetched.
+	FindNextUnprocessedPost(ctx context.Context, agingPerHour float64) (*entities.Post, error)
 	// Create inserts a new Haiku record into the database.
 	Create(ctx context.Context, tx *gorm.DB, haiku *entities.Haiku) error
 
-	FindOldestByState(ctx context.Context, tx *gorm.DB, state entities.HaikuState) (*entities.Haiku, error)
+	// FindNextByState returns the haiku in state that has the highest
+	// priority once agingPerHour is added for every hour since it was
+	// created. Ties go to the oldest.
+	FindNextByState(ctx context.Context, tx *gorm.DB, state entities.HaikuState, agingPerHour float64) (*entities.Haiku, error)
+	// FindByPostID returns the newest haiku of a post.
+	FindByPostID(ctx context.Context, tx *gorm.DB, postID string) (*entities.Haiku, error)
+	// FindByMentionID returns the haiku requested by a mention.
+	FindByMentionID(ctx context.Context, tx *gorm.DB, mentionID string) (*entities.Haiku, error)
+	// CountRequestedSince returns the number of haikus an author requested
+	// by mention since the given time.
+	CountRequestedSince(ctx context.Context, tx *gorm.DB, authorID string, since time.Time) (int64, error)
+	// CountByAuthorsSince returns, for each of the given authors that has
+	// any, the number of haikus of their posts created since the given time.
+	CountByAuthorsSince(ctx context.Context, tx *gorm.DB, platform entities.Platform, authorIDs []string, since time.Time) (map[string]int, error)
+	// FindPublishedSince returns the haikus published since the given time,
+	// oldest first, with their posts attached.
+	FindPublishedSince(ctx context.Context, tx *gorm.DB, since time.Time) ([]entities.Haiku, error)
+	// FindStale returns the haikus in state last updated before the given
+	// time, oldest first.
+	FindStale(ctx context.Context, tx *gorm.DB, state entities.HaikuState, before time.Time) ([]entities.Haiku, error)
+	// CountByState returns the number of haikus in each state that has any.
+	CountByState(ctx context.Context, tx *gorm.DB) (map[entities.HaikuState]int64, error)
+	// List returns a page of the haikus matching filter, newest first, with
+	// their posts attached, and the number of matching haikus.
+	List(ctx context.Context, tx *gorm.DB, filter HaikuFilter, page Page) ([]entities.Haiku, int64, error)
+	// AddEvent appends an event to a haiku's history.
+	AddEvent(ctx context.Context, tx *gorm.DB, event *entities.HaikuEvent) error
+	// FindEvents returns the history of a haiku, oldest first.
+	FindEvents(ctx context.Context, tx *gorm.DB, haikuID string) ([]entities.HaikuEvent, error)
 }
 
 type haikuRepositoryImpl struct {
@@ -43,17 +76,23 @@ func NewHaikuRepository(db *gorm.DB) HaikuRepository {
 // Create inserts a new Haiku record into the database.
 // It uses the provided transaction if not nil, otherwise falls back to the base DB.
 func (r *haikuRepositoryImpl) Create(ctx context.Context, tx *gorm.DB, haiku *entities.Haiku) error {
+	ctx, span := tracing.StartRepository(ctx, "HaikuRepository.Create")
+	defer span.End()
+
 	db := r.getDB(tx)
 
 	return db.WithContext(ctx).Create(haiku).Error
 }
 
-// FindByID uses the provided transaction (or the base DB if tx is nil) to retrieve a Haiku.
+// FindByID uses the provided transaction (or the base DB if tx is nil) to retrieve a Haiku with its Post.
 func (r *haikuRepositoryImpl) FindByID(ctx context.Context, tx *gorm.DB, id string) (*entities.Haiku, error) {
+	ctx, span := tracing.StartRepository(ctx, "HaikuRepository.FindByID")
+	defer span.End()
+
 	var h entities.Haiku
 	db := r.getDB(tx)
 
-	if err := db.WithContext(ctx).First(&h, "id = ?", id).Error; err != nil {
+	if err := db.WithContext(ctx).Preload("Post").First(&h, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &h, nil
@@ -61,6 +100,9 @@ func (r *haikuRepositoryImpl) FindByID(ctx context.Context, tx *gorm.DB, id stri
 
 // FindByIDForUpdate uses row-level locking (FOR UPDATE) to retrieve a Haiku.
 func (r *haikuRepositoryImpl) FindByIDForUpdate(ctx context.Context, tx *gorm.DB, id string) (*entities.Haiku, error) {
+	ctx, span := tracing.StartRepository(ctx, "HaikuRepository.FindByIDForUpdate")
+	defer span.End()
+
 	var h entities.Haiku
 	db := r.getDB(tx)
 
@@ -74,45 +116,283 @@ func (r *haikuRepositoryImpl) FindByIDForUpdate(ctx context.Context, tx *gorm.DB
 
 // Save persists the haiku within the provided transaction.
 func (r *haikuRepositoryImpl) Save(ctx context.Context, tx *gorm.DB, haiku *entities.Haiku) error {
+	ctx, span := tracing.StartRepository(ctx, "HaikuRepository.Save")
+	defer span.End()
+
 	db := r.getDB(tx)
 
 	return db.WithContext(ctx).Save(haiku).Error
 }
 
-// FindOldestUnprocessedPost returns the oldest post that does not have an associated haiku.
+// FindNextUnprocessedPost returns the highest-priority post that does not have an associated haiku.
 // It uses a NOT EXISTS clause for efficiency.
-func (r *haikuRepositoryImpl) FindOldestUnprocessedPost(ctx context.Context) (*entities.Post, error) {
+func (r *haikuRepositoryImpl) FindNextUnprocessedPost(ctx context.Context, agingPerHour float64) (*entities.Post, error) {
+	ctx, span := tracing.StartRepository(ctx, "HaikuRepository.FindNextUnprocessedPost")
+	defer span.End()
+
 	var post entities.Post
 	// Using NOT EXISTS avoids the overhead of a join when checking for missing haiku records.
 	err := r.db.WithContext(ctx).
 		Where("NOT EXISTS (SELECT 1 FROM haikus WHERE haikus.post_id = posts.id)").
+		Order(agedPriority("fetched_at", agingPerHour)).
 		Order("created_at ASC").
 		Limit(1).
 		First(&post).Error
 
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("no unprocessed post found")
+			return nil, fmt.Errorf("no unprocessed post found: %w", err)
 		}
 		return nil, err
 	}
 	return &post, nil
 }
 
-func (r *haikuRepositoryImpl) FindOldestByState(ctx context.Context, tx *gorm.DB, state entities.HaikuState) (*entities.Haiku, error) {
+func (r *haikuRepositoryImpl) FindNextByState(ctx context.Context, tx *gorm.DB, state entities.HaikuState, agingPerHour float64) (*entities.Haiku, error) {
+	ctx, span := tracing.StartRepository(ctx, "HaikuRepository.FindNextByState")
+	defer span.End()
+
 	var h entities.Haiku
 	db := r.getDB(tx)
 
 	err := db.Preload("Post").WithContext(ctx).
 		Where("state = ?", state).
+		Order(agedPriority("created_at", agingPerHour)).
 		Order("created_at ASC").
 		Limit(1).
 		First(&h).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("no haiku found with state %s", state)
+			return nil, fmt.Errorf("no haiku found with state %s: %w", state, err)
 		}
 		return nil, fmt.Errorf("failed to fetch haiku by state: %w", err)
 	}
 	return &h, nil
 }
+
+// FindByPostID returns the newest haiku of the post.
+func (r *haikuRepositoryImpl) FindByPostID(ctx context.Context, tx *gorm.DB, postID string) (*entities.Haiku, error) {
+	ctx, span := tracing.StartRepository(ctx, "HaikuRepository.FindByPostID")
+	defer span.End()
+
+	var h entities.Haiku
+	db := r.getDB(tx)
+
+	err := db.WithContext(ctx).
+		Where("post_id = ?", postID).
+		Order("created_at DESC").
+		First(&h).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find haiku of post %s: %w", postID, err)
+	}
+	return &h, nil
+}
+
+// FindByMentionID returns the haiku requested by the mention.
+func (r *haikuRepositoryImpl) FindByMentionID(ctx context.Context, tx *gorm.DB, mentionID string) (*entities.Haiku, error) {
+	ctx, span := tracing.StartRepository(ctx, "HaikuRepository.FindByMentionID")
+	defer span.End()
+
+	var h entities.Haiku
+	db := r.getDB(tx)
+
+	if err := db.WithContext(ctx).First(&h, "mention_id = ?", mentionID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find haiku requested by mention %s: %w", mentionID, err)
+	}
+	return &h, nil
+}
+
+// CountRequestedSince counts the author's requests, whatever became of them.
+func (r *haikuRepositoryImpl) CountRequestedSince(ctx context.Context, tx *gorm.DB, authorID string, since time.Time) (int64, error) {
+	ctx, span := tracing.StartRepository(ctx, "HaikuRepository.CountRequestedSince")
+	defer span.End()
+
+	var count int64
+	db := r.getDB(tx)
+
+	err := db.WithContext(ctx).
+		Model(&entities.Haiku{}).
+		Where("requested_by = ? AND created_at >= ?", authorID, since).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count haikus requested by %s: %w", authorID, err)
+	}
+	return count, nil
+}
+
+// CountByAuthorsSince joins posts to group haikus by author.
+func (r *haikuRepositoryImpl) CountByAuthorsSince(ctx context.Context, tx *gorm.DB, platform entities.Platform, authorIDs []string, since time.Time) (map[string]int, error) {
+	ctx, span := tracing.StartRepository(ctx, "HaikuRepository.CountByAuthorsSince")
+	defer span.End()
+
+	counts := make(map[string]int)
+	if len(authorIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		AuthorID string
+		Count    int
+	}
+	db := r.getDB(tx)
+
+	err := db.WithContext(ctx).
+		Model(&entities.Haiku{}).
+		Select("posts.author->>'ID' AS author_id, COUNT(*) AS count").
+		Joins("JOIN posts ON posts.id = haikus.post_id").
+		Where("posts.platform = ? AND posts.author->>'ID' IN ? AND haikus.created_at >= ?", platform, authorIDs, since).
+		Group("posts.author->>'ID'").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count haikus by author: %w", err)
+	}
+
+	for _, row := range rows {
+		counts[row.AuthorID] = row.Count
+	}
+	return counts, nil
+}
+
+// agedPriority orders rows by priority plus agingPerHour for every hour
+// since column, highest first. It is a raw column rather than an
+// expression with bound values, which First would replace by its own
+// ORDER BY instead of appending to.
+func agedPriority(column string, agingPerHour float64) clause.OrderByColumn {
+	return clause.OrderByColumn{
+		Column: clause.Column{
+			Name: fmt.Sprintf("priority + %g * EXTRACT(EPOCH FROM now() - %s) / 3600", agingPerHour, column),
+			Raw:  true,
+		},
+		Desc: true,
+	}
+}
+
+// FindPublishedSince returns the haikus with a reply published since the
+// given time.
+func (r *haikuRepositoryImpl) FindPublishedSince(ctx context.Context, tx *gorm.DB, since time.Time) ([]entities.Haiku, error) {
+	ctx, span := tracing.StartRepository(ctx, "HaikuRepository.FindPublishedSince")
+	defer span.End()
+
+	var haikus []entities.Haiku
+	db := r.getDB(tx)
+
+	err := db.WithContext(ctx).
+		Preload("Post").
+		Where("reply_id IS NOT NULL AND published_at >= ?", since).
+		Order("published_at ASC").
+		Find(&haikus).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find published haikus: %w", err)
+	}
+	return haikus, nil
+}
+
+// FindStale returns the haikus in state not updated since before.
+func (r *haikuRepositoryImpl) FindStale(ctx context.Context, tx *gorm.DB, state entities.HaikuState, before time.Time) ([]entities.Haiku, error) {
+	ctx, span := tracing.StartRepository(ctx, "HaikuRepository.FindStale")
+	defer span.End()
+
+	var haikus []entities.Haiku
+	db := r.getDB(tx)
+
+	err := db.WithContext(ctx).
+		Where("state = ? AND updated_at < ?", state, before).
+		Order("updated_at ASC").
+		Find(&haikus).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stale %s haikus: %w", state, err)
+	}
+	return haikus, nil
+}
+
+// CountByState groups haikus by state.
+func (r *haikuRepositoryImpl) CountByState(ctx context.Context, tx *gorm.DB) (map[entities.HaikuState]int64, error) {
+	ctx, span := tracing.StartRepository(ctx, "HaikuRepository.CountByState")
+	defer span.End()
+
+	var rows []struct {
+		State entities.HaikuState
+		Count int64
+	}
+	db := r.getDB(tx)
+
+	err := db.WithContext(ctx).
+		Model(&entities.Haiku{}).
+		Select("state, COUNT(*) AS count").
+		Group("state").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count haikus by state: %w", err)
+	}
+
+	counts := make(map[entities.HaikuState]int64, len(rows))
+	for _, row := range rows {
+		counts[row.State] = row.Count
+	}
+	return counts, nil
+}
+
+// List filters haikus, joining posts to filter by platform.
+func (r *haikuRepositoryImpl) List(ctx context.Context, tx *gorm.DB, filter HaikuFilter, page Page) ([]entities.Haiku, int64, error) {
+	ctx, span := tracing.StartRepository(ctx, "HaikuRepository.List")
+	defer span.End()
+
+	db := r.getDB(tx)
+
+	query := db.WithContext(ctx).Model(&entities.Haiku{})
+	if filter.State != "" {
+		query = query.Where("haikus.state = ?", filter.State)
+	}
+	if filter.Platform != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM posts WHERE posts.id = haikus.post_id AND posts.platform = ?)", filter.Platform)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		query = query.Where("haikus.created_at >= ?", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		query = query.Where("haikus.created_at < ?", filter.CreatedTo)
+	}
+	// A new session lets the count and the page query share the conditions.
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count haikus: %w", err)
+	}
+
+	var haikus []entities.Haiku
+	err := query.Preload("Post").
+		Order("haikus.created_at DESC, haikus.id").
+		Limit(page.Limit).
+		Offset(page.Offset).
+		Find(&haikus).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list haikus: %w", err)
+	}
+	return haikus, total, nil
+}
+
+// AddEvent inserts the event.
+func (r *haikuRepositoryImpl) AddEvent(ctx context.Context, tx *gorm.DB, event *entities.HaikuEvent) error {
+	ctx, span := tracing.StartRepository(ctx, "HaikuRepository.AddEvent")
+	defer span.End()
+
+	db := r.getDB(tx)
+
+	return db.WithContext(ctx).Create(event).Error
+}
+
+// FindEvents returns the events of a haiku in insertion order.
+func (r *haikuRepositoryImpl) FindEvents(ctx context.Context, tx *gorm.DB, haikuID string) ([]entities.HaikuEvent, error) {
+	ctx, span := tracing.StartRepository(ctx, "HaikuRepository.FindEvents")
+	defer span.End()
+
+	var events []entities.HaikuEvent
+	db := r.getDB(tx)
+
+	err := db.WithContext(ctx).
+		Where("haiku_id = ?", haikuID).
+		Order("id ASC").
+		Find(&events).Error
+	return events, err
+}
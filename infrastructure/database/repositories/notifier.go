@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/tracing"
+	"gorm.io/gorm"
+)
+
+// Notifier publishes change notifications to listeners in other processes.
+type Notifier interface {
+	// Notify sends payload on channel. Inside a transaction the notification
+	// is only delivered once the transaction commits.
+	Notify(ctx context.Context, tx *gorm.DB, channel, payload string) error
+}
+
+type notifierImpl struct {
+	db *gorm.DB
+}
+
+func (n notifierImpl) getDB(tx *gorm.DB) *gorm.DB {
+	if tx == nil {
+		return n.db
+	}
+
+	return tx
+}
+
+// NewNotifier creates a Notifier backed by Postgres NOTIFY.
+func NewNotifier(db *gorm.DB) Notifier {
+	return &notifierImpl{db: db}
+}
+
+// Notify issues pg_notify, which is transactional like any other statement.
+func (n *notifierImpl) Notify(ctx context.Context, tx *gorm.DB, channel, payload string) error {
+	ctx, span := tracing.StartRepository(ctx, "Notifier.Notify")
+	defer span.End()
+
+	db := n.getDB(tx)
+
+	return db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", channel, payload).Error
+}
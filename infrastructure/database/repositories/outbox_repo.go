@@ -0,0 +1,173 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/tracing"
+	"github.com/guregu/null"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OutboxRepository stores publish requests and the log of their delivery attempts.
+type OutboxRepository interface {
+	// Create inserts an entry unless one with the same dedupe key exists.
+	Create(ctx context.Context, tx *gorm.DB, entry *entities.OutboxEntry) error
+	// FindDueForUpdate locks the oldest pending entry whose next attempt is due,
+	// skipping entries locked by other dispatchers.
+	FindDueForUpdate(ctx context.Context, tx *gorm.DB, now time.Time) (*entities.OutboxEntry, error)
+	// Save persists an entry.
+	Save(ctx context.Context, tx *gorm.DB, entry *entities.OutboxEntry) error
+	// AddDelivery appends a delivery attempt to the log.
+	AddDelivery(ctx context.Context, tx *gorm.DB, delivery *entities.OutboxDelivery) error
+	// FindByHaikuID returns every entry created for a haiku, oldest first.
+	FindByHaikuID(ctx context.Context, tx *gorm.DB, haikuID string) ([]entities.OutboxEntry, error)
+	// FindByReceipt returns the entry delivered as the platform post receipt.
+	FindByReceipt(ctx context.Context, tx *gorm.DB, platform entities.Platform, receipt string) (*entities.OutboxEntry, error)
+	// FindDeliveries returns the delivery attempts of an entry, oldest first.
+	FindDeliveries(ctx context.Context, tx *gorm.DB, outboxID string) ([]entities.OutboxDelivery, error)
+	// LastDeliveredAt returns when the most recent entry was delivered; invalid if none was.
+	LastDeliveredAt(ctx context.Context, tx *gorm.DB) (null.Time, error)
+}
+
+type outboxRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (r outboxRepositoryImpl) getDB(tx *gorm.DB) *gorm.DB {
+	if tx == nil {
+		return r.db
+	}
+
+	return tx
+}
+
+// NewOutboxRepository creates a new instance of OutboxRepository.
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepositoryImpl{db: db}
+}
+
+// Create inserts the entry. A conflicting dedupe key is not an error: the
+// publication was already requested and the existing entry stands.
+func (r *outboxRepositoryImpl) Create(ctx context.Context, tx *gorm.DB, entry *entities.OutboxEntry) error {
+	ctx, span := tracing.StartRepository(ctx, "OutboxRepository.Create")
+	defer span.End()
+
+	db := r.getDB(tx)
+
+	return db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "dedupe_key"}},
+			DoNothing: true,
+		}).
+		Create(entry).Error
+}
+
+// FindDueForUpdate uses FOR UPDATE SKIP LOCKED so concurrent dispatchers never claim the same entry.
+func (r *outboxRepositoryImpl) FindDueForUpdate(ctx context.Context, tx *gorm.DB, now time.Time) (*entities.OutboxEntry, error) {
+	ctx, span := tracing.StartRepository(ctx, "OutboxRepository.FindDueForUpdate")
+	defer span.End()
+
+	var entry entities.OutboxEntry
+	db := r.getDB(tx)
+
+	err := db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= ?", entities.OutboxStatusPending, now).
+		Order("next_attempt_at ASC").
+		Limit(1).
+		First(&entry).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("no outbox entry due: %w", err)
+		}
+		return nil, fmt.Errorf("failed to fetch due outbox entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// Save persists the entry within the provided transaction.
+func (r *outboxRepositoryImpl) Save(ctx context.Context, tx *gorm.DB, entry *entities.OutboxEntry) error {
+	ctx, span := tracing.StartRepository(ctx, "OutboxRepository.Save")
+	defer span.End()
+
+	db := r.getDB(tx)
+
+	return db.WithContext(ctx).Save(entry).Error
+}
+
+// AddDelivery inserts the delivery attempt.
+func (r *outboxRepositoryImpl) AddDelivery(ctx context.Context, tx *gorm.DB, delivery *entities.OutboxDelivery) error {
+	ctx, span := tracing.StartRepository(ctx, "OutboxRepository.AddDelivery")
+	defer span.End()
+
+	db := r.getDB(tx)
+
+	return db.WithContext(ctx).Create(delivery).Error
+}
+
+// FindByHaikuID returns the entries for a haiku.
+func (r *outboxRepositoryImpl) FindByHaikuID(ctx context.Context, tx *gorm.DB, haikuID string) ([]entities.OutboxEntry, error) {
+	ctx, span := tracing.StartRepository(ctx, "OutboxRepository.FindByHaikuID")
+	defer span.End()
+
+	var entries []entities.OutboxEntry
+	db := r.getDB(tx)
+
+	err := db.WithContext(ctx).
+		Where("haiku_id = ?", haikuID).
+		Order("created_at ASC").
+		Find(&entries).Error
+	return entries, err
+}
+
+// FindByReceipt returns the entry whose publication has the given receipt.
+func (r *outboxRepositoryImpl) FindByReceipt(ctx context.Context, tx *gorm.DB, platform entities.Platform, receipt string) (*entities.OutboxEntry, error) {
+	ctx, span := tracing.StartRepository(ctx, "OutboxRepository.FindByReceipt")
+	defer span.End()
+
+	var entry entities.OutboxEntry
+	db := r.getDB(tx)
+
+	err := db.WithContext(ctx).
+		Where("platform = ? AND receipt = ?", platform, receipt).
+		First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// FindDeliveries returns the attempts for an entry.
+func (r *outboxRepositoryImpl) FindDeliveries(ctx context.Context, tx *gorm.DB, outboxID string) ([]entities.OutboxDelivery, error) {
+	ctx, span := tracing.StartRepository(ctx, "OutboxRepository.FindDeliveries")
+	defer span.End()
+
+	var deliveries []entities.OutboxDelivery
+	db := r.getDB(tx)
+
+	err := db.WithContext(ctx).
+		Where("outbox_id = ?", outboxID).
+		Order("attempt ASC").
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// LastDeliveredAt returns the latest delivery time.
+func (r *outboxRepositoryImpl) LastDeliveredAt(ctx context.Context, tx *gorm.DB) (null.Time, error) {
+	ctx, span := tracing.StartRepository(ctx, "OutboxRepository.LastDeliveredAt")
+	defer span.End()
+
+	var last null.Time
+	db := r.getDB(tx)
+
+	err := db.WithContext(ctx).
+		Model(&entities.OutboxEntry{}).
+		Select("MAX(delivered_at)").
+		Row().
+		Scan(&last)
+	return last, err
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/tracing"
 	"gorm.io/gorm"
 )
 
@@ -16,6 +17,9 @@ type PostRepository interface {
 	SaveBatch(ctx context.Context, tx *gorm.DB, posts []entities.Post) error
 	// FindByID retrieves a Post by its ID.
 	FindByID(ctx context.Context, tx *gorm.DB, id string) (*entities.Post, error)
+	// List returns a page of the posts matching filter, newest first, and the
+	// number of matching posts.
+	List(ctx context.Context, tx *gorm.DB, filter PostFilter, page Page) ([]entities.Post, int64, error)
 	// You can add other methods as needed.
 }
 
@@ -39,6 +43,9 @@ func NewPostRepository(db *gorm.DB) PostRepository {
 // Create inserts a new Post record into the database.
 // If tx is nil, the base DB is used.
 func (r *postRepositoryImpl) Create(ctx context.Context, tx *gorm.DB, post *entities.Post) error {
+	ctx, span := tracing.StartRepository(ctx, "PostRepository.Create")
+	defer span.End()
+
 	db := r.getDB(tx)
 
 	return db.WithContext(ctx).Create(post).Error
@@ -46,6 +53,9 @@ func (r *postRepositoryImpl) Create(ctx context.Context, tx *gorm.DB, post *enti
 
 // SaveBatch inserts multiple Post records.
 func (r *postRepositoryImpl) SaveBatch(ctx context.Context, tx *gorm.DB, posts []entities.Post) error {
+	ctx, span := tracing.StartRepository(ctx, "PostRepository.SaveBatch")
+	defer span.End()
+
 	db := r.getDB(tx)
 
 	// Using Create with a slice will insert all records in one call.
@@ -54,6 +64,9 @@ func (r *postRepositoryImpl) SaveBatch(ctx context.Context, tx *gorm.DB, posts [
 
 // FindByID retrieves a Post by its ID.
 func (r *postRepositoryImpl) FindByID(ctx context.Context, tx *gorm.DB, id string) (*entities.Post, error) {
+	ctx, span := tracing.StartRepository(ctx, "PostRepository.FindByID")
+	defer span.End()
+
 	var post entities.Post
 	db := r.getDB(tx)
 
@@ -62,3 +75,44 @@ func (r *postRepositoryImpl) FindByID(ctx context.Context, tx *gorm.DB, id strin
 	}
 	return &post, nil
 }
+
+// List filters posts. The query is matched with ILIKE, so % and _ in it act as wildcards.
+func (r *postRepositoryImpl) List(ctx context.Context, tx *gorm.DB, filter PostFilter, page Page) ([]entities.Post, int64, error) {
+	ctx, span := tracing.StartRepository(ctx, "PostRepository.List")
+	defer span.End()
+
+	db := r.getDB(tx)
+
+	query := db.WithContext(ctx).Model(&entities.Post{})
+	if filter.Platform != "" {
+		query = query.Where("platform = ?", filter.Platform)
+	}
+	if filter.Query != "" {
+		pattern := "%" + filter.Query + "%"
+		query = query.Where("(text ILIKE ? OR author->>'Username' ILIKE ?)", pattern, pattern)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		query = query.Where("created_at >= ?", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		query = query.Where("created_at < ?", filter.CreatedTo)
+	}
+	// A new session lets the count and the page query share the conditions.
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count posts: %w", err)
+	}
+
+	var posts []entities.Post
+	err := query.
+		Order("created_at DESC, id").
+		Limit(page.Limit).
+		Offset(page.Offset).
+		Find(&posts).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list posts: %w", err)
+	}
+	return posts, total, nil
+}
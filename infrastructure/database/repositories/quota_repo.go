@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/tracing"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// QuotaRepository stores per-period API consumption counters.
+type QuotaRepository interface {
+	// Increment atomically adds amount to the counter, creating it if needed.
+	Increment(ctx context.Context, tx *gorm.DB, provider, resource, period string, amount int64) error
+	// Reserve atomically adds amount to the counter, creating it if needed, unless
+	// the counter would exceed limit. It reports whether amount was added.
+	Reserve(ctx context.Context, tx *gorm.DB, provider, resource, period string, amount, limit int64) (bool, error)
+	// FindUsage returns the consumed amount, or 0 if nothing was recorded.
+	FindUsage(ctx context.Context, tx *gorm.DB, provider, resource, period string) (int64, error)
+}
+
+type quotaRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (r quotaRepositoryImpl) getDB(tx *gorm.DB) *gorm.DB {
+	if tx == nil {
+		return r.db
+	}
+
+	return tx
+}
+
+// NewQuotaRepository creates a new instance of QuotaRepository.
+func NewQuotaRepository(db *gorm.DB) QuotaRepository {
+	return &quotaRepositoryImpl{db: db}
+}
+
+// Increment upserts the counter row so concurrent writers never lose updates.
+func (r *quotaRepositoryImpl) Increment(ctx context.Context, tx *gorm.DB, provider, resource, period string, amount int64) error {
+	ctx, span := tracing.StartRepository(ctx, "QuotaRepository.Increment")
+	defer span.End()
+
+	db := r.getDB(tx)
+
+	usage := entities.QuotaUsage{
+		Provider: provider,
+		Resource: resource,
+		Period:   period,
+		Used:     amount,
+	}
+
+	return db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "provider"}, {Name: "resource"}, {Name: "period"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"used":       gorm.Expr("quota_usage.used + EXCLUDED.used"),
+				"updated_at": gorm.Expr("now()"),
+			}),
+		}).
+		Create(&usage).Error
+}
+
+// Reserve checks the limit and adds amount in a single upsert, so concurrent
+// reservations can never overshoot the limit together.
+func (r *quotaRepositoryImpl) Reserve(ctx context.Context, tx *gorm.DB, provider, resource, period string, amount, limit int64) (bool, error) {
+	ctx, span := tracing.StartRepository(ctx, "QuotaRepository.Reserve")
+	defer span.End()
+
+	if amount > limit {
+		return false, nil
+	}
+
+	db := r.getDB(tx)
+
+	usage := entities.QuotaUsage{
+		Provider: provider,
+		Resource: resource,
+		Period:   period,
+		Used:     amount,
+	}
+
+	result := db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "provider"}, {Name: "resource"}, {Name: "period"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"used":       gorm.Expr("quota_usage.used + EXCLUDED.used"),
+				"updated_at": gorm.Expr("now()"),
+			}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				gorm.Expr("quota_usage.used + EXCLUDED.used <= ?", limit),
+			}},
+		}).
+		Create(&usage)
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindUsage returns the consumed amount for a period.
+func (r *quotaRepositoryImpl) FindUsage(ctx context.Context, tx *gorm.DB, provider, resource, period string) (int64, error) {
+	ctx, span := tracing.StartRepository(ctx, "QuotaRepository.FindUsage")
+	defer span.End()
+
+	var used int64
+	db := r.getDB(tx)
+
+	err := db.WithContext(ctx).
+		Model(&entities.QuotaUsage{}).
+		Select("COALESCE(SUM(used), 0)").
+		Where("provider = ? AND resource = ? AND period = ?", provider, resource, period).
+		Scan(&used).Error
+	return used, err
+}
@@ -0,0 +1,73 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Mux is where endpoints are registered, e.g. a Server.
+type Mux interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// Server serves the bot's operational endpoints, such as /metrics and /readyz.
+type Server struct {
+	mux *http.ServeMux
+	srv *http.Server
+}
+
+// New creates a server listening on addr, e.g. ":8080". Register handlers
+// with Handle before calling Start.
+func New(addr string) *Server {
+	mux := http.NewServeMux()
+
+	return &Server{
+		mux: mux,
+		srv: &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+// Handle registers handler for pattern, using http.ServeMux pattern syntax.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Start binds the listen address and serves in the background. It returns an
+// error if the address cannot be bound.
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("HTTP server stopped", "error", err)
+		}
+	}()
+	slog.Info("HTTP server listening", "addr", ln.Addr().String())
+	return nil
+}
+
+// Shutdown stops accepting connections and waits for active requests until ctx expires.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// WriteJSON writes v as the JSON body of a response with the given status.
+func WriteJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.WarnContext(r.Context(), "Failed to write response", "error", err)
+	}
+}
@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/dapplux/twitter-haiku-bot/config"
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New builds a logger writing cfg.Format ("text" or "json") records at or
+// above cfg.Level to out. Configured secrets are scrubbed from the output and
+// attributes added with With are attached to records logged with a context.
+func New(cfg config.Log, out io.Writer, secrets ...string) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %v", cfg.Level, err)
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	w := NewRedactingWriter(out, secrets...)
+
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text", "":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q, must be text or json", cfg.Format)
+	}
+
+	return slog.New(ContextHandler{Handler: handler}), nil
+}
+
+// Setup installs the logger built by New as the slog default. Output of the
+// standard log package is routed through it as well.
+func Setup(cfg config.Log, out io.Writer, secrets ...string) error {
+	logger, err := New(cfg, out, secrets...)
+	if err != nil {
+		return err
+	}
+
+	slog.SetDefault(logger)
+	return nil
+}
+
+type ctxKey struct{}
+
+// With returns a context carrying args, as accepted by slog.Logger.With, in
+// addition to any already attached. They are added to every record logged
+// with the context, e.g. through slog.InfoContext.
+func With(ctx context.Context, args ...any) context.Context {
+	attrs := append(attrsFrom(ctx), argsToAttrs(args)...)
+	return context.WithValue(ctx, ctxKey{}, attrs)
+}
+
+func attrsFrom(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	// Copy so contexts derived from the same parent never share a backing array.
+	return append([]slog.Attr(nil), attrs...)
+}
+
+func argsToAttrs(args []any) []slog.Attr {
+	var r slog.Record
+	r.Add(args...)
+
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return attrs
+}
+
+// ContextHandler adds the attributes attached to a record's context with With,
+// and the trace and span IDs of the span active in it so logs can be joined
+// with traces.
+type ContextHandler struct {
+	slog.Handler
+}
+
+func (h ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(ctxKey{}).([]slog.Attr); ok {
+		r.AddAttrs(attrs...)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h ContextHandler) WithGroup(name string) slog.Handler {
+	return ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// Haiku returns the attributes identifying a haiku in log records:
+// haiku_id, post_id, platform, state and attempt.
+func Haiku(h *entities.Haiku) []any {
+	return []any{
+		slog.String("haiku_id", h.ID),
+		slog.String("post_id", h.PostID),
+		slog.String("platform", string(h.Post.Platform)),
+		slog.String("state", string(h.State)),
+		slog.Int("attempt", h.Attempt),
+	}
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// redacted replaces secret values in log output.
+const redacted = "[REDACTED]"
+
+// RedactingWriter scrubs known secret values from everything written through it.
+// New places it under every log handler so every log line is filtered.
+type RedactingWriter struct {
+	mu       sync.Mutex
+	out      io.Writer
+	replacer *strings.Replacer
+}
+
+// NewRedactingWriter wraps out, replacing each non-empty secret with "[REDACTED]".
+// The replacer prefers earlier arguments at the same position, so secrets are
+// tried longest first and one containing another is always redacted whole.
+func NewRedactingWriter(out io.Writer, secrets ...string) *RedactingWriter {
+	secrets = slices.Clone(secrets)
+	slices.SortFunc(secrets, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a, b)
+	})
+
+	var pairs []string
+	for _, s := range slices.Compact(secrets) {
+		if s != "" {
+			pairs = append(pairs, s, redacted)
+		}
+	}
+
+	return &RedactingWriter{
+		out:      out,
+		replacer: strings.NewReplacer(pairs...),
+	}
+}
+
+// Write writes the scrubbed form of p. It reports len(p) on success so
+// callers are not confused by the length change.
+func (w *RedactingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := io.WriteString(w.out, w.replacer.Replace(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactingWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets []string
+		line    string
+		want    string
+	}{
+		{"single secret", []string{"hunter2"}, "password=hunter2", "password=[REDACTED]"},
+		{"every occurrence", []string{"abc"}, "abc and abc", "[REDACTED] and [REDACTED]"},
+		{"longer secret containing a shorter one", []string{"token", "token-secret"}, "key=token-secret", "key=[REDACTED]"},
+		{"shorter secret given last", []string{"token-secret", "token"}, "key=token-secret token", "key=[REDACTED] [REDACTED]"},
+		{"overlapping prefix", []string{"abc", "abcdef"}, "abcdefg", "[REDACTED]g"},
+		{"duplicates", []string{"abc", "abc"}, "abc", "[REDACTED]"},
+		{"empty secrets ignored", []string{""}, "nothing secret", "nothing secret"},
+		{"no secrets", nil, "plain", "plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			w := NewRedactingWriter(&out, tt.secrets...)
+			n, err := w.Write([]byte(tt.line))
+			if err != nil || n != len(tt.line) {
+				t.Fatalf("Write() = %d, %v, want %d, nil", n, err, len(tt.line))
+			}
+			if out.String() != tt.want {
+				t.Errorf("Write(%q) wrote %q, want %q", tt.line, out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "haiku_bot"
+
+var (
+	// PostsFetched counts posts returned by the platform and saved.
+	PostsFetched = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_fetched_total",
+		Help:      "Posts fetched from a platform and saved.",
+	}, []string{"platform"})
+
+	// PostsExcluded counts fetched posts dropped because their author is on
+	// the blocklist or opt-out list.
+	PostsExcluded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_excluded_total",
+		Help:      "Fetched posts dropped because their author is blocked or opted out.",
+	}, []string{"platform", "list"})
+
+	// Mentions counts mentions read for haiku requests by what became of them:
+	// requested, ignored, duplicate, rate_limited, excluded, not_found or
+	// unsupported_language.
+	Mentions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mentions_total",
+		Help:      "Mentions read for haiku requests, by outcome.",
+	}, []string{"platform", "outcome"})
+
+	// EngagementSamples counts engagement samples stored for published haikus.
+	EngagementSamples = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "engagement_samples_total",
+		Help:      "Engagement samples stored for published haikus.",
+	}, []string{"platform"})
+
+	// HaikuTransitions counts committed haiku state changes. Creation is
+	// recorded with an empty from label.
+	HaikuTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "haiku_transitions_total",
+		Help:      "Haiku state transitions, by previous and new state.",
+	}, []string{"from", "to"})
+
+	// OutboxDeliveries counts publication delivery attempts by outcome.
+	OutboxDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_deliveries_total",
+		Help:      "Outbox delivery attempts, by platform and outcome.",
+	}, []string{"platform", "outcome"})
+
+	// AICallDuration observes HTTP calls to AI models, retries included as separate calls.
+	AICallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ai_call_duration_seconds",
+		Help:      "Latency of AI model calls, by model and status code.",
+		Buckets:   []float64{0.25, 0.5, 1, 2, 5, 10, 20, 30, 60},
+	}, []string{"model", "code"})
+
+	// PlatformCallDuration observes HTTP calls to platform APIs.
+	PlatformCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "platform_call_duration_seconds",
+		Help:      "Latency of platform API calls, by endpoint and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "code"})
+)
+
+// Handler serves every registered metric in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RegisterRateLimiter exports the tokens currently available to a rate limiter.
+// Registering the same limiter name twice keeps the first registration.
+func RegisterRateLimiter(name string, tokens func() float64) {
+	gauge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "rate_limit_tokens",
+		Help:        "Requests that can be made right now without waiting on the client-side rate limiter.",
+		ConstLabels: prometheus.Labels{"limiter": name},
+	}, tokens)
+
+	register(gauge)
+}
+
+// register registers c, ignoring collectors that are already registered.
+func register(c prometheus.Collector) {
+	var already prometheus.AlreadyRegisteredError
+	if err := prometheus.Register(c); err != nil && !errors.As(err, &already) {
+		panic(err)
+	}
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/quota"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrapeTimeout bounds the database queries made while collecting.
+const scrapeTimeout = 5 * time.Second
+
+var (
+	queueDepthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "queue_depth"),
+		"Haikus currently in each state.",
+		[]string{"state"}, nil,
+	)
+	quotaUsedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "quota_used"),
+		"Consumption of a budgeted resource in the current period.",
+		[]string{"provider", "resource", "period"}, nil,
+	)
+	quotaLimitDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "quota_limit"),
+		"Configured budget of a resource per period.",
+		[]string{"provider", "resource", "period"}, nil,
+	)
+	lastPublishedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "last_published_timestamp_seconds"),
+		"Unix time of the most recent successful publication, 0 if none.",
+		nil, nil,
+	)
+)
+
+// PipelineCollector reads pipeline state from the database at scrape time, so
+// every replica reports the same values and they survive restarts. Alert on
+// "nothing posted in 12h" with:
+//
+//	time() - max(haiku_bot_last_published_timestamp_seconds) > 12 * 3600
+type PipelineCollector struct {
+	haikuRepo  repositories.HaikuRepository
+	outboxRepo repositories.OutboxRepository
+	tracker    *quota.Tracker
+}
+
+// NewPipelineCollector creates a collector over the given repositories and quota tracker.
+func NewPipelineCollector(haikuRepo repositories.HaikuRepository, outboxRepo repositories.OutboxRepository, tracker *quota.Tracker) *PipelineCollector {
+	return &PipelineCollector{
+		haikuRepo:  haikuRepo,
+		outboxRepo: outboxRepo,
+		tracker:    tracker,
+	}
+}
+
+// Register adds the collector to the default registry.
+func (c *PipelineCollector) Register() {
+	register(c)
+}
+
+// Describe implements prometheus.Collector.
+func (c *PipelineCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthDesc
+	ch <- quotaUsedDesc
+	ch <- quotaLimitDesc
+	ch <- lastPublishedDesc
+}
+
+// Collect implements prometheus.Collector. Metrics whose query fails are
+// left out of the scrape rather than reported as zero.
+func (c *PipelineCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+
+	if counts, err := c.haikuRepo.CountByState(ctx, nil); err != nil {
+		slog.Warn("Metrics: failed to count haikus by state", "error", err)
+	} else {
+		for _, state := range entities.HaikuStates {
+			ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(counts[state]), string(state))
+		}
+	}
+
+	if statuses, err := c.tracker.Statuses(ctx); err != nil {
+		slog.Warn("Metrics: failed to read quota usage", "error", err)
+	} else {
+		for _, s := range statuses {
+			labels := []string{s.Provider, s.Resource, string(s.Period)}
+			ch <- prometheus.MustNewConstMetric(quotaUsedDesc, prometheus.GaugeValue, float64(s.Used), labels...)
+			ch <- prometheus.MustNewConstMetric(quotaLimitDesc, prometheus.GaugeValue, float64(s.Limit), labels...)
+		}
+	}
+
+	if last, err := c.outboxRepo.LastDeliveredAt(ctx, nil); err != nil {
+		slog.Warn("Metrics: failed to read last publication time", "error", err)
+	} else {
+		var ts float64
+		if last.Valid {
+			ts = float64(last.Time.Unix())
+		}
+		ch <- prometheus.MustNewConstMetric(lastPublishedDesc, prometheus.GaugeValue, ts)
+	}
+}
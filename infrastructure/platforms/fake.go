@@ -0,0 +1,276 @@
+package platforms
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+)
+
+// fakeTopics seeds the text of generated posts, a few of them in
+// Japanese and Vietnamese.
+var fakeTopics = []string{
+	"A new open source database promises faster queries for analytics workloads.",
+	"Developers debate whether AI pair programmers improve code quality.",
+	"The latest language release adds generics to the standard library.",
+	"A major cloud outage took down several popular apps for hours.",
+	"Security researchers disclosed a critical flaw in a widely used library.",
+	"Startups race to ship smaller, cheaper language models for phones.",
+	"新しいオープンソースのデータベースが分析クエリを高速化する。",
+	"Các nhà phát triển tranh luận liệu trợ lý AI có cải thiện chất lượng mã hay không.",
+}
+
+// fakeStopReplyRate is the probability that the author of a post replies
+// "stop" to the bot's comment on it.
+const fakeStopReplyRate = 0.05
+
+// fakeRequestRate is the probability that a call to FetchMentions finds a
+// new request to haiku a post. Requests come from a handful of fans, so
+// per-user limits are hit now and then.
+const (
+	fakeRequestRate = 0.02
+	fakeFans        = 5
+)
+
+// fakeSearchProfile is the search profile of generated posts.
+const fakeSearchProfile = "fake search"
+
+// fakeReply is the bot's comment as the platform's audience sees it: its
+// impressions approach reach over the first day or so, and likeRate of the
+// viewers like it.
+type fakeReply struct {
+	postedAt time.Time
+	reach    int
+	likeRate float64
+}
+
+// fakeComment is a comment posted on a fake post, awaiting replies.
+type fakeComment struct {
+	id     string
+	author entities.Author
+}
+
+// FakeStats counts calls made against a FakeProvider.
+type FakeStats struct {
+	FetchRequests int
+	FetchFailures int
+	PostsRead     int
+	Comments      int
+	CommentErrors int
+	Mentions      int
+	// EngagementReads counts the replies whose metrics were read.
+	EngagementReads int
+}
+
+// FakeProvider is a PlatformProvider that generates synthetic posts and
+// accepts comments without network access. It is deterministic for a given seed.
+type FakeProvider struct {
+	mu          sync.Mutex
+	now         func() time.Time
+	rand        *rand.Rand
+	failureRate float64
+	nextID      int
+	stats       FakeStats
+	// posts remembers every generated post; unanswered holds the comments
+	// whose replies have not been fetched yet, and mentions every mention
+	// generated so far, oldest first.
+	posts      map[string]entities.Post
+	unanswered []fakeComment
+	mentions   []entities.Mention
+	// replies holds the bot's comments by ID.
+	replies map[string]fakeReply
+}
+
+// NewFakeProvider creates a fake platform. now stamps generated posts and
+// failureRate (0..1) is the probability that any call returns an error.
+func NewFakeProvider(now func() time.Time, seed int64, failureRate float64) *FakeProvider {
+	if now == nil {
+		now = time.Now
+	}
+
+	return &FakeProvider{
+		now:         now,
+		rand:        rand.New(rand.NewSource(seed)),
+		failureRate: failureRate,
+		posts:       make(map[string]entities.Post),
+		replies:     make(map[string]fakeReply),
+	}
+}
+
+// FetchPosts returns up to limit freshly generated posts.
+func (fp *FakeProvider) FetchPosts(ctx context.Context, limit int) ([]entities.Post, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	fp.stats.FetchRequests++
+	if fp.rand.Float64() < fp.failureRate {
+		fp.stats.FetchFailures++
+		return nil, fmt.Errorf("fake platform: simulated fetch failure")
+	}
+
+	posts := make([]entities.Post, 0, limit)
+	for i := 0; i < limit; i++ {
+		posts = append(posts, fp.newPost())
+	}
+
+	fp.stats.PostsRead += len(posts)
+	return posts, nil
+}
+
+// FetchPost returns a post generated earlier.
+func (fp *FakeProvider) FetchPost(ctx context.Context, postID string) (*entities.Post, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	fp.stats.FetchRequests++
+	if fp.rand.Float64() < fp.failureRate {
+		fp.stats.FetchFailures++
+		return nil, fmt.Errorf("fake platform: simulated fetch failure")
+	}
+
+	post, ok := fp.posts[postID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPostNotFound, postID)
+	}
+	fp.stats.PostsRead++
+	return &post, nil
+}
+
+// newPost generates and remembers a post written within the last day.
+// The caller must hold fp.mu.
+func (fp *FakeProvider) newPost() entities.Post {
+	fp.nextID++
+	authorID := fp.rand.Intn(50)
+	post := entities.Post{
+		ID: fmt.Sprintf("fake-%d", fp.nextID),
+		Author: entities.Author{
+			ID:       fmt.Sprintf("%d", authorID),
+			Username: fmt.Sprintf("user%d", authorID),
+		},
+		Text:          fakeTopics[fp.rand.Intn(len(fakeTopics))],
+		Likes:         fp.rand.Intn(500),
+		Shares:        fp.rand.Intn(200),
+		Replies:       fp.rand.Intn(50),
+		Platform:      entities.PlatformTwitter,
+		SearchProfile: fakeSearchProfile,
+		CreatedAt:     fp.now().Add(-time.Duration(fp.rand.Intn(24*60)) * time.Minute),
+	}
+	fp.posts[post.ID] = post
+	return post
+}
+
+// CommentOn records the comment, logs it and returns a generated reply ID.
+func (fp *FakeProvider) CommentOn(ctx context.Context, postID, message string) (string, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	if fp.rand.Float64() < fp.failureRate {
+		fp.stats.CommentErrors++
+		return "", fmt.Errorf("fake platform: simulated comment failure")
+	}
+
+	fp.stats.Comments++
+	slog.DebugContext(ctx, "Fake comment", "post_id", postID, "message", message)
+	id := fmt.Sprintf("fake-reply-%d", fp.stats.Comments)
+	fp.unanswered = append(fp.unanswered, fakeComment{id: id, author: fp.posts[postID].Author})
+	fp.replies[id] = fakeReply{
+		postedAt: fp.now(),
+		reach:    50 + fp.rand.Intn(950),
+		likeRate: 0.005 + 0.045*fp.rand.Float64(),
+	}
+	return id, nil
+}
+
+// FetchEngagement returns the metrics of the bot's comments among postIDs.
+func (fp *FakeProvider) FetchEngagement(ctx context.Context, postIDs []string) (map[string]entities.Engagement, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	fp.stats.FetchRequests++
+	if fp.rand.Float64() < fp.failureRate {
+		fp.stats.FetchFailures++
+		return nil, fmt.Errorf("fake platform: simulated fetch failure")
+	}
+
+	engagement := make(map[string]entities.Engagement, len(postIDs))
+	for _, id := range postIDs {
+		r, ok := fp.replies[id]
+		if !ok {
+			continue
+		}
+		hours := fp.now().Sub(r.postedAt).Hours()
+		impressions := int(float64(r.reach) * (1 - math.Exp(-hours/12)))
+		likes := int(float64(impressions) * r.likeRate)
+		engagement[id] = entities.Engagement{
+			Likes:       likes,
+			Shares:      likes / 5,
+			Replies:     likes / 8,
+			Impressions: impressions,
+		}
+	}
+	fp.stats.EngagementReads += len(engagement)
+	return engagement, nil
+}
+
+// FetchMentions returns up to limit mentions after sinceID, or from the
+// first one if sinceID is empty. Each call may add new mentions: now and then
+// a post's author replies to the bot's comment asking it to stop, or a fan
+// asks for a haiku of a post.
+func (fp *FakeProvider) FetchMentions(ctx context.Context, sinceID string, limit int) ([]entities.Mention, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	if fp.rand.Float64() < fp.failureRate {
+		return nil, fmt.Errorf("fake platform: simulated mentions failure")
+	}
+
+	for _, c := range fp.unanswered {
+		if fp.rand.Float64() < fakeStopReplyRate {
+			fp.addMention(c.author, "@haikubot stop", c.id)
+		}
+	}
+	fp.unanswered = nil
+	if fp.rand.Float64() < fakeRequestRate {
+		fan := fp.rand.Intn(fakeFans)
+		author := entities.Author{ID: fmt.Sprintf("fan%d", fan), Username: fmt.Sprintf("fan%d", fan)}
+		fp.addMention(author, "@haikubot haiku this please", fp.newPost().ID)
+	}
+
+	start := 0
+	for i, m := range fp.mentions {
+		if m.ID == sinceID {
+			start = i + 1
+		}
+	}
+	end := min(start+limit, len(fp.mentions))
+	return slices.Clone(fp.mentions[start:end]), nil
+}
+
+// addMention records a mention by author replying to post inReplyToID.
+// The caller must hold fp.mu.
+func (fp *FakeProvider) addMention(author entities.Author, text, inReplyToID string) {
+	fp.stats.Mentions++
+	fp.mentions = append(fp.mentions, entities.Mention{
+		ID:             fmt.Sprintf("fake-mention-%d", fp.stats.Mentions),
+		Author:         author,
+		Text:           text,
+		InReplyToID:    inReplyToID,
+		ConversationID: inReplyToID,
+		Platform:       entities.PlatformTwitter,
+		CreatedAt:      fp.now(),
+	})
+}
+
+// Stats returns a snapshot of the call counters.
+func (fp *FakeProvider) Stats() FakeStats {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	return fp.stats
+}
@@ -0,0 +1,160 @@
+package platforms
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/quota"
+)
+
+// MeteredProvider wraps a PlatformProvider and records every call against a quota.Meter.
+// Calls that would exceed a budget are refused before reaching the platform.
+type MeteredProvider struct {
+	next     PlatformProvider
+	meter    quota.Meter
+	provider string
+}
+
+// NewMeteredProvider wraps next, recording usage under the given provider name.
+func NewMeteredProvider(next PlatformProvider, meter quota.Meter, provider string) *MeteredProvider {
+	return &MeteredProvider{
+		next:     next,
+		meter:    meter,
+		provider: provider,
+	}
+}
+
+// FetchPosts reserves reads for limit posts, lowered to what is left of the
+// read budget, then records the request and releases the reads of posts the
+// platform did not return.
+func (mp *MeteredProvider) FetchPosts(ctx context.Context, limit int) ([]entities.Post, error) {
+	limit, err := mp.clamp(ctx, quota.ResourceTweetsRead, limit)
+	if err != nil {
+		return nil, err
+	}
+	if err := mp.meter.Reserve(ctx, mp.provider, quota.ResourceTweetsRead, int64(limit)); err != nil {
+		return nil, err
+	}
+
+	posts, err := mp.next.FetchPosts(ctx, limit)
+	mp.record(ctx, quota.ResourceRequests, 1)
+	if err != nil {
+		mp.release(ctx, quota.ResourceTweetsRead, int64(limit))
+		return nil, err
+	}
+
+	mp.release(ctx, quota.ResourceTweetsRead, int64(limit-len(posts)))
+	return posts, nil
+}
+
+// FetchPost reserves one read, then records the request and releases the
+// read if the post could not be fetched.
+func (mp *MeteredProvider) FetchPost(ctx context.Context, postID string) (*entities.Post, error) {
+	if err := mp.meter.Reserve(ctx, mp.provider, quota.ResourceTweetsRead, 1); err != nil {
+		return nil, err
+	}
+
+	post, err := mp.next.FetchPost(ctx, postID)
+	mp.record(ctx, quota.ResourceRequests, 1)
+	if err != nil {
+		mp.release(ctx, quota.ResourceTweetsRead, 1)
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// CommentOn reserves one write, then records the request and releases the
+// write if posting failed.
+func (mp *MeteredProvider) CommentOn(ctx context.Context, postID, message string) (string, error) {
+	if err := mp.meter.Reserve(ctx, mp.provider, quota.ResourceTweetsWritten, 1); err != nil {
+		return "", err
+	}
+
+	commentID, err := mp.next.CommentOn(ctx, postID, message)
+	mp.record(ctx, quota.ResourceRequests, 1)
+	if err != nil {
+		mp.release(ctx, quota.ResourceTweetsWritten, 1)
+		return "", err
+	}
+
+	return commentID, nil
+}
+
+// FetchMentions reserves reads for limit mentions, lowered to what is left of
+// the read budget, then records the request and releases the reads of
+// mentions the platform did not return.
+func (mp *MeteredProvider) FetchMentions(ctx context.Context, sinceID string, limit int) ([]entities.Mention, error) {
+	limit, err := mp.clamp(ctx, quota.ResourceTweetsRead, limit)
+	if err != nil {
+		return nil, err
+	}
+	if err := mp.meter.Reserve(ctx, mp.provider, quota.ResourceTweetsRead, int64(limit)); err != nil {
+		return nil, err
+	}
+
+	mentions, err := mp.next.FetchMentions(ctx, sinceID, limit)
+	mp.record(ctx, quota.ResourceRequests, 1)
+	if err != nil {
+		mp.release(ctx, quota.ResourceTweetsRead, int64(limit))
+		return nil, err
+	}
+
+	mp.release(ctx, quota.ResourceTweetsRead, int64(limit-len(mentions)))
+	return mentions, nil
+}
+
+// FetchEngagement reserves reads for every post, then records the request and
+// releases the reads of posts the platform did not return.
+func (mp *MeteredProvider) FetchEngagement(ctx context.Context, postIDs []string) (map[string]entities.Engagement, error) {
+	if err := mp.meter.Reserve(ctx, mp.provider, quota.ResourceTweetsRead, int64(len(postIDs))); err != nil {
+		return nil, err
+	}
+
+	engagement, err := mp.next.FetchEngagement(ctx, postIDs)
+	mp.record(ctx, quota.ResourceRequests, 1)
+	if err != nil {
+		mp.release(ctx, quota.ResourceTweetsRead, int64(len(postIDs)))
+		return nil, err
+	}
+
+	mp.release(ctx, quota.ResourceTweetsRead, int64(len(postIDs)-len(engagement)))
+	return engagement, nil
+}
+
+// clamp lowers limit to what is left of the resource's budget, so a nearly
+// spent budget still serves a smaller page. It fails only once nothing is left.
+func (mp *MeteredProvider) clamp(ctx context.Context, resource string, limit int) (int, error) {
+	remaining, err := mp.meter.Remaining(ctx, mp.provider, resource)
+	if err != nil {
+		return 0, err
+	}
+	if remaining <= 0 {
+		return 0, fmt.Errorf("%w: %s %s budget spent", quota.ErrBudgetExceeded, mp.provider, resource)
+	}
+	if remaining < int64(limit) {
+		return int(remaining), nil
+	}
+	return limit, nil
+}
+
+// record logs instead of failing: the platform call already happened and must not be retried
+// just because its accounting could not be stored.
+func (mp *MeteredProvider) record(ctx context.Context, resource string, amount int64) {
+	if err := mp.meter.Record(ctx, mp.provider, resource, amount); err != nil {
+		slog.WarnContext(ctx, "Failed to record quota usage", "provider", mp.provider, "resource", resource, "error", err)
+	}
+}
+
+// release logs instead of failing for the same reason; an unreleased
+// reservation only makes the budget stricter than it needs to be.
+func (mp *MeteredProvider) release(ctx context.Context, resource string, amount int64) {
+	if amount <= 0 {
+		return
+	}
+	if err := mp.meter.Release(ctx, mp.provider, resource, amount); err != nil {
+		slog.WarnContext(ctx, "Failed to release quota reservation", "provider", mp.provider, "resource", resource, "error", err)
+	}
+}
@@ -0,0 +1,52 @@
+package platforms
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/memory"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/quota"
+)
+
+func TestMeteredFetchPosts(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	month := quota.PeriodMonthly.Key(now)
+
+	tests := []struct {
+		name        string
+		used        int64
+		limit       int
+		failureRate float64
+		wantPosts   int
+		wantErr     bool
+		wantUsed    int64
+	}{
+		{"within budget", 0, 5, 0, 5, false, 5},
+		{"clamped to the remaining budget", 8, 5, 0, 2, false, 10},
+		{"budget spent", 10, 5, 0, 0, true, 10},
+		{"failed fetch releases its reservation", 0, 5, 1, 0, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := memory.NewQuotaRepository(memory.NewStore(clock))
+			_ = repo.Increment(ctx, nil, quota.ProviderTwitter, quota.ResourceTweetsRead, month, tt.used)
+			budgets := []quota.Budget{{Provider: quota.ProviderTwitter, Resource: quota.ResourceTweetsRead, Period: quota.PeriodMonthly, Limit: 10}}
+			tracker := quota.NewTracker(repo, budgets, clock)
+			mp := NewMeteredProvider(NewFakeProvider(clock, 1, tt.failureRate), tracker, quota.ProviderTwitter)
+
+			posts, err := mp.FetchPosts(ctx, tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchPosts() error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(posts) != tt.wantPosts {
+				t.Errorf("FetchPosts() returned %d posts, want %d", len(posts), tt.wantPosts)
+			}
+			if used, _ := tracker.Usage(ctx, quota.ProviderTwitter, quota.ResourceTweetsRead, quota.PeriodMonthly); used != tt.wantUsed {
+				t.Errorf("reads used = %d, want %d", used, tt.wantUsed)
+			}
+		})
+	}
+}
@@ -2,14 +2,29 @@ package platforms
 
 import (
 	"context"
+	"errors"
 
 	"github.com/dapplux/twitter-haiku-bot/entities"
 )
 
+// ErrPostNotFound is returned by FetchPost for posts that do not exist or
+// are not visible to the bot.
+var ErrPostNotFound = errors.New("post not found")
+
 // PlatformProvider defines methods for fetching posts and posting comments.
 type PlatformProvider interface {
 	// FetchPosts fetches posts from the platform.
 	FetchPosts(ctx context.Context, limit int) ([]entities.Post, error)
-	// CommentOn posts a comment on a tweet or equivalent post.
-	CommentOn(postID, message string) error
+	// FetchPost fetches a single post by its platform ID.
+	FetchPost(ctx context.Context, postID string) (*entities.Post, error)
+	// CommentOn posts a comment on a tweet or equivalent post and returns
+	// the platform ID of the comment.
+	CommentOn(ctx context.Context, postID, message string) (string, error)
+	// FetchMentions fetches up to limit posts mentioning the bot that are
+	// newer than the mention sinceID, oldest first. An empty sinceID fetches
+	// the most recent ones.
+	FetchMentions(ctx context.Context, sinceID string, limit int) ([]entities.Mention, error)
+	// FetchEngagement fetches the current public metrics of posts by their
+	// platform IDs. Posts that were deleted or are not visible are left out.
+	FetchEngagement(ctx context.Context, postIDs []string) (map[string]entities.Engagement, error)
 }
@@ -6,12 +6,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/metrics"
 	"github.com/dapplux/twitter-haiku-bot/infrastructure/transport"
 	"github.com/dghubble/oauth1"
 )
@@ -21,8 +26,14 @@ const (
 	TwitterBaseURL        = "https://api.twitter.com/2"
 	TwitterSearchEndpoint = TwitterBaseURL + "/tweets/search/recent"
 	TwitterPostEndpoint   = TwitterBaseURL + "/tweets"
+	TwitterUsersEndpoint  = TwitterBaseURL + "/users"
 	TwitterFreeAPILimit   = 10               // Free API allows 10 requests per 15 minutes
 	TwitterRateLimitReset = 15 * time.Minute // API resets every 15 minutes
+	// TwitterSearchQuery is the search FetchPosts runs unless narrowed to
+	// some languages by TwitterSearchQueryFor.
+	TwitterSearchQuery = "software news -is:retweet"
+	// twitterMaxIDs is the most tweets one lookup may ask for.
+	twitterMaxIDs = 100
 )
 
 // TwitterProvider manages API interactions using an HTTP client.
@@ -32,6 +43,13 @@ type TwitterProvider struct {
 	AccessToken       string
 	AccessTokenSecret string
 	Client            *http.Client
+	// Query is the search FetchPosts runs, recorded as the search profile of
+	// the posts it finds. It defaults to TwitterSearchQuery.
+	Query string
+
+	// userID is the authenticated account's ID, looked up on first use.
+	mu     sync.Mutex
+	userID string
 }
 
 // NewTwitterProvider initializes a Twitter API client with OAuth 1.0a and rate limiting.
@@ -41,10 +59,14 @@ func NewTwitterProvider(consumerKey, consumerSecret, accessToken, accessTokenSec
 	// Create the OAuth1-signed client.
 	httpClient := config.Client(oauth1.NoContext, token)
 	// Wrap the existing OAuth client's transport with a rate-limited transport.
-	httpClient.Transport = transport.NewRateLimitTransport(
+	// Latency is measured inside the limiter so waiting for a token is not counted;
+	// the trace span is outside it so the wait shows up in traces.
+	limiter := transport.NewRateLimitTransport(
 		float64(TwitterFreeAPILimit)/TwitterRateLimitReset.Seconds(), // Rate: 10 requests per 900 sec
-		httpClient.Transport,
+		transport.NewLatencyTransport(observeTwitterCall, httpClient.Transport),
 	)
+	metrics.RegisterRateLimiter("twitter", limiter.Tokens)
+	httpClient.Transport = transport.NewTracingTransport(twitterSpanName, limiter)
 
 	return &TwitterProvider{
 		ConsumerKey:       consumerKey,
@@ -52,18 +74,32 @@ func NewTwitterProvider(consumerKey, consumerSecret, accessToken, accessTokenSec
 		AccessToken:       accessToken,
 		AccessTokenSecret: accessTokenSecret,
 		Client:            httpClient,
+		Query:             TwitterSearchQuery,
+	}
+}
+
+// TwitterSearchQueryFor narrows TwitterSearchQuery to tweets in the given
+// languages, e.g. "(lang:en OR lang:ja)". No languages leaves it unnarrowed.
+func TwitterSearchQueryFor(languages []string) string {
+	if len(languages) == 0 {
+		return TwitterSearchQuery
+	}
+	filters := make([]string, len(languages))
+	for i, lang := range languages {
+		filters[i] = "lang:" + lang
 	}
+	return TwitterSearchQuery + " (" + strings.Join(filters, " OR ") + ")"
 }
 
 // FetchPosts fetches recent tweets matching a query using OAuth1 authentication.
 func (tp *TwitterProvider) FetchPosts(ctx context.Context, limit int) ([]entities.Post, error) {
 	// Build query parameters.
 	q := url.Values{}
-	q.Set("query", "software news lang:en -is:retweet")
+	q.Set("query", tp.Query)
 	q.Set("max_results", fmt.Sprintf("%d", limit))
 	q.Set("sort_order", "relevancy") // Sort by popularity.
 	q.Set("expansions", "author_id") // Expand author ID.
-	q.Set("tweet.fields", "public_metrics,author_id")
+	q.Set("tweet.fields", "public_metrics,author_id,created_at,lang")
 	q.Set("user.fields", "username") // Get usernames in `includes.users`.
 
 	apiURL := fmt.Sprintf("%s?%s", TwitterSearchEndpoint, q.Encode())
@@ -97,16 +133,16 @@ func (tp *TwitterProvider) FetchPosts(ctx context.Context, limit int) ([]entitie
 						if resetTimestamp, err := strconv.ParseInt(resetHeader, 10, 64); err == nil {
 							waitDuration := time.Until(time.Unix(resetTimestamp, 0))
 							if waitDuration > 0 {
-								fmt.Printf("Rate limit hit. Waiting for %v until reset.\n", waitDuration)
+								slog.WarnContext(ctx, "Twitter rate limit hit, waiting until reset", "wait", waitDuration)
 								time.Sleep(waitDuration)
 							}
 						} else {
-							fmt.Printf("Error parsing x-rate-limit-reset header: %v. Falling back to %v backoff.\n", err, backoff)
+							slog.WarnContext(ctx, "Error parsing x-rate-limit-reset header, falling back to backoff", "backoff", backoff, "error", err)
 							time.Sleep(backoff)
 							backoff *= 2
 						}
 					} else {
-						fmt.Printf("x-rate-limit-reset header not found. Retrying in %v.\n", backoff)
+						slog.WarnContext(ctx, "x-rate-limit-reset header not found, retrying after backoff", "backoff", backoff)
 						time.Sleep(backoff)
 						backoff *= 2
 					}
@@ -118,7 +154,11 @@ func (tp *TwitterProvider) FetchPosts(ctx context.Context, limit int) ([]entitie
 					if err := json.Unmarshal(bodyBytes, &result); err != nil {
 						return nil, err
 					}
-					return mapTwitterPosts(result)
+					posts, err := mapTwitterPosts(result)
+					for i := range posts {
+						posts[i].SearchProfile = tp.Query
+					}
+					return posts, err
 				}
 			}
 		}
@@ -132,8 +172,39 @@ func (tp *TwitterProvider) FetchPosts(ctx context.Context, limit int) ([]entitie
 	return nil, fmt.Errorf("error fetching posts from platform: %v", lastErr)
 }
 
-// CommentOn replies to a tweet with a given message using OAuth 1.0a.
-func (tp *TwitterProvider) CommentOn(tweetID, message string) error {
+// FetchPost looks up a single tweet with its author and metrics.
+func (tp *TwitterProvider) FetchPost(ctx context.Context, tweetID string) (*entities.Post, error) {
+	q := url.Values{}
+	q.Set("expansions", "author_id")
+	q.Set("tweet.fields", "public_metrics,author_id,created_at,lang")
+	q.Set("user.fields", "username")
+
+	body, err := tp.get(ctx, fmt.Sprintf("%s/%s?%s", TwitterPostEndpoint, url.PathEscape(tweetID), q.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tweet %s: %w", tweetID, err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse tweet %s: %w", tweetID, err)
+	}
+	// Deleted and protected tweets come back as 200 with only "errors".
+	tweet, ok := result["data"]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPostNotFound, tweetID)
+	}
+	result["data"] = []interface{}{tweet}
+
+	posts, err := mapTwitterPosts(result)
+	if err != nil || len(posts) == 0 {
+		return nil, fmt.Errorf("failed to parse tweet %s: %v", tweetID, err)
+	}
+	return &posts[0], nil
+}
+
+// CommentOn replies to a tweet with a given message using OAuth 1.0a and
+// returns the ID of the created reply.
+func (tp *TwitterProvider) CommentOn(ctx context.Context, tweetID, message string) (string, error) {
 	payload := map[string]interface{}{
 		"text": message,
 		"reply": map[string]string{
@@ -143,12 +214,12 @@ func (tp *TwitterProvider) CommentOn(tweetID, message string) error {
 
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	req, err := http.NewRequest("POST", TwitterPostEndpoint, bytes.NewBuffer(payloadBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", TwitterPostEndpoint, bytes.NewBuffer(payloadBytes))
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -156,21 +227,206 @@ func (tp *TwitterProvider) CommentOn(tweetID, message string) error {
 
 	resp, err := tp.Client.Do(req)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	fmt.Println("Raw Response:", string(bodyBytes))
 	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to comment on tweet: %s", string(bodyBytes))
+		return "", fmt.Errorf("failed to comment on tweet: %s", string(bodyBytes))
+	}
+
+	var result struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return "", fmt.Errorf("comment posted but response could not be parsed: %v", err)
 	}
 
-	return nil
+	return result.Data.ID, nil
+}
+
+// FetchMentions fetches tweets mentioning the authenticated account that are
+// newer than sinceID, oldest first. Only the newest page is read, so a
+// backlog longer than limit is partly skipped.
+func (tp *TwitterProvider) FetchMentions(ctx context.Context, sinceID string, limit int) ([]entities.Mention, error) {
+	userID, err := tp.accountID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	// The API accepts between 5 and 100 results per page.
+	q.Set("max_results", strconv.Itoa(min(max(limit, 5), 100)))
+	if sinceID != "" {
+		q.Set("since_id", sinceID)
+	}
+	q.Set("expansions", "author_id")
+	q.Set("tweet.fields", "author_id,created_at,conversation_id,referenced_tweets")
+	q.Set("user.fields", "username")
+
+	body, err := tp.get(ctx, fmt.Sprintf("%s/%s/mentions?%s", TwitterUsersEndpoint, userID, q.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch mentions: %w", err)
+	}
+
+	var result struct {
+		Data []struct {
+			ID               string    `json:"id"`
+			AuthorID         string    `json:"author_id"`
+			Text             string    `json:"text"`
+			ConversationID   string    `json:"conversation_id"`
+			CreatedAt        time.Time `json:"created_at"`
+			ReferencedTweets []struct {
+				Type string `json:"type"`
+				ID   string `json:"id"`
+			} `json:"referenced_tweets"`
+		} `json:"data"`
+		Includes struct {
+			Users []struct {
+				ID       string `json:"id"`
+				Username string `json:"username"`
+			} `json:"users"`
+		} `json:"includes"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse mentions: %w", err)
+	}
+
+	usernames := make(map[string]string, len(result.Includes.Users))
+	for _, u := range result.Includes.Users {
+		usernames[u.ID] = u.Username
+	}
+	mentions := make([]entities.Mention, 0, len(result.Data))
+	for _, t := range result.Data {
+		m := entities.Mention{
+			ID:             t.ID,
+			Author:         entities.Author{ID: t.AuthorID, Username: usernames[t.AuthorID]},
+			Text:           t.Text,
+			ConversationID: t.ConversationID,
+			Platform:       entities.PlatformTwitter,
+			CreatedAt:      t.CreatedAt,
+		}
+		for _, ref := range t.ReferencedTweets {
+			if ref.Type == "replied_to" {
+				m.InReplyToID = ref.ID
+			}
+		}
+		mentions = append(mentions, m)
+	}
+
+	// The API lists newest first.
+	slices.Reverse(mentions)
+	if len(mentions) > limit {
+		mentions = mentions[:limit]
+	}
+	return mentions, nil
+}
+
+// FetchEngagement looks up the public metrics of tweets, up to twitterMaxIDs
+// per request.
+func (tp *TwitterProvider) FetchEngagement(ctx context.Context, tweetIDs []string) (map[string]entities.Engagement, error) {
+	engagement := make(map[string]entities.Engagement, len(tweetIDs))
+	for ids := range slices.Chunk(tweetIDs, twitterMaxIDs) {
+		q := url.Values{}
+		q.Set("ids", strings.Join(ids, ","))
+		q.Set("tweet.fields", "public_metrics")
+
+		body, err := tp.get(ctx, fmt.Sprintf("%s?%s", TwitterPostEndpoint, q.Encode()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch tweet metrics: %w", err)
+		}
+		// Deleted and protected tweets are listed under "errors" instead.
+		var result struct {
+			Data []struct {
+				ID            string `json:"id"`
+				PublicMetrics struct {
+					LikeCount       int `json:"like_count"`
+					RetweetCount    int `json:"retweet_count"`
+					ReplyCount      int `json:"reply_count"`
+					ImpressionCount int `json:"impression_count"`
+				} `json:"public_metrics"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse tweet metrics: %w", err)
+		}
+
+		for _, t := range result.Data {
+			engagement[t.ID] = entities.Engagement{
+				Likes:       t.PublicMetrics.LikeCount,
+				Shares:      t.PublicMetrics.RetweetCount,
+				Replies:     t.PublicMetrics.ReplyCount,
+				Impressions: t.PublicMetrics.ImpressionCount,
+			}
+		}
+	}
+	return engagement, nil
+}
+
+// accountID returns the ID of the authenticated account.
+func (tp *TwitterProvider) accountID(ctx context.Context) (string, error) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	if tp.userID != "" {
+		return tp.userID, nil
+	}
+
+	body, err := tp.get(ctx, TwitterUsersEndpoint+"/me")
+	if err != nil {
+		return "", fmt.Errorf("failed to look up the bot's account: %w", err)
+	}
+	var result struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil || result.Data.ID == "" {
+		return "", fmt.Errorf("failed to parse the bot's account: %s", string(body))
+	}
+
+	tp.userID = result.Data.ID
+	return tp.userID, nil
+}
+
+// get requests apiURL and returns the body of a 200 response.
+func (tp *TwitterProvider) get(ctx context.Context, apiURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := tp.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// observeTwitterCall records API latency by endpoint, e.g. "GET /2/tweets/search/recent".
+func observeTwitterCall(r *http.Request, code string, elapsed time.Duration) {
+	metrics.PlatformCallDuration.WithLabelValues(r.Method+" "+r.URL.Path, code).Observe(elapsed.Seconds())
+}
+
+// twitterSpanName names API call spans by endpoint, e.g. "twitter GET /2/tweets".
+func twitterSpanName(r *http.Request) string {
+	return "twitter " + r.Method + " " + r.URL.Path
 }
 
 // mapTwitterPosts maps the JSON response to a slice of entities.Post.
@@ -206,6 +462,12 @@ func mapTwitterPosts(result map[string]interface{}) ([]entities.Post, error) {
 		authorID, _ := tweet["author_id"].(string)
 		text, _ := tweet["text"].(string)
 		createdAtStr, _ := tweet["created_at"].(string)
+		// Twitter tags tweets with ISO 639-1 codes, or "und" and the like
+		// when it cannot tell.
+		lang, _ := tweet["lang"].(string)
+		if len(lang) != 2 {
+			lang = ""
+		}
 
 		var createdAt time.Time
 		if createdAtStr != "" {
@@ -232,6 +494,7 @@ func mapTwitterPosts(result map[string]interface{}) ([]entities.Post, error) {
 			Shares:    shares,
 			Replies:   replies,
 			Platform:  entities.PlatformTwitter,
+			Language:  lang,
 			CreatedAt: createdAt,
 		})
 	}
@@ -2,7 +2,8 @@ package platforms
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/dapplux/twitter-haiku-bot/entities"
@@ -68,8 +69,33 @@ func (tm *TwitterMock) FetchPosts(ctx context.Context, limit int) ([]entities.Po
 	return mockData[:limit], nil
 }
 
+// FetchPost returns the mock tweet with the given ID.
+func (tm *TwitterMock) FetchPost(ctx context.Context, postID string) (*entities.Post, error) {
+	posts, _ := tm.FetchPosts(ctx, 3)
+	for _, p := range posts {
+		if p.ID == postID {
+			return &p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPostNotFound, postID)
+}
+
+// FetchMentions returns no mentions.
+func (tm *TwitterMock) FetchMentions(ctx context.Context, sinceID string, limit int) ([]entities.Mention, error) {
+	return nil, nil
+}
+
+// FetchEngagement reports no engagement for any post.
+func (tm *TwitterMock) FetchEngagement(ctx context.Context, postIDs []string) (map[string]entities.Engagement, error) {
+	engagement := make(map[string]entities.Engagement, len(postIDs))
+	for _, id := range postIDs {
+		engagement[id] = entities.Engagement{}
+	}
+	return engagement, nil
+}
+
 // CommentOnPost mocks commenting on a tweet
-func (tm *TwitterMock) CommentOn(postID, message string) error {
-	log.Printf("Mock Comment on Post ID %s: %s\n", postID, message)
-	return nil
+func (tm *TwitterMock) CommentOn(ctx context.Context, postID, message string) (string, error) {
+	slog.InfoContext(ctx, "Mock comment", "post_id", postID, "message", message)
+	return "mock-reply-" + postID, nil
 }
@@ -0,0 +1,260 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/config"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+)
+
+// Providers and resources metered by the bot.
+const (
+	ProviderTwitter     = "twitter"
+	ProviderHuggingFace = "huggingface"
+
+	// ResourceRequests counts API calls regardless of outcome.
+	ResourceRequests = "requests"
+	// ResourceTweetsRead counts tweets returned by search, which is what the
+	// Twitter free tier caps per month.
+	ResourceTweetsRead = "tweets_read"
+	// ResourceTweetsWritten counts tweets successfully posted.
+	ResourceTweetsWritten = "tweets_written"
+)
+
+// Period is the window a budget applies to.
+type Period string
+
+const (
+	PeriodDaily   Period = "daily"
+	PeriodMonthly Period = "monthly"
+)
+
+// ErrBudgetExceeded is returned when consuming more would exceed a budget.
+var ErrBudgetExceeded = errors.New("quota budget exceeded")
+
+// Key returns the storage key of the period containing t, e.g. "2025-02" or "2025-02-11".
+func (p Period) Key(t time.Time) string {
+	if p == PeriodDaily {
+		return t.UTC().Format("2006-01-02")
+	}
+	return t.UTC().Format("2006-01")
+}
+
+// Budget caps consumption of one provider resource per period.
+type Budget struct {
+	Provider string
+	Resource string
+	Period   Period
+	Limit    int64
+}
+
+// Status is a budget together with its consumption in the current period.
+type Status struct {
+	Budget
+	PeriodKey string
+	Used      int64
+}
+
+// Remaining returns how much of the budget is left, never below zero.
+func (s Status) Remaining() int64 {
+	if s.Used >= s.Limit {
+		return 0
+	}
+	return s.Limit - s.Used
+}
+
+// Meter records consumption and checks it against budgets.
+type Meter interface {
+	// Allow returns an error wrapping ErrBudgetExceeded if consuming amount
+	// more of the resource would exceed any of its budgets.
+	Allow(ctx context.Context, provider, resource string, amount int64) error
+	// Remaining returns how much of the resource is left under its tightest
+	// budget, or math.MaxInt64 if it has none.
+	Remaining(ctx context.Context, provider, resource string) (int64, error)
+	// Reserve atomically adds amount to the daily and monthly counters of the
+	// resource. If that would exceed any of its budgets nothing is added and
+	// an error wrapping ErrBudgetExceeded is returned.
+	Reserve(ctx context.Context, provider, resource string, amount int64) error
+	// Release gives back amount of an earlier reservation that went unused.
+	Release(ctx context.Context, provider, resource string, amount int64) error
+	// Record adds amount to the daily and monthly counters of the resource.
+	Record(ctx context.Context, provider, resource string, amount int64) error
+}
+
+// Tracker is a Meter persisted through a QuotaRepository.
+type Tracker struct {
+	repo    repositories.QuotaRepository
+	budgets []Budget
+	now     func() time.Time
+}
+
+// NewTracker creates a Tracker enforcing budgets. now defaults to time.Now.
+func NewTracker(repo repositories.QuotaRepository, budgets []Budget, now func() time.Time) *Tracker {
+	if now == nil {
+		now = time.Now
+	}
+
+	return &Tracker{
+		repo:    repo,
+		budgets: budgets,
+		now:     now,
+	}
+}
+
+// BudgetsFromConfig converts configured limits into budgets, skipping disabled (zero) ones.
+func BudgetsFromConfig(c config.Quota) []Budget {
+	all := []Budget{
+		{Provider: ProviderTwitter, Resource: ResourceTweetsRead, Period: PeriodMonthly, Limit: c.TwitterReadsMonthly},
+		{Provider: ProviderTwitter, Resource: ResourceTweetsWritten, Period: PeriodMonthly, Limit: c.TwitterWritesMonthly},
+		{Provider: ProviderTwitter, Resource: ResourceTweetsWritten, Period: PeriodDaily, Limit: c.TwitterWritesDaily},
+		{Provider: ProviderHuggingFace, Resource: ResourceRequests, Period: PeriodDaily, Limit: c.HuggingFaceRequestsDaily},
+	}
+
+	var budgets []Budget
+	for _, b := range all {
+		if b.Limit > 0 {
+			budgets = append(budgets, b)
+		}
+	}
+	return budgets
+}
+
+// Allow checks every budget of the resource against the current period's usage.
+func (t *Tracker) Allow(ctx context.Context, provider, resource string, amount int64) error {
+	now := t.now()
+	for _, b := range t.budgets {
+		if b.Provider != provider || b.Resource != resource {
+			continue
+		}
+
+		key := b.Period.Key(now)
+		used, err := t.repo.FindUsage(ctx, nil, provider, resource, key)
+		if err != nil {
+			return fmt.Errorf("failed to read quota usage: %w", err)
+		}
+
+		if used+amount > b.Limit {
+			return fmt.Errorf("%w: %s %s %s budget %d, used %d, requested %d",
+				ErrBudgetExceeded, provider, resource, b.Period, b.Limit, used, amount)
+		}
+	}
+	return nil
+}
+
+// Remaining returns the smallest amount left across the resource's budgets.
+func (t *Tracker) Remaining(ctx context.Context, provider, resource string) (int64, error) {
+	now := t.now()
+	remaining := int64(math.MaxInt64)
+	for _, b := range t.budgets {
+		if b.Provider != provider || b.Resource != resource {
+			continue
+		}
+
+		key := b.Period.Key(now)
+		used, err := t.repo.FindUsage(ctx, nil, provider, resource, key)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read quota usage: %w", err)
+		}
+
+		status := Status{Budget: b, PeriodKey: key, Used: used}
+		remaining = min(remaining, status.Remaining())
+	}
+	return remaining, nil
+}
+
+// Reserve checks and increments each period's counter in one statement, so
+// concurrent callers cannot both pass a check that only one of them fits
+// into. Counters reserved before a budget turns out exhausted are released.
+func (t *Tracker) Reserve(ctx context.Context, provider, resource string, amount int64) error {
+	if amount == 0 {
+		return nil
+	}
+
+	now := t.now()
+	var reserved []Period
+	for _, p := range []Period{PeriodDaily, PeriodMonthly} {
+		if err := t.reserve(ctx, provider, resource, p, p.Key(now), amount); err != nil {
+			for _, r := range reserved {
+				if releaseErr := t.repo.Increment(ctx, nil, provider, resource, r.Key(now), -amount); releaseErr != nil {
+					err = errors.Join(err, fmt.Errorf("failed to release quota reservation: %w", releaseErr))
+				}
+			}
+			return err
+		}
+		reserved = append(reserved, p)
+	}
+	return nil
+}
+
+// reserve adds amount to one period's counter, enforcing the tightest budget
+// configured for that period if there is one.
+func (t *Tracker) reserve(ctx context.Context, provider, resource string, period Period, key string, amount int64) error {
+	limit, budgeted := int64(0), false
+	for _, b := range t.budgets {
+		if b.Provider == provider && b.Resource == resource && b.Period == period && (!budgeted || b.Limit < limit) {
+			limit, budgeted = b.Limit, true
+		}
+	}
+
+	if !budgeted {
+		if err := t.repo.Increment(ctx, nil, provider, resource, key, amount); err != nil {
+			return fmt.Errorf("failed to record quota usage: %w", err)
+		}
+		return nil
+	}
+
+	ok, err := t.repo.Reserve(ctx, nil, provider, resource, key, amount, limit)
+	if err != nil {
+		return fmt.Errorf("failed to reserve quota: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s %s %s budget %d, requested %d",
+			ErrBudgetExceeded, provider, resource, period, limit, amount)
+	}
+	return nil
+}
+
+// Release decrements both counters by the unused part of a reservation.
+func (t *Tracker) Release(ctx context.Context, provider, resource string, amount int64) error {
+	return t.Record(ctx, provider, resource, -amount)
+}
+
+// Record increments both the daily and the monthly counter so either kind of
+// budget can be configured later without losing history.
+func (t *Tracker) Record(ctx context.Context, provider, resource string, amount int64) error {
+	if amount == 0 {
+		return nil
+	}
+
+	now := t.now()
+	for _, p := range []Period{PeriodDaily, PeriodMonthly} {
+		if err := t.repo.Increment(ctx, nil, provider, resource, p.Key(now), amount); err != nil {
+			return fmt.Errorf("failed to record quota usage: %w", err)
+		}
+	}
+	return nil
+}
+
+// Statuses returns every configured budget with its usage in the current period.
+func (t *Tracker) Statuses(ctx context.Context) ([]Status, error) {
+	now := t.now()
+	statuses := make([]Status, 0, len(t.budgets))
+	for _, b := range t.budgets {
+		key := b.Period.Key(now)
+		used, err := t.repo.FindUsage(ctx, nil, b.Provider, b.Resource, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read quota usage: %w", err)
+		}
+		statuses = append(statuses, Status{Budget: b, PeriodKey: key, Used: used})
+	}
+	return statuses, nil
+}
+
+// Usage returns the consumption of a resource in the period containing now.
+func (t *Tracker) Usage(ctx context.Context, provider, resource string, period Period) (int64, error) {
+	return t.repo.FindUsage(ctx, nil, provider, resource, period.Key(t.now()))
+}
@@ -0,0 +1,146 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/memory"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+)
+
+var testBudgets = []Budget{
+	{Provider: ProviderTwitter, Resource: ResourceTweetsRead, Period: PeriodMonthly, Limit: 100},
+	{Provider: ProviderTwitter, Resource: ResourceTweetsWritten, Period: PeriodDaily, Limit: 10},
+	{Provider: ProviderTwitter, Resource: ResourceTweetsWritten, Period: PeriodMonthly, Limit: 20},
+}
+
+func newTestTracker(now time.Time) (*Tracker, repositories.QuotaRepository) {
+	clock := func() time.Time { return now }
+	repo := memory.NewQuotaRepository(memory.NewStore(clock))
+	return NewTracker(repo, testBudgets, clock), repo
+}
+
+func TestReserve(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                   string
+		resource               string
+		usedDaily, usedMonthly int64
+		amount                 int64
+		wantErr                bool
+		wantDaily, wantMonthly int64
+	}{
+		{"within budget", ResourceTweetsRead, 0, 40, 60, false, 60, 100},
+		{"over budget adds nothing", ResourceTweetsRead, 0, 40, 61, true, 0, 40},
+		{"daily budget binds", ResourceTweetsWritten, 10, 10, 1, true, 10, 10},
+		{"monthly budget binds, daily released", ResourceTweetsWritten, 3, 20, 1, true, 3, 20},
+		{"both budgets fit", ResourceTweetsWritten, 3, 10, 2, false, 5, 12},
+		{"unbudgeted resource", ResourceRequests, 0, 0, 5, false, 5, 5},
+		{"nothing to reserve", ResourceTweetsRead, 0, 100, 0, false, 0, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, repo := newTestTracker(now)
+			_ = repo.Increment(ctx, nil, ProviderTwitter, tt.resource, PeriodDaily.Key(now), tt.usedDaily)
+			_ = repo.Increment(ctx, nil, ProviderTwitter, tt.resource, PeriodMonthly.Key(now), tt.usedMonthly)
+
+			err := tracker.Reserve(ctx, ProviderTwitter, tt.resource, tt.amount)
+			if tt.wantErr != errors.Is(err, ErrBudgetExceeded) {
+				t.Fatalf("Reserve() error = %v, want budget exceeded %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("Reserve() error = %v", err)
+			}
+
+			daily, _ := tracker.Usage(ctx, ProviderTwitter, tt.resource, PeriodDaily)
+			monthly, _ := tracker.Usage(ctx, ProviderTwitter, tt.resource, PeriodMonthly)
+			if daily != tt.wantDaily || monthly != tt.wantMonthly {
+				t.Errorf("usage = %d daily, %d monthly, want %d, %d", daily, monthly, tt.wantDaily, tt.wantMonthly)
+			}
+		})
+	}
+}
+
+func TestReserveConcurrently(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	tracker, _ := newTestTracker(now)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	granted := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if tracker.Reserve(ctx, ProviderTwitter, ResourceTweetsWritten, 1) == nil {
+				mu.Lock()
+				granted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if granted != 10 {
+		t.Errorf("%d concurrent reservations granted, want 10", granted)
+	}
+	if used, _ := tracker.Usage(ctx, ProviderTwitter, ResourceTweetsWritten, PeriodDaily); used != 10 {
+		t.Errorf("daily usage = %d, want 10", used)
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                   string
+		resource               string
+		usedDaily, usedMonthly int64
+		want                   int64
+	}{
+		{"untouched budget", ResourceTweetsRead, 0, 0, 100},
+		{"partly used", ResourceTweetsRead, 0, 70, 30},
+		{"overdrawn", ResourceTweetsRead, 0, 120, 0},
+		{"tightest budget wins", ResourceTweetsWritten, 4, 15, 5},
+		{"unbudgeted resource", ResourceRequests, 7, 7, math.MaxInt64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, repo := newTestTracker(now)
+			_ = repo.Increment(ctx, nil, ProviderTwitter, tt.resource, PeriodDaily.Key(now), tt.usedDaily)
+			_ = repo.Increment(ctx, nil, ProviderTwitter, tt.resource, PeriodMonthly.Key(now), tt.usedMonthly)
+
+			got, err := tracker.Remaining(ctx, ProviderTwitter, tt.resource)
+			if err != nil {
+				t.Fatalf("Remaining() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Remaining() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelease(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	tracker, _ := newTestTracker(now)
+
+	if err := tracker.Reserve(ctx, ProviderTwitter, ResourceTweetsRead, 100); err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if err := tracker.Release(ctx, ProviderTwitter, ResourceTweetsRead, 40); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if err := tracker.Reserve(ctx, ProviderTwitter, ResourceTweetsRead, 40); err != nil {
+		t.Errorf("Reserve() error = %v after releasing 40, want the released amount reservable again", err)
+	}
+}
@@ -0,0 +1,136 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/dapplux/twitter-haiku-bot/config"
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/logging"
+)
+
+const (
+	instrumentationName = "github.com/dapplux/twitter-haiku-bot"
+	serviceName         = "twitter-haiku-bot"
+)
+
+// propagator encodes span contexts persisted on rows as W3C traceparent headers.
+var propagator = propagation.TraceContext{}
+
+// Setup installs the global tracer provider configured by cfg and returns a
+// function that flushes and stops it. With the "none" exporter tracing stays
+// a no-op and the returned function does nothing. The stdout exporter scrubs
+// secrets from the spans it prints, as the logs do.
+func Setup(ctx context.Context, cfg config.Tracing, secrets ...string) (func(ctx context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "none":
+		return func(ctx context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(logging.NewRedactingWriter(os.Stdout, secrets...)), stdouttrace.WithPrettyPrint())
+	case "otlp":
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer used for the bot's own spans.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Haiku returns the span attributes identifying a haiku and its post.
+func Haiku(h *entities.Haiku) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("haiku.id", h.ID),
+		attribute.String("haiku.state", string(h.State)),
+		attribute.Int("haiku.attempt", h.Attempt),
+		attribute.String("post.id", h.PostID),
+		attribute.String("post.platform", string(h.Post.Platform)),
+	}
+}
+
+// Inject encodes the span context of ctx as a traceparent value for storage,
+// or returns "" if ctx holds no sampled span.
+func Inject(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// Extract decodes a stored traceparent value. The span context is invalid if
+// the value is empty or malformed.
+func Extract(traceparent string) trace.SpanContext {
+	if traceparent == "" {
+		return trace.SpanContext{}
+	}
+	carrier := propagation.MapCarrier{"traceparent": traceparent}
+	return trace.SpanContextFromContext(propagator.Extract(context.Background(), carrier))
+}
+
+// StartContinued starts a span in the trace persisted as traceparent, so work
+// on one item done by separate asynchronous runs forms a single trace. The
+// span links back to the span active in ctx, e.g. the job run that picked the
+// item up. Without a stored trace the span is a child of the span in ctx.
+func StartContinued(ctx context.Context, traceparent, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	stored := Extract(traceparent)
+	if !stored.IsValid() {
+		return Tracer().Start(ctx, name, opts...)
+	}
+
+	if current := trace.SpanContextFromContext(ctx); current.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: current}))
+	}
+	return Tracer().Start(trace.ContextWithRemoteSpanContext(ctx, stored), name, opts...)
+}
+
+// End records err, if any, on span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,38 @@
+package transport
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ObserveFunc receives the outcome of a request: the response status code,
+// or "error" if no response was received, and how long the round trip took.
+type ObserveFunc func(r *http.Request, code string, elapsed time.Duration)
+
+// LatencyTransport reports the duration of every round trip. Place it inside
+// RateLimitTransport so time spent waiting for a token is not counted.
+type LatencyTransport struct {
+	observe ObserveFunc
+	rTriper http.RoundTripper
+}
+
+func NewLatencyTransport(observe ObserveFunc, rTriper http.RoundTripper) http.RoundTripper {
+	return &LatencyTransport{
+		observe: observe,
+		rTriper: rTriper,
+	}
+}
+
+func (t *LatencyTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.rTriper.RoundTrip(r)
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	t.observe(r, code, time.Since(start))
+
+	return resp, err
+}
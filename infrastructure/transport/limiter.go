@@ -11,7 +11,7 @@ type RateLimitTransport struct {
 	rTriper http.RoundTripper
 }
 
-func NewRateLimitTransport(r float64, rTriper http.RoundTripper) http.RoundTripper {
+func NewRateLimitTransport(r float64, rTriper http.RoundTripper) *RateLimitTransport {
 	return &RateLimitTransport{
 		limiter: rate.NewLimiter(rate.Limit(r), 1),
 		rTriper: rTriper,
@@ -19,6 +19,14 @@ func NewRateLimitTransport(r float64, rTriper http.RoundTripper) http.RoundTripp
 }
 
 func (t *RateLimitTransport) RoundTrip(r *http.Request) (*http.Response, error) {
-	t.limiter.Wait(r.Context())
+	// Wait fails once the request context is cancelled, e.g. during shutdown.
+	if err := t.limiter.Wait(r.Context()); err != nil {
+		return nil, err
+	}
 	return t.rTriper.RoundTrip(r)
 }
+
+// Tokens returns the number of requests that can be made now without waiting.
+func (t *RateLimitTransport) Tokens() float64 {
+	return t.limiter.Tokens()
+}
@@ -0,0 +1,21 @@
+package transport
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// NewTracingTransport records a client span for every request, named by
+// spanName. Place it outermost so the span includes time spent waiting on
+// RateLimitTransport. Trace headers are not sent: the APIs called are third
+// parties that do not take part in our traces.
+func NewTracingTransport(spanName func(r *http.Request) string, rTriper http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(rTriper,
+		otelhttp.WithPropagators(propagation.NewCompositeTextMapPropagator()),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return spanName(r)
+		}),
+	)
+}
@@ -0,0 +1,138 @@
+package language
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+)
+
+// scripts maps writing systems to the language they most likely stand for.
+// Han is left out: it is shared by Chinese and Japanese.
+var scripts = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+}
+
+// stopwords are common short words that tell Latin-script languages apart.
+// English comes first so it wins ties.
+var stopwords = []struct {
+	lang  string
+	words []string
+}{
+	{"en", []string{"the", "and", "is", "are", "of", "to", "in", "that", "it", "for", "with", "on", "this", "you", "was", "new"}},
+	{"es", []string{"el", "los", "las", "que", "y", "por", "para", "con", "una", "del", "es", "está", "como"}},
+	{"fr", []string{"le", "les", "des", "et", "est", "une", "du", "pour", "dans", "pas", "sur", "avec", "qui"}},
+	{"de", []string{"der", "die", "das", "und", "ist", "nicht", "mit", "ein", "eine", "den", "auf", "für", "von", "zu"}},
+	{"pt", []string{"os", "um", "uma", "não", "com", "do", "da", "dos", "das", "é", "em", "mais"}},
+	{"it", []string{"il", "gli", "di", "è", "non", "per", "sono", "della", "che", "anche"}},
+}
+
+// vietnamese holds letters only Vietnamese uses among Latin-script
+// languages; tone marks with a dot below or hook above are in the Latin
+// Extended Additional block.
+const vietnamese = "đĐăĂơƠưƯ"
+
+// Of returns the language of post: the one recorded on it, or else the one
+// detected from its text.
+func Of(post entities.Post) string {
+	if post.Language != "" {
+		return post.Language
+	}
+	return Detect(post.Text)
+}
+
+// Detect guesses the ISO 639-1 code of the language text is written in. The
+// script decides most languages: kana mark Japanese and Han without kana
+// Chinese. Latin text is Vietnamese if it uses letters only Vietnamese has,
+// and otherwise in the language whose common words it uses most, English if
+// none stands out. Mentions, hashtags and links are ignored. Detect returns
+// an empty string for text without letters.
+func Detect(text string) string {
+	var words []string
+	for _, word := range strings.Fields(text) {
+		if strings.HasPrefix(word, "@") || strings.HasPrefix(word, "#") || strings.Contains(word, "://") {
+			continue
+		}
+		words = append(words, word)
+	}
+
+	counts := make(map[string]int)
+	han, latin, vi := 0, 0, false
+	for _, word := range words {
+		for _, r := range word {
+			switch {
+			case !unicode.IsLetter(r):
+			case unicode.Is(unicode.Han, r):
+				han++
+			case unicode.Is(unicode.Latin, r):
+				latin++
+				if strings.ContainsRune(vietnamese, r) || (r >= 0x1EA0 && r <= 0x1EF9) {
+					vi = true
+				}
+			default:
+				for _, s := range scripts {
+					if unicode.Is(s.table, r) {
+						counts[s.lang]++
+						break
+					}
+				}
+			}
+		}
+	}
+
+	// Japanese mixes kana with Han, so any kana makes Han count as Japanese.
+	if counts["ja"] > 0 {
+		counts["ja"] += han
+	} else {
+		counts["zh"] = han
+	}
+	best, most := "", latin
+	for _, s := range scripts {
+		if counts[s.lang] > most {
+			best, most = s.lang, counts[s.lang]
+		}
+	}
+	if counts["zh"] > most {
+		best = "zh"
+	}
+	if best != "" {
+		return best
+	}
+	if latin == 0 {
+		return ""
+	}
+	if vi {
+		return "vi"
+	}
+	return latinLanguage(words)
+}
+
+// latinLanguage returns the language whose stopwords appear most in words.
+func latinLanguage(words []string) string {
+	best, most := "en", 0
+	for _, sw := range stopwords {
+		n := 0
+		for _, word := range words {
+			word = strings.ToLower(strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) }))
+			if slices.Contains(sw.words, word) {
+				n++
+			}
+		}
+		if n > most {
+			best, most = sw.lang, n
+		}
+	}
+	return best
+}
@@ -0,0 +1,59 @@
+package language
+
+import (
+	"testing"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"japanese kana and kanji", "古池や蛙飛び込む水の音", "ja"},
+		{"japanese mostly kanji", "日本語のニュース速報", "ja"},
+		{"chinese", "今天的软件新闻很有意思", "zh"},
+		{"korean", "오늘의 소프트웨어 뉴스", "ko"},
+		{"russian", "Новости программного обеспечения", "ru"},
+		{"english", "The new release of the compiler is out", "en"},
+		{"vietnamese tone marks", "Tin tức phần mềm mới nhất hôm nay", "vi"},
+		{"vietnamese letters", "Đi học", "vi"},
+		{"spanish", "La nueva versión es para los usuarios", "es"},
+		{"german", "Die neue Version ist nicht mit der alten kompatibel", "de"},
+		{"short latin defaults to english", "Golang!", "en"},
+		{"ambiguous latin defaults to english", "Kubernetes Docker Rust", "en"},
+		{"mentions, hashtags and links ignored", "@tanaka #golang https://example.com こんにちは", "ja"},
+		{"latin outweighs a stray kanji", "Release notes for v2 are out 新", "en"},
+		{"only mentions and links", "@someone #tag https://example.com", ""},
+		{"no letters", "123 !!! 🎉", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.text); got != tt.want {
+				t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOf(t *testing.T) {
+	tests := []struct {
+		name string
+		post entities.Post
+		want string
+	}{
+		{"recorded language wins", entities.Post{Language: "fr", Text: "The new release is out"}, "fr"},
+		{"detected without one", entities.Post{Text: "古池や蛙飛び込む水の音"}, "ja"},
+		{"empty without text", entities.Post{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Of(tt.post); got != tt.want {
+				t.Errorf("Of() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Package language decides how posts are haikued by the language they are
+// written in.
+package language
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/dapplux/twitter-haiku-bot/config"
+	"github.com/dapplux/twitter-haiku-bot/haikuform"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/ai"
+)
+
+// Pipeline is how posts in a language are haikued.
+type Pipeline string
+
+const (
+	// Native haikus posts in their own language.
+	Native Pipeline = "native"
+	// Translate translates posts into Target and haikus the translation.
+	Translate Pipeline = "translate"
+	// Skip leaves posts alone.
+	Skip Pipeline = "skip"
+)
+
+// Target is the language posts are translated into.
+const Target = "en"
+
+// Policy picks the pipeline of each language and checks the form of
+// haikus in it.
+type Policy struct {
+	pipelines   map[string]Pipeline
+	unsupported Pipeline
+	forms       haikuform.ByLanguage
+}
+
+// NewPolicy creates a policy from the pipeline of each supported language
+// and the one of every other language, including posts whose language is
+// unknown. forms checks haikus of the languages it has a validator for.
+func NewPolicy(pipelines map[string]Pipeline, unsupported Pipeline, forms haikuform.ByLanguage) *Policy {
+	return &Policy{pipelines: pipelines, unsupported: unsupported, forms: forms}
+}
+
+// FromConfig builds the policy configured by cfg. Languages haikued
+// natively must be ones the text processor can write haikus in. Japanese
+// haikus are counted with the built-in kanji readings and those of the
+// readings file.
+func FromConfig(cfg config.Languages) (*Policy, error) {
+	supported := ai.HaikuLanguages()
+	pipelines := make(map[string]Pipeline, len(cfg.Pipelines))
+	for lang, pipeline := range cfg.Pipelines {
+		if Pipeline(pipeline) == Native && !slices.Contains(supported, lang) {
+			return nil, fmt.Errorf("languages.pipelines.%s: haikus cannot be written in %q, want one of %v", lang, lang, supported)
+		}
+		pipelines[lang] = Pipeline(pipeline)
+	}
+
+	readings := haikuform.DefaultReadings()
+	if cfg.ReadingsFile != "" {
+		extra, err := haikuform.LoadReadings(cfg.ReadingsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kanji readings: %w", err)
+		}
+		maps.Copy(readings, extra)
+	}
+	return NewPolicy(pipelines, Pipeline(cfg.Unsupported), haikuform.Validators(readings)), nil
+}
+
+// Forms returns the validators of the languages whose haiku form can be
+// checked. A nil policy has the built-in ones.
+func (p *Policy) Forms() haikuform.ByLanguage {
+	if p == nil {
+		return haikuform.Validators(haikuform.DefaultReadings())
+	}
+	return p.forms
+}
+
+// SearchLanguages returns the languages worth fetching posts in, sorted,
+// or nil if posts in every language are: a nil policy and one haikuing
+// unsupported languages skip none.
+func (p *Policy) SearchLanguages() []string {
+	if p == nil || p.unsupported != Skip {
+		return nil
+	}
+	var langs []string
+	for lang, pipeline := range p.pipelines {
+		if pipeline != Skip {
+			langs = append(langs, lang)
+		}
+	}
+	slices.Sort(langs)
+	return langs
+}
+
+// Pipeline returns how posts in lang are haikued. A nil policy haikus every
+// post in its own language.
+func (p *Policy) Pipeline(lang string) Pipeline {
+	if p == nil {
+		return Native
+	}
+	if pipeline, ok := p.pipelines[lang]; ok {
+		return pipeline
+	}
+	return p.unsupported
+}
+
+// HaikuLanguage returns the language the haiku of a post in lang is written
+// in, or an empty string if the post is skipped. Posts of unknown language
+// haikued natively get English haikus, as before languages were told apart.
+func (p *Policy) HaikuLanguage(lang string) string {
+	switch p.Pipeline(lang) {
+	case Native:
+		if lang == "" {
+			return Target
+		}
+		return lang
+	case Translate:
+		return Target
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,102 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// hook is a named shutdown step.
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Manager owns the process root context. On SIGINT/SIGTERM it cancels that
+// context and runs the registered shutdown hooks, newest first, each under its own deadline.
+type Manager struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	signals chan os.Signal
+	timeout time.Duration
+	hooks   []hook
+}
+
+// New creates a Manager that traps SIGINT and SIGTERM. timeout bounds the
+// time each shutdown hook may take, so a hook that overruns does not leave
+// the ones after it (e.g. closing the database) an expired context.
+func New(timeout time.Duration) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	return &Manager{
+		ctx:     ctx,
+		cancel:  cancel,
+		signals: signals,
+		timeout: timeout,
+	}
+}
+
+// Context returns the root context, cancelled when shutdown begins.
+func (m *Manager) Context() context.Context {
+	return m.ctx
+}
+
+// OnShutdown registers fn to run during shutdown. Hooks run in reverse
+// registration order, so register dependencies (e.g. the database) before
+// their users (e.g. the scheduler).
+func (m *Manager) OnShutdown(name string, fn func(ctx context.Context) error) {
+	m.hooks = append(m.hooks, hook{name: name, fn: fn})
+}
+
+// Shutdown starts shutdown as if a signal had been received.
+func (m *Manager) Shutdown() {
+	m.cancel()
+}
+
+// Wait blocks until a signal arrives or Shutdown is called, then cancels the
+// root context and runs every hook. A second signal exits immediately.
+// It returns the errors of all failed hooks.
+func (m *Manager) Wait() error {
+	select {
+	case sig := <-m.signals:
+		slog.Info("Received signal, shutting down", "signal", sig.String(), "deadline", m.timeout)
+	case <-m.ctx.Done():
+		slog.Info("Shutting down", "deadline", m.timeout)
+	}
+	m.cancel()
+
+	go func() {
+		sig := <-m.signals
+		slog.Warn("Received signal again, exiting immediately", "signal", sig.String())
+		os.Exit(1)
+	}()
+
+	var errs []error
+	for i := len(m.hooks) - 1; i >= 0; i-- {
+		h := m.hooks[i]
+		start := time.Now()
+		if err := m.run(h); err != nil {
+			slog.Error("Shutdown step failed", "step", h.name, "elapsed", time.Since(start), "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+			continue
+		}
+		slog.Info("Shutdown step completed", "step", h.name, "elapsed", time.Since(start))
+	}
+
+	signal.Stop(m.signals)
+	return errors.Join(errs...)
+}
+
+// run calls a hook with a fresh shutdown deadline.
+func (m *Manager) run(h hook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	return h.fn(ctx)
+}
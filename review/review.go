@@ -0,0 +1,123 @@
+// Package review decides which generated haikus may be published without a
+// human looking at them first.
+package review
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dapplux/twitter-haiku-bot/config"
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/haikuform"
+)
+
+// AutoReviewer is the reviewer recorded on haikus approved by rules.
+const AutoReviewer = "auto"
+
+// Rule is one condition for approving a haiku without human review.
+type Rule interface {
+	// Name identifies the rule in review reasons.
+	Name() string
+	// Check returns an empty string if haiku passes, otherwise why it does not.
+	Check(ctx context.Context, haiku *entities.Haiku) (string, error)
+}
+
+// Decision is the outcome of evaluating a haiku.
+type Decision struct {
+	// Approved is true if every rule passed.
+	Approved bool
+	// Reason lists the rules passed, or the first rule failed and why.
+	Reason string
+}
+
+// Policy approves haikus that pass every rule. A policy without rules sends
+// every haiku to human review.
+type Policy struct {
+	rules []Rule
+}
+
+// NewPolicy creates a policy from rules, all of which must pass.
+func NewPolicy(rules ...Rule) *Policy {
+	return &Policy{rules: rules}
+}
+
+// FromConfig builds the policy configured by cfg, or returns nil if review
+// mode is off. forms checks the form of haikus by language. extra rules are
+// added to the configured ones.
+func FromConfig(cfg config.Review, forms haikuform.ByLanguage, extra ...Rule) *Policy {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	var rules []Rule
+	if cfg.AutoApproveForm {
+		rules = append(rules, FormRule{Validators: forms})
+	}
+	if cfg.AutoApproveMaxSafetyScore > 0 {
+		rules = append(rules, SafetyRule{MaxScore: cfg.AutoApproveMaxSafetyScore})
+	}
+	return NewPolicy(append(rules, extra...)...)
+}
+
+// Evaluate checks haiku against every rule.
+func (p *Policy) Evaluate(ctx context.Context, haiku *entities.Haiku) (Decision, error) {
+	if len(p.rules) == 0 {
+		return Decision{Reason: "no auto-approval rules configured"}, nil
+	}
+
+	passed := make([]string, 0, len(p.rules))
+	for _, rule := range p.rules {
+		reason, err := rule.Check(ctx, haiku)
+		if err != nil {
+			return Decision{}, fmt.Errorf("review rule %s: %w", rule.Name(), err)
+		}
+		if reason != "" {
+			return Decision{Reason: fmt.Sprintf("%s: %s", rule.Name(), reason)}, nil
+		}
+		passed = append(passed, rule.Name())
+	}
+	return Decision{Approved: true, Reason: "passed " + strings.Join(passed, ", ")}, nil
+}
+
+// FormRule passes haikus whose text has a valid form in their language.
+// Haikus in languages without a validator, or whose form cannot be checked,
+// do not pass and are left to a reviewer.
+type FormRule struct {
+	Validators haikuform.ByLanguage
+}
+
+func (FormRule) Name() string {
+	return "form"
+}
+
+func (r FormRule) Check(ctx context.Context, haiku *entities.Haiku) (string, error) {
+	v, ok := r.Validators[haiku.Language]
+	if !ok {
+		return fmt.Sprintf("form of %q haikus cannot be checked", haiku.Language), nil
+	}
+	if err := v.Validate(haiku.Text.String); err != nil {
+		return err.Error(), nil
+	}
+	return "", nil
+}
+
+// SafetyRule passes haikus whose safety score is at most MaxScore. Haikus
+// that were never screened do not pass.
+type SafetyRule struct {
+	MaxScore float64
+}
+
+func (SafetyRule) Name() string {
+	return "safety"
+}
+
+func (r SafetyRule) Check(ctx context.Context, haiku *entities.Haiku) (string, error) {
+	if !haiku.SafetyScore.Valid {
+		return "not screened", nil
+	}
+	if haiku.SafetyScore.Float64 > r.MaxScore {
+		return fmt.Sprintf("score %.2f above %.2f", haiku.SafetyScore.Float64, r.MaxScore), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,85 @@
+// Package safety screens post and haiku text for content the bot must not
+// reply to or publish, such as tragedies, hate speech and NSFW material.
+package safety
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dapplux/twitter-haiku-bot/config"
+)
+
+// Verdict is a classifier's assessment of a text.
+type Verdict struct {
+	// Score is how unsafe the text is, from 0 (clean) to 1.
+	Score float64
+	// Category names what was found, e.g. "tragedy"; empty for clean text.
+	Category string
+	// Evidence is what the score is based on, e.g. the matched phrase.
+	Evidence string
+}
+
+// Reason describes the verdict for humans, e.g. `tragedy: "killed" (0.90)`.
+func (v Verdict) Reason() string {
+	if v.Category == "" {
+		return fmt.Sprintf("clean (%.2f)", v.Score)
+	}
+	return fmt.Sprintf("%s: %q (%.2f)", v.Category, v.Evidence, v.Score)
+}
+
+// Classifier scores text. Implementations may be local, like
+// WordlistClassifier, or call out to a moderation model; the latter should
+// return an error rather than a clean verdict when the model is unavailable.
+type Classifier interface {
+	Classify(ctx context.Context, text string) (Verdict, error)
+}
+
+// Filter flags text that any of its classifiers scores at or above a threshold.
+type Filter struct {
+	threshold   float64
+	classifiers []Classifier
+}
+
+// NewFilter creates a filter flagging text scored at least threshold.
+func NewFilter(threshold float64, classifiers ...Classifier) *Filter {
+	return &Filter{threshold: threshold, classifiers: classifiers}
+}
+
+// Check classifies text with every classifier and returns the highest-scoring
+// verdict, and whether it reaches the threshold.
+func (f *Filter) Check(ctx context.Context, text string) (Verdict, bool, error) {
+	var worst Verdict
+	if strings.TrimSpace(text) == "" {
+		return worst, false, nil
+	}
+
+	for _, c := range f.classifiers {
+		v, err := c.Classify(ctx, text)
+		if err != nil {
+			return Verdict{}, false, fmt.Errorf("safety classifier %T: %w", c, err)
+		}
+		if v.Score > worst.Score {
+			worst = v
+		}
+	}
+	return worst, worst.Score >= f.threshold, nil
+}
+
+// FromConfig builds the filter configured by cfg, or returns nil if safety
+// screening is off.
+func FromConfig(cfg config.Safety) (*Filter, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	rules := DefaultRules()
+	if cfg.WordlistFile != "" {
+		extra, err := LoadRules(cfg.WordlistFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load safety wordlist: %w", err)
+		}
+		rules = append(rules, extra...)
+	}
+	return NewFilter(cfg.Threshold, NewWordlistClassifier(rules)), nil
+}
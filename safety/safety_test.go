@@ -0,0 +1,81 @@
+package safety
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	f := NewFilter(0.8, NewWordlistClassifier(DefaultRules()))
+
+	tests := []struct {
+		name         string
+		text         string
+		wantCategory string
+		wantScore    float64
+		wantFlagged  bool
+	}{
+		{"clean", "A new compiler release speeds up builds", "", 0, false},
+		{"empty", "   ", "", 0, false},
+		{"tragedy", "Dozens killed in the storm", "tragedy", 0.9, true},
+		{"ignores case", "Dozens KILLED in the storm", "tragedy", 0.9, true},
+		{"below threshold", "Minor accident on the highway", "tragedy", 0.4, false},
+		{"whole words only", "The skilled team shipped it", "", 0, false},
+		{"highest rule wins", "Racist nazis rallied", "hate", 1.0, true},
+		{"adult content", "New OnlyFans feature", "nsfw", 1.0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, flagged, err := f.Check(context.Background(), tt.text)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if v.Category != tt.wantCategory || v.Score != tt.wantScore || flagged != tt.wantFlagged {
+				t.Errorf("Check(%q) = %s, flagged %v, want %s (%.2f), flagged %v",
+					tt.text, v.Reason(), flagged, tt.wantCategory, tt.wantScore, tt.wantFlagged)
+			}
+		})
+	}
+}
+
+// failingClassifier stands in for a moderation model that is unavailable.
+type failingClassifier struct{}
+
+func (failingClassifier) Classify(ctx context.Context, text string) (Verdict, error) {
+	return Verdict{}, errors.New("model unavailable")
+}
+
+func TestCheckClassifierError(t *testing.T) {
+	f := NewFilter(0.8, NewWordlistClassifier(DefaultRules()), failingClassifier{})
+	if _, flagged, err := f.Check(context.Background(), "A clean post"); err == nil || flagged {
+		t.Errorf("Check() = flagged %v, %v, want an error instead of a clean verdict", flagged, err)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name      string
+		wordlist  string
+		wantRules int
+		wantErr   bool
+	}{
+		{"rules, comments and blank lines", "# deaths\ntragedy 0.9 killed|died\n\nhate 1.0 heil\n", 2, false},
+		{"missing pattern", "tragedy 0.9\n", 0, true},
+		{"score out of range", "tragedy 1.5 killed\n", 0, true},
+		{"score not a number", "tragedy high killed\n", 0, true},
+		{"invalid pattern", "tragedy 0.9 (killed\n", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseRules(strings.NewReader(tt.wordlist))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRules() error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(rules) != tt.wantRules {
+				t.Errorf("ParseRules() = %d rules, want %d", len(rules), tt.wantRules)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package safety
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//go:embed wordlist.txt
+var defaultWordlist string
+
+// Rule scores text matching Pattern with Score.
+type Rule struct {
+	Category string
+	Score    float64
+	Pattern  *regexp.Regexp
+}
+
+// WordlistClassifier scores text by the highest-scoring rule it matches.
+type WordlistClassifier struct {
+	rules []Rule
+}
+
+// NewWordlistClassifier creates a classifier from rules.
+func NewWordlistClassifier(rules []Rule) *WordlistClassifier {
+	return &WordlistClassifier{rules: rules}
+}
+
+// DefaultRules returns the built-in rules.
+func DefaultRules() []Rule {
+	rules, err := ParseRules(strings.NewReader(defaultWordlist))
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in wordlist: %v", err))
+	}
+	return rules
+}
+
+// LoadRules reads rules from a wordlist file in the format of ParseRules.
+func LoadRules(path string) ([]Rule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rules, err := ParseRules(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// ParseRules reads one rule per line as "<category> <score> <pattern>", e.g.
+//
+//	tragedy 0.9 (killed|died) in
+//
+// The pattern is a regular expression matched case-insensitively against
+// whole words. Blank lines and lines starting with # are ignored.
+func ParseRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: want <category> <score> <pattern>", n)
+		}
+		score, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || score < 0 || score > 1 {
+			return nil, fmt.Errorf("line %d: score must be between 0 and 1, got %q", n, fields[1])
+		}
+		pattern, err := regexp.Compile(`(?i)\b(?:` + strings.TrimSpace(fields[2]) + `)\b`)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		rules = append(rules, Rule{Category: fields[0], Score: score, Pattern: pattern})
+	}
+	return rules, scanner.Err()
+}
+
+func (c *WordlistClassifier) Classify(ctx context.Context, text string) (Verdict, error) {
+	var v Verdict
+	for _, rule := range c.rules {
+		if rule.Score <= v.Score {
+			continue
+		}
+		if match := rule.Pattern.FindString(text); match != "" {
+			v = Verdict{Score: rule.Score, Category: rule.Category, Evidence: match}
+		}
+	}
+	return v, nil
+}
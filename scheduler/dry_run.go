@@ -2,61 +2,79 @@ package scheduler
 
 import (
 	"context"
-	"log"
+	"log/slog"
 
 	"github.com/dapplux/twitter-haiku-bot/services"
 )
 
 // DryRunScheduler sequentially executes all steps of your workflow.
 // It fetches posts, processes haiku summaries, processes haiku texts,
-// and posts haikus to the platform. Delays are inserted between steps
-// for demonstration purposes.
-func DryRunScheduler(ctx context.Context, haikuSvc *services.HaikuService, postSvc *services.PostService) {
-	log.Println("Starting Dry Run Scheduler...")
+// queues haikus for publishing and delivers them to the platform. Delays
+// are inserted between steps for demonstration purposes.
+func DryRunScheduler(ctx context.Context, haikuSvc *services.HaikuService, postSvc *services.PostService, dispatcher *services.OutboxDispatcher) {
+	slog.InfoContext(ctx, "Starting Dry Run Scheduler...")
 
 	// // Step 1: Fetch and Save posts.
-	// log.Println("Step 1: Fetching posts and saving them.")
+	// slog.InfoContext(ctx, "Step 1: Fetching posts and saving them.")
 	// if err := postSvc.FetchAndSave(ctx, 10); err != nil {
-	// 	log.Printf("Error in FetchAndSave: %v", err)
+	// 	slog.ErrorContext(ctx, "Error in FetchAndSave", "error", err)
 	// } else {
-	// 	log.Println("FetchAndSave executed successfully.")
+	// 	slog.InfoContext(ctx, "FetchAndSave executed successfully.")
 	// }
 	// // Optional delay between steps.
 	// time.Sleep(2 * time.Second)
 
-	// log.Println("Step 2: Creating haiku from unprocessed post.")
+	// slog.InfoContext(ctx, "Step 2: Creating haiku from unprocessed post.")
 	// if err := haikuSvc.CreateHaikuFromUnprocessedPost(ctx); err != nil {
-	// 	log.Printf("Error in CreateHaikuFromUnprocessedPost: %v", err)
+	// 	slog.ErrorContext(ctx, "Error in CreateHaikuFromUnprocessedPost", "error", err)
 	// } else {
-	// 	log.Println("CreateHaikuFromUnprocessedPost executed successfully.")
+	// 	slog.InfoContext(ctx, "CreateHaikuFromUnprocessedPost executed successfully.")
 	// }
 	// time.Sleep(2 * time.Second)
 
 	// // Step 3: Process Haiku Summary Generation.
-	// log.Println("Step 3: Processing Haiku Summary.")
+	// slog.InfoContext(ctx, "Step 3: Processing Haiku Summary.")
 	// if err := haikuSvc.ProcessSummary(ctx); err != nil {
-	// 	log.Printf("Error in ProcessSummary: %v", err)
+	// 	slog.ErrorContext(ctx, "Error in ProcessSummary", "error", err)
 	// } else {
-	// 	log.Println("ProcessSummary executed successfully.")
+	// 	slog.InfoContext(ctx, "ProcessSummary executed successfully.")
 	// }
 	// time.Sleep(2 * time.Second)
 
 	// // Step 4: Process Haiku Text Generation.
-	// log.Println("Step 4: Processing Haiku Text Generation.")
+	// slog.InfoContext(ctx, "Step 4: Processing Haiku Text Generation.")
 	// if err := haikuSvc.ProcessHaikuText(ctx); err != nil {
-	// 	log.Printf("Error in ProcessHaikuText: %v", err)
+	// 	slog.ErrorContext(ctx, "Error in ProcessHaikuText", "error", err)
 	// } else {
-	// 	log.Println("ProcessHaikuText executed successfully.")
+	// 	slog.InfoContext(ctx, "ProcessHaikuText executed successfully.")
 	// }
 	// time.Sleep(2 * time.Second)
 
-	// Step 5: Post Haiku to Platform.
-	log.Println("Step 5: Posting Haiku to Platform.")
+	// Step 5: Submit Haiku for Review (review mode only).
+	if haikuSvc.ReviewEnabled() {
+		slog.InfoContext(ctx, "Step 5: Submitting Haiku for Review.")
+		if err := haikuSvc.SubmitForReview(ctx); err != nil {
+			slog.ErrorContext(ctx, "Error in SubmitForReview", "error", err)
+		} else {
+			slog.InfoContext(ctx, "SubmitForReview executed successfully.")
+		}
+	}
+
+	// Step 6: Queue Haiku for Publishing.
+	slog.InfoContext(ctx, "Step 6: Queueing Haiku for Publishing.")
 	if err := haikuSvc.PostHaiku(ctx); err != nil {
-		log.Printf("Error in PostHaiku: %v", err)
+		slog.ErrorContext(ctx, "Error in PostHaiku", "error", err)
+	} else {
+		slog.InfoContext(ctx, "PostHaiku executed successfully.")
+	}
+
+	// Step 7: Deliver the queued publication to the Platform.
+	slog.InfoContext(ctx, "Step 7: Delivering Haiku to Platform.")
+	if err := dispatcher.DispatchNext(ctx); err != nil {
+		slog.ErrorContext(ctx, "Error in DispatchNext", "error", err)
 	} else {
-		log.Println("PostHaiku executed successfully.")
+		slog.InfoContext(ctx, "DispatchNext executed successfully.")
 	}
 
-	log.Println("Dry Run Scheduler completed.")
+	slog.InfoContext(ctx, "Dry Run Scheduler completed.")
 }
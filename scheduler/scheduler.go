@@ -2,111 +2,497 @@ package scheduler
 
 import (
 	"context"
-	"log"
-	"sync/atomic"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/dapplux/twitter-haiku-bot/config"
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/logging"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/quota"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/tracing"
 	"github.com/dapplux/twitter-haiku-bot/services"
 )
 
+// ParseSpec parses a cron spec with the same parser the Scheduler uses.
+func ParseSpec(spec string) (cron.Schedule, error) {
+	return config.CronParser.Parse(spec)
+}
+
+// cancelGrace is how long Stop waits for cancelled jobs to return.
+const cancelGrace = 5 * time.Second
+
+// Job names, used in logs and as leader election lock names.
+const (
+	JobFetchAndSave                   = "FetchAndSave"
+	JobCreateHaikuFromUnprocessedPost = "CreateHaikuFromUnprocessedPost"
+	JobProcessSummary                 = "ProcessSummary"
+	JobProcessHaikuText               = "ProcessHaikuText"
+	JobSubmitForReview                = "SubmitForReview"
+	JobPostHaiku                      = "PostHaiku"
+	JobDispatchOutbox                 = "DispatchOutbox"
+	JobProcessStopReplies             = "ProcessStopReplies"
+	JobProcessMentions                = "ProcessMentions"
+	JobTrackEngagement                = "TrackEngagement"
+)
+
+// Job is a named unit of work fired on a cron spec.
+type Job struct {
+	Name string
+	// Spec is the cron spec including any CRON_TZ prefix.
+	Spec string
+	// Jitter is the maximum random delay applied before each run.
+	Jitter time.Duration
+	// Singleton jobs must run on one replica only and require leadership.
+	Singleton bool
+	// Run performs one run. A run deferred for lack of budget is not an error.
+	Run func(ctx context.Context) error
+}
+
+// LeaderElector decides which replica runs singleton jobs.
+type LeaderElector interface {
+	IsLeader(job string) bool
+}
+
 type Scheduler struct {
 	cron          *cron.Cron
 	haikuService  *services.HaikuService
 	postService   *services.PostService
-	platformIndex uint64 // for round-robin if needed
-	// Optionally, you can maintain counters for monthly usage.
-	monthlyFetchCount int64
-	monthlyFetchLimit int64 // e.g., 100
+	authorService *services.AuthorService
+	// mentionService is nil while haiku requests by mention are off.
+	mentionService    *services.MentionService
+	engagementService *services.EngagementService
+	dispatcher        *services.OutboxDispatcher
+	platformIndex     uint64 // for round-robin if needed
+	// quota defers jobs whose API budget is exhausted.
+	quota    quota.Meter
+	schedule config.Schedule
+	// leader gates singleton jobs; nil means this is the only replica.
+	leader LeaderElector
+
+	triggered sync.WaitGroup // runs started by Trigger rather than cron
+
+	mu sync.Mutex
+	// ctx is the Start context; once cancelled no new runs begin.
+	ctx context.Context
+	// jobCtx is handed to running jobs. It outlives the Start context so that
+	// shutdown lets in-flight jobs finish, and is cancelled only when Stop's deadline expires.
+	jobCtx     context.Context
+	cancelJobs context.CancelFunc
+	jobs       map[string]Job
+
+	running map[string]time.Time    // job name -> start time
+	pending map[string]bool         // job name -> rerun requested while running
+	entries map[string]cron.EntryID // job name -> cron entry
+	history map[string]*JobStatus   // job name -> outcome of finished runs
 }
 
-// NewScheduler creates a new Scheduler instance.
-func NewScheduler(haikuSvc *services.HaikuService, postSvc *services.PostService) *Scheduler {
+// NewScheduler creates a new Scheduler instance running jobs per the given
+// schedule. mentionSvc may be nil to turn haiku requests off, and leader
+// when only one replica runs.
+func NewScheduler(haikuSvc *services.HaikuService, postSvc *services.PostService, authorSvc *services.AuthorService, mentionSvc *services.MentionService, engagementSvc *services.EngagementService, dispatcher *services.OutboxDispatcher, meter quota.Meter, schedule config.Schedule, leader LeaderElector) *Scheduler {
 	return &Scheduler{
-		cron:              cron.New(cron.WithSeconds()),
+		cron:              cron.New(cron.WithParser(config.CronParser)),
 		haikuService:      haikuSvc,
 		postService:       postSvc,
-		monthlyFetchLimit: 100,
+		authorService:     authorSvc,
+		mentionService:    mentionSvc,
+		engagementService: engagementSvc,
+		dispatcher:        dispatcher,
+		quota:             meter,
+		schedule:          schedule,
+		leader:            leader,
+		running:           make(map[string]time.Time),
+		pending:           make(map[string]bool),
+		entries:           make(map[string]cron.EntryID),
+		history:           make(map[string]*JobStatus),
 	}
 }
 
-// Start configures and starts all scheduled jobs.
-func (s *Scheduler) Start(ctx context.Context) {
-	// Schedule fetching posts every Tuesday evening at 18:00.
-	// Cron format: "MINUTE HOUR DOM MONTH DOW"
-	// This expression means: At minute 0, hour 18, every day-of-month, every month, on Tuesday.
-	_, err := s.cron.AddFunc("0 0 18 * * 2", func() {
-		if atomic.LoadInt64(&s.monthlyFetchCount) >= s.monthlyFetchLimit {
-			log.Println("Monthly fetch limit reached, skipping fetch cycle.")
-			return
-		}
+// eventTriggers maps notification channels to the job that consumes the
+// announced work. Queueing haikus for publishing is left out on purpose: it
+// keeps its cron cadence, while queued publications are delivered right away.
+var eventTriggers = map[string]string{
+	services.PostsSavedChannel:                           JobCreateHaikuFromUnprocessedPost,
+	services.StateChannel(entities.HaikuStateCreated):    JobProcessSummary,
+	services.StateChannel(entities.HaikuStateSummaryGot): JobProcessHaikuText,
+	// Only fires while review mode is on; see Jobs.
+	services.StateChannel(entities.HaikuStateHaikuTextGot): JobSubmitForReview,
+	services.StateChannel(entities.HaikuStateComenting):    JobDispatchOutbox,
+}
 
-		log.Println("Running PostService.FetchAndSave")
-		// Fetch posts (limit could be 10 posts per fetch)
-		if err := s.postService.FetchAndSave(ctx, 10); err != nil {
-			log.Printf("Error in FetchAndSave: %v", err)
-			return
-		}
+// EventChannels returns the notification channels the Scheduler reacts to.
+func EventChannels() []string {
+	channels := make([]string, 0, len(eventTriggers))
+	for channel := range eventTriggers {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
 
-		atomic.AddInt64(&s.monthlyFetchCount, 1)
-	})
-	if err != nil {
-		log.Printf("Failed to schedule FetchAndSave: %v", err)
+// SingletonJobs returns the names of all jobs that require leadership,
+// enabled or not, so every replica agrees on the set of locks.
+func SingletonJobs() []string {
+	return []string{JobFetchAndSave, JobCreateHaikuFromUnprocessedPost, JobSubmitForReview, JobPostHaiku, JobProcessStopReplies, JobProcessMentions, JobTrackEngagement}
+}
+
+// Jobs returns the enabled jobs together with their cron specs.
+// Start registers them with cron; the simulator drives them from a virtual clock.
+func (s *Scheduler) Jobs() []Job {
+	fetch := s.schedule.FetchPosts
+	candidates := []struct {
+		cfg config.Job
+		job Job
+	}{
+		{fetch, Job{
+			Name:      JobFetchAndSave,
+			Singleton: true,
+			Run: func(ctx context.Context) error {
+				if !s.withinBudget(ctx, quota.ProviderTwitter, quota.ResourceTweetsRead, int64(fetch.BatchSize)) {
+					return nil
+				}
+
+				slog.InfoContext(ctx, "Running PostService.FetchAndSave")
+				if err := s.postService.FetchAndSave(ctx, fetch.BatchSize); err != nil {
+					slog.ErrorContext(ctx, "Error in FetchAndSave", "error", err)
+					return err
+				}
+				return nil
+			},
+		}},
+		{s.schedule.CreateHaiku, Job{
+			// Two replicas could otherwise create two haikus for the same post.
+			Name:      JobCreateHaikuFromUnprocessedPost,
+			Singleton: true,
+			Run: s.batch("HaikuService.CreateHaikuFromUnprocessedPost", s.schedule.CreateHaiku.BatchSize,
+				s.haikuService.CreateHaikuFromUnprocessedPost),
+		}},
+		{s.schedule.ProcessSummary, Job{
+			Name: JobProcessSummary,
+			Run: s.budgeted(quota.ProviderHuggingFace, quota.ResourceRequests,
+				s.batch("HaikuService.ProcessSummary", s.schedule.ProcessSummary.BatchSize, s.haikuService.ProcessSummary)),
+		}},
+		{s.schedule.ProcessHaikuText, Job{
+			Name: JobProcessHaikuText,
+			Run: s.budgeted(quota.ProviderHuggingFace, quota.ResourceRequests,
+				s.batch("HaikuService.ProcessHaikuText", s.schedule.ProcessHaikuText.BatchSize, s.haikuService.ProcessHaikuText)),
+		}},
+		{s.schedule.SubmitForReview, Job{
+			// Haikus are not claimed for review, so replicas would screen and
+			// evaluate the same haiku twice.
+			Name:      JobSubmitForReview,
+			Singleton: true,
+			Run:       s.batch("HaikuService.SubmitForReview", s.schedule.SubmitForReview.BatchSize, s.haikuService.SubmitForReview),
+		}},
+		{s.schedule.PostHaiku, Job{
+			Name:      JobPostHaiku,
+			Singleton: true,
+			Run: s.budgeted(quota.ProviderTwitter, quota.ResourceTweetsWritten,
+				s.batch("HaikuService.PostHaiku", s.schedule.PostHaiku.BatchSize, s.haikuService.PostHaiku)),
+		}},
+		{s.schedule.DispatchOutbox, Job{
+			// Replicas claim entries with SKIP LOCKED, so dispatching needs no leader.
+			Name: JobDispatchOutbox,
+			Run: s.budgeted(quota.ProviderTwitter, quota.ResourceTweetsWritten,
+				s.batch("OutboxDispatcher.DispatchNext", s.schedule.DispatchOutbox.BatchSize, s.dispatcher.DispatchNext)),
+		}},
+		{s.schedule.ProcessStopReplies, Job{
+			// Replicas would otherwise race on the mention cursor.
+			Name:      JobProcessStopReplies,
+			Singleton: true,
+			Run: func(ctx context.Context) error {
+				limit := s.schedule.ProcessStopReplies.BatchSize
+				if !s.withinBudget(ctx, quota.ProviderTwitter, quota.ResourceTweetsRead, int64(limit)) {
+					return nil
+				}
+				return s.authorService.ProcessStopReplies(ctx, limit)
+			},
+		}},
+		{s.schedule.ProcessMentions, Job{
+			// Replicas would otherwise race on the mention cursor.
+			Name:      JobProcessMentions,
+			Singleton: true,
+			Run: func(ctx context.Context) error {
+				limit := s.schedule.ProcessMentions.BatchSize
+				if !s.withinBudget(ctx, quota.ProviderTwitter, quota.ResourceTweetsRead, int64(limit)) {
+					return nil
+				}
+				return s.mentionService.ProcessMentions(ctx, limit)
+			},
+		}},
+		{s.schedule.TrackEngagement, Job{
+			// Replicas would otherwise store every sample twice.
+			Name:      JobTrackEngagement,
+			Singleton: true,
+			// The number of replies read is only known once the job runs,
+			// so the platform checks the budget of each lookup.
+			Run: s.budgeted(quota.ProviderTwitter, quota.ResourceTweetsRead, func(ctx context.Context) error {
+				return s.engagementService.TrackEngagement(ctx, s.schedule.TrackEngagement.BatchSize)
+			}),
+		}},
 	}
 
-	// Schedule sCreateHaikuFromUnprocessedPost job.
-	_, err = s.cron.AddFunc("@every 1m", func() {
-		log.Println("Running HaikuService.CreateHaikuFromUnprocessedPost")
-		if err := s.haikuService.CreateHaikuFromUnprocessedPost(ctx); err != nil {
-			log.Printf("Error in CreateHaikuFromUnprocessedPost: %v", err)
+	var jobs []Job
+	for _, c := range candidates {
+		if !c.cfg.Enabled {
+			continue
+		}
+		if c.job.Name == JobSubmitForReview && !s.haikuService.ReviewEnabled() {
+			continue
+		}
+		if c.job.Name == JobProcessMentions && s.mentionService == nil {
+			continue
 		}
-	})
-	if err != nil {
-		log.Printf("Failed to schedule CreateHaikuFromUnprocessedPost: %v", err)
+		c.job.Spec = c.cfg.CronSpec()
+		c.job.Jitter = c.cfg.Jitter
+		jobs = append(jobs, c.job)
 	}
+	return jobs
+}
 
-	// Schedule summary processing job.
-	// Runs every 2 hours (adjust if necessary).
-	_, err = s.cron.AddFunc("@every 2m", func() {
-		log.Println("Running HaikuService.ProcessSummary")
-		if err := s.haikuService.ProcessSummary(ctx); err != nil {
-			log.Printf("Error in ProcessSummary: %v", err)
+// batch runs step up to size times, stopping early once there is no work left.
+// It returns the errors of failed steps; deferred steps are not failures.
+func (s *Scheduler) batch(name string, size int, step func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		slog.InfoContext(ctx, "Running "+name)
+		var errs []error
+		for i := 0; i < size; i++ {
+			err := step(ctx)
+			if errors.Is(err, services.ErrNoWork) {
+				slog.DebugContext(ctx, name+": nothing to do", "error", err)
+				break
+			}
+			if err != nil {
+				slog.ErrorContext(ctx, "Error in "+name, "error", err)
+				if !errors.Is(err, quota.ErrBudgetExceeded) {
+					errs = append(errs, err)
+				}
+			}
 		}
-	})
-	if err != nil {
-		log.Printf("Failed to schedule ProcessSummary: %v", err)
+		return errors.Join(errs...)
 	}
+}
 
-	// Schedule haiku text generation job.
-	// Runs every 2 hours, offset by 30 minutes from the summary processing job.
-	_, err = s.cron.AddFunc("@every 2m", func() {
-		log.Println("Running HaikuService.ProcessHaikuText")
-		if err := s.haikuService.ProcessHaikuText(ctx); err != nil {
-			log.Printf("Error in ProcessHaikuText: %v", err)
+// budgeted skips run when a single unit of the resource would exceed its budget.
+func (s *Scheduler) budgeted(provider, resource string, run func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if !s.withinBudget(ctx, provider, resource, 1) {
+			return nil
 		}
-	})
-	if err != nil {
-		log.Printf("Failed to schedule ProcessHaikuText: %v", err)
+		return run(ctx)
+	}
+}
+
+// withinBudget reports whether a job may consume amount of a resource. Jobs
+// over budget are skipped; their work stays queued for a later run.
+func (s *Scheduler) withinBudget(ctx context.Context, provider, resource string, amount int64) bool {
+	if err := s.quota.Allow(ctx, provider, resource, amount); err != nil {
+		slog.InfoContext(ctx, "Deferring job", "error", err)
+		return false
 	}
+	return true
+}
 
-	// Schedule posting haikus.
-	// The cron expression "0 0 */3 * * *" means: at second 0, minute 0, every 3rd hour of every day.
-	_, err = s.cron.AddFunc("0 0 */3 * * *", func() {
-		log.Println("Running HaikuService.PostHaiku")
-		if err := s.haikuService.PostHaiku(ctx); err != nil {
-			log.Printf("Error in PostHaiku: %v", err)
+// Start configures and starts all scheduled jobs. Once ctx is cancelled no
+// new runs begin; runs already in progress continue until Stop.
+func (s *Scheduler) Start(ctx context.Context) {
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	all := s.Jobs()
+	jobs := make(map[string]Job, len(all))
+	for _, job := range all {
+		jobs[job.Name] = job
+	}
+	s.mu.Lock()
+	s.ctx, s.jobCtx, s.cancelJobs, s.jobs = ctx, jobCtx, cancelJobs, jobs
+	s.mu.Unlock()
+
+	for _, job := range all {
+		job := job
+		id, err := s.cron.AddFunc(job.Spec, func() {
+			if !sleepJitter(ctx, job.Jitter) {
+				return
+			}
+			s.runJob(job)
+		})
+		if err != nil {
+			slog.Error("Failed to schedule job", "job", job.Name, "spec", job.Spec, "error", err)
+			continue
 		}
-	})
-	if err != nil {
-		log.Printf("Failed to schedule PostHaiku: %v", err)
+		s.mu.Lock()
+		s.entries[job.Name] = id
+		s.mu.Unlock()
 	}
 
 	s.cron.Start()
-	log.Println("Scheduler started")
+	slog.Info("Scheduler started")
+}
+
+// Trigger runs the named job now, outside its cron schedule. Unknown or
+// disabled jobs are ignored.
+func (s *Scheduler) Trigger(name string) {
+	if !s.Started() {
+		return
+	}
+	s.mu.Lock()
+	job, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	s.triggered.Add(1)
+	go func() {
+		defer s.triggered.Done()
+		s.runJob(job)
+	}()
 }
 
-// Stop stops the cron scheduler.
-func (s *Scheduler) Stop() {
-	s.cron.Stop()
-	log.Println("Scheduler stopped")
+// HandleNotification triggers the job that consumes work announced on channel.
+func (s *Scheduler) HandleNotification(channel, payload string) {
+	if name, ok := eventTriggers[channel]; ok {
+		s.Trigger(name)
+	}
+}
+
+// runJob runs job unless this replica is not its leader. At most one run of
+// a job is in progress at a time: a run requested meanwhile is coalesced into
+// a single rerun once the current one finishes.
+func (s *Scheduler) runJob(job Job) {
+	if s.ctx.Err() != nil {
+		return
+	}
+	if job.Singleton && s.leader != nil && !s.leader.IsLeader(job.Name) {
+		slog.Debug("Skipping job: not the leader", "job", job.Name)
+		return
+	}
+	if !s.beginRun(job.Name) {
+		return
+	}
+
+	for {
+		s.recordRun(job.Name, runJobOnce(s.jobCtx, job))
+		if !s.endRun(job.Name) || s.ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// runJobOnce runs job in a span of its own. Every run gets its own ID so its
+// log lines can be grouped.
+func runJobOnce(ctx context.Context, job Job) error {
+	runID := uuid.NewString()
+	ctx, span := tracing.Tracer().Start(logging.With(ctx, "job", job.Name, "run_id", runID), "job "+job.Name,
+		trace.WithAttributes(attribute.String("job.name", job.Name), attribute.String("job.run_id", runID)))
+
+	err := job.Run(ctx)
+	tracing.End(span, err)
+	return err
+}
+
+// sleepJitter waits a random duration up to max. It returns false if ctx is
+// cancelled while waiting.
+func sleepJitter(ctx context.Context, max time.Duration) bool {
+	if max <= 0 {
+		return true
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(time.Duration(rand.Int63n(int64(max)))):
+		return true
+	}
+}
+
+// beginRun marks the job as running. If it already is, a rerun is queued
+// and false is returned.
+func (s *Scheduler) beginRun(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, busy := s.running[name]; busy {
+		s.pending[name] = true
+		return false
+	}
+	s.running[name] = time.Now()
+	return true
+}
+
+// endRun reports whether a rerun was queued; if so the job stays marked as running.
+func (s *Scheduler) endRun(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending[name] {
+		delete(s.pending, name)
+		s.running[name] = time.Now()
+		return true
+	}
+	delete(s.running, name)
+	return false
+}
+
+// Running describes the jobs currently in progress, e.g. "PostHaiku (running 3s)".
+func (s *Scheduler) Running() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var jobs []string
+	for name, start := range s.running {
+		jobs = append(jobs, fmt.Sprintf("%s (running %s)", name, time.Since(start).Round(time.Millisecond)))
+	}
+	sort.Strings(jobs)
+	return jobs
+}
+
+// Stop stops scheduling new runs and waits for running jobs to finish. If ctx
+// expires first, the running jobs are cancelled and reported as interrupted,
+// and given cancelGrace to return so none outlives the database.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	cronDone := s.cron.Stop()
+	done := make(chan struct{})
+	go func() {
+		<-cronDone.Done()
+		s.triggered.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.Info("Scheduler stopped")
+		return nil
+	case <-ctx.Done():
+	}
+
+	interrupted := s.Running()
+	for _, job := range interrupted {
+		slog.Warn("Interrupted job", "job", job)
+	}
+	for _, h := range s.haikuService.InFlight() {
+		slog.Warn("Interrupted haiku", logging.Haiku(&h)...)
+	}
+	s.mu.Lock()
+	cancelJobs := s.cancelJobs
+	s.mu.Unlock()
+	if cancelJobs != nil {
+		cancelJobs()
+	}
+	select {
+	case <-done:
+	case <-time.After(cancelGrace):
+		slog.Warn("Jobs still running after cancellation", "grace", cancelGrace)
+	}
+
+	return fmt.Errorf("scheduler stopped before %d job(s) finished: %s", len(interrupted), strings.Join(interrupted, ", "))
 }
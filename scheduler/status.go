@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+)
+
+// JobStatus describes a scheduled job and the outcome of its finished runs on
+// this replica. Zero times mean "never".
+type JobStatus struct {
+	Name      string    `json:"name"`
+	Spec      string    `json:"spec"`
+	Singleton bool      `json:"singleton"`
+	Running   bool      `json:"running"`
+	NextRun   time.Time `json:"next_run"`
+	PrevRun   time.Time `json:"prev_run"`
+	// LastSuccess is when the most recent successful run finished.
+	LastSuccess time.Time `json:"last_success"`
+	// LastFailure is when the most recent failed run finished, with its error.
+	LastFailure time.Time `json:"last_failure"`
+	LastError   string    `json:"last_error,omitempty"`
+	// ConsecutiveFailures counts failed runs since the last success.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+}
+
+// recordRun stores the outcome of a finished run of the named job.
+func (s *Scheduler) recordRun(name string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.history[name]
+	if !ok {
+		h = &JobStatus{}
+		s.history[name] = h
+	}
+	if err != nil {
+		h.LastFailure = time.Now()
+		h.LastError = err.Error()
+		h.ConsecutiveFailures++
+		return
+	}
+	h.LastSuccess = time.Now()
+	h.ConsecutiveFailures = 0
+}
+
+// Started reports whether the scheduler has been started and is not shutting down.
+func (s *Scheduler) Started() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ctx != nil && s.ctx.Err() == nil
+}
+
+// JobStatuses returns the status of every scheduled job, by name.
+func (s *Scheduler) JobStatuses() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.entries))
+	for name, id := range s.entries {
+		status := JobStatus{}
+		if h, ok := s.history[name]; ok {
+			status = *h
+		}
+		job := s.jobs[name]
+		entry := s.cron.Entry(id)
+		status.Name = name
+		status.Spec = job.Spec
+		status.Singleton = job.Singleton
+		status.NextRun = entry.Next
+		status.PrevRun = entry.Prev
+		_, status.Running = s.running[name]
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
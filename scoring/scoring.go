@@ -0,0 +1,93 @@
+// Package scoring decides in which order fetched posts are haikued.
+package scoring
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/config"
+	"github.com/dapplux/twitter-haiku-bot/entities"
+)
+
+// topic is a keyword and the weight posts mentioning it gain.
+type topic struct {
+	pattern *regexp.Regexp
+	weight  float64
+}
+
+// Scorer computes post priorities. A post scores its weighted engagement
+// plus the weights of its topics, halved for every half-life of its age and
+// divided by one plus the diversity penalty for every recent post of its
+// author.
+type Scorer struct {
+	engagementWeight  float64
+	freshnessHalfLife time.Duration
+	diversityPenalty  float64
+	diversityWindow   time.Duration
+	topics            []topic
+	agingPerHour      float64
+}
+
+// FromConfig builds the scorer configured by cfg, or returns nil if scoring
+// is off.
+func FromConfig(cfg config.Scoring) *Scorer {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	s := &Scorer{
+		engagementWeight:  cfg.EngagementWeight,
+		freshnessHalfLife: cfg.FreshnessHalfLife,
+		diversityPenalty:  cfg.DiversityPenalty,
+		diversityWindow:   cfg.DiversityWindow,
+		agingPerHour:      cfg.AgingPerHour,
+	}
+	keywords := make([]string, 0, len(cfg.Topics))
+	for keyword := range cfg.Topics {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	for _, keyword := range keywords {
+		s.topics = append(s.topics, topic{
+			pattern: regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(keyword) + `\b`),
+			weight:  cfg.Topics[keyword],
+		})
+	}
+	return s
+}
+
+// Score returns the priority of post at now, given how many posts of its
+// author were kept within the diversity window. Posts without a creation
+// time are treated as brand new.
+func (s *Scorer) Score(post entities.Post, authorRecent int, now time.Time) float64 {
+	score := s.engagementWeight * math.Log1p(float64(post.Likes+2*post.Shares+post.Replies))
+	for _, t := range s.topics {
+		if t.pattern.MatchString(post.Text) {
+			score += t.weight
+		}
+	}
+
+	if !post.CreatedAt.IsZero() {
+		if age := now.Sub(post.CreatedAt); age > 0 {
+			score *= math.Exp2(-float64(age) / float64(s.freshnessHalfLife))
+		}
+	}
+	return score / (1 + s.diversityPenalty*float64(authorRecent))
+}
+
+// DiversityWindow is how far back posts of the same author count against a
+// new one.
+func (s *Scorer) DiversityWindow() time.Duration {
+	return s.diversityWindow
+}
+
+// AgingPerHour is the priority queued items gain for every hour they wait.
+// It is zero for a nil scorer, which leaves queues in arrival order.
+func (s *Scorer) AgingPerHour() float64 {
+	if s == nil {
+		return 0
+	}
+	return s.agingPerHour
+}
@@ -0,0 +1,90 @@
+package scoring
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/config"
+	"github.com/dapplux/twitter-haiku-bot/entities"
+)
+
+func testScorer() *Scorer {
+	return FromConfig(config.Scoring{
+		Enabled:           true,
+		EngagementWeight:  1,
+		FreshnessHalfLife: 6 * time.Hour,
+		DiversityPenalty:  0.5,
+		DiversityWindow:   24 * time.Hour,
+		Topics:            map[string]float64{"golang": 2, "rust": 1, "crypto": -3},
+		AgingPerHour:      0.25,
+	})
+}
+
+func TestScore(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	engaged := math.Log1p(10 + 2*5 + 3)
+
+	tests := []struct {
+		name         string
+		post         entities.Post
+		authorRecent int
+		want         float64
+	}{
+		{"no engagement, no topics", entities.Post{Text: "hello", CreatedAt: now}, 0, 0},
+		{"engagement weighs shares double", entities.Post{Likes: 10, Shares: 5, Replies: 3, CreatedAt: now}, 0, engaged},
+		{"topic weights add up", entities.Post{Text: "Golang and Rust news", CreatedAt: now}, 0, 3},
+		{"topics match whole words only", entities.Post{Text: "golangci and rustacean", CreatedAt: now}, 0, 0},
+		{"negative weights demote", entities.Post{Text: "crypto golang", CreatedAt: now}, 0, -1},
+		{"halved every half-life", entities.Post{Text: "golang", CreatedAt: now.Add(-6 * time.Hour)}, 0, 1},
+		{"quartered after two half-lives", entities.Post{Text: "golang", CreatedAt: now.Add(-12 * time.Hour)}, 0, 0.5},
+		{"future posts are not boosted", entities.Post{Text: "golang", CreatedAt: now.Add(time.Hour)}, 0, 2},
+		{"missing creation time is new", entities.Post{Text: "golang"}, 0, 2},
+		{"recent posts of author penalized", entities.Post{Text: "golang", CreatedAt: now}, 2, 1},
+	}
+	s := testScorer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Score(tt.post, tt.authorRecent, now); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Score() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreOrdering(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := testScorer()
+
+	// Each pair lists the post that must score higher first.
+	tests := []struct {
+		name          string
+		higher, lower entities.Post
+	}{
+		{"heavier topic", entities.Post{Text: "golang", CreatedAt: now}, entities.Post{Text: "rust", CreatedAt: now}},
+		{"more engagement", entities.Post{Likes: 100, CreatedAt: now}, entities.Post{Likes: 10, CreatedAt: now}},
+		{"share over like", entities.Post{Shares: 1, CreatedAt: now}, entities.Post{Likes: 1, CreatedAt: now}},
+		{"fresher", entities.Post{Text: "golang", CreatedAt: now.Add(-time.Hour)}, entities.Post{Text: "golang", CreatedAt: now.Add(-5 * time.Hour)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			higher, lower := s.Score(tt.higher, 0, now), s.Score(tt.lower, 0, now)
+			if higher <= lower {
+				t.Errorf("Score = %v, want above %v", higher, lower)
+			}
+		})
+	}
+}
+
+func TestAgingPerHour(t *testing.T) {
+	if got := testScorer().AgingPerHour(); got != 0.25 {
+		t.Errorf("AgingPerHour() = %v, want 0.25", got)
+	}
+	var off *Scorer
+	if got := off.AgingPerHour(); got != 0 {
+		t.Errorf("nil AgingPerHour() = %v, want 0", got)
+	}
+	if s := FromConfig(config.Scoring{Enabled: false, AgingPerHour: 1}); s != nil {
+		t.Errorf("FromConfig() = %v for disabled scoring, want nil", s)
+	}
+}
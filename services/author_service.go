@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/platforms"
+	"github.com/guregu/null"
+	"gorm.io/gorm"
+)
+
+// optOutCursor remembers the newest mention checked for opt-out replies.
+const optOutCursor = "mentions.optout"
+
+// stopPattern matches replies asking the bot to leave the author alone,
+// after any leading @mentions.
+var stopPattern = regexp.MustCompile(`(?i)^(?:@\w+\s+)*(?:stop|unsubscribe|opt[ -]?out|leave me alone|no more)\b`)
+
+// AuthorService manages the authors the bot must not reply to.
+type AuthorService struct {
+	unit       repositories.UnitOfWork
+	authorRepo repositories.AuthorListRepository
+	outboxRepo repositories.OutboxRepository
+	cursorRepo repositories.CursorRepository
+	platform   platforms.PlatformProvider
+}
+
+func NewAuthorService(unit repositories.UnitOfWork, authorRepo repositories.AuthorListRepository, outboxRepo repositories.OutboxRepository, cursorRepo repositories.CursorRepository, platform platforms.PlatformProvider) *AuthorService {
+	return &AuthorService{
+		unit:       unit,
+		authorRepo: authorRepo,
+		outboxRepo: outboxRepo,
+		cursorRepo: cursorRepo,
+		platform:   platform,
+	}
+}
+
+// Add puts an author on list. Adding an author already on it is not an error
+// and keeps the original entry.
+func (s *AuthorService) Add(ctx context.Context, list entities.AuthorList, entry entities.AuthorListEntry) (*entities.AuthorListEntry, error) {
+	if !slices.Contains(entities.AuthorLists, list) {
+		return nil, fmt.Errorf("%w: unknown author list %q", ErrInvalidInput, list)
+	}
+	if !slices.Contains(entities.Platforms, entry.Platform) {
+		return nil, fmt.Errorf("%w: unknown platform %q", ErrInvalidInput, entry.Platform)
+	}
+	if entry.AuthorID = strings.TrimSpace(entry.AuthorID); entry.AuthorID == "" {
+		return nil, fmt.Errorf("%w: author ID must not be empty", ErrInvalidInput)
+	}
+
+	added, err := s.authorRepo.Add(ctx, nil, list, &entry)
+	if err != nil {
+		return nil, err
+	}
+	if added {
+		slog.InfoContext(ctx, "Author added to list", "list", string(list), "platform", string(entry.Platform), "author_id", entry.AuthorID, "added_by", entry.AddedBy)
+	}
+	return &entry, nil
+}
+
+// Remove takes an author off list.
+func (s *AuthorService) Remove(ctx context.Context, list entities.AuthorList, platform entities.Platform, authorID string) error {
+	if !slices.Contains(entities.AuthorLists, list) {
+		return fmt.Errorf("%w: unknown author list %q", ErrInvalidInput, list)
+	}
+	if err := s.authorRepo.Remove(ctx, nil, list, platform, authorID); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Author removed from list", "list", string(list), "platform", string(platform), "author_id", authorID)
+	return nil
+}
+
+// ProcessStopReplies reads up to limit new mentions and opts out the authors
+// of replies asking one of the bot's haikus to stop. The mention cursor is
+// advanced in the same transaction, so each mention is handled once.
+func (s *AuthorService) ProcessStopReplies(ctx context.Context, limit int) error {
+	since, err := s.cursorRepo.Get(ctx, nil, optOutCursor)
+	if err != nil {
+		return fmt.Errorf("failed to read mention cursor: %w", err)
+	}
+	mentions, err := s.platform.FetchMentions(ctx, since, limit)
+	if err != nil {
+		return err
+	}
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	return s.unit.Transaction(func(tx *gorm.DB) error {
+		for _, m := range mentions {
+			if m.InReplyToID == "" || !stopPattern.MatchString(strings.TrimSpace(m.Text)) {
+				continue
+			}
+			if _, err := s.outboxRepo.FindByReceipt(ctx, tx, m.Platform, m.InReplyToID); err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					continue // not a reply to one of our haikus
+				}
+				return fmt.Errorf("failed to look up replied-to haiku: %w", err)
+			}
+
+			added, err := s.authorRepo.Add(ctx, tx, entities.AuthorListOptOut, &entities.AuthorListEntry{
+				Platform: m.Platform,
+				AuthorID: m.Author.ID,
+				Username: m.Author.Username,
+				Reason:   null.StringFrom(fmt.Sprintf("replied %q to %s", m.Text, m.InReplyToID)),
+				AddedBy:  entities.AddedByReply,
+			})
+			if err != nil {
+				return err
+			}
+			if added {
+				slog.InfoContext(ctx, "Author opted out by reply", "platform", string(m.Platform), "author_id", m.Author.ID, "username", m.Author.Username, "mention_id", m.ID)
+			}
+		}
+		return s.cursorRepo.Set(ctx, tx, optOutCursor, mentions[len(mentions)-1].ID)
+	})
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/logging"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/metrics"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/platforms"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/quota"
+)
+
+// EngagementService samples the public metrics of published haikus into a
+// time series and ranks what was published by how it was received.
+type EngagementService struct {
+	haikuRepo      repositories.HaikuRepository
+	engagementRepo repositories.EngagementRepository
+	platform       platforms.PlatformProvider
+	// trackFor is how long after publishing a haiku is sampled.
+	trackFor time.Duration
+	now      func() time.Time
+}
+
+// NewEngagementService creates an EngagementService. now may be nil to use
+// wall-clock time.
+func NewEngagementService(haikuRepo repositories.HaikuRepository, engagementRepo repositories.EngagementRepository, platform platforms.PlatformProvider, trackFor time.Duration, now func() time.Time) *EngagementService {
+	if now == nil {
+		now = time.Now
+	}
+
+	return &EngagementService{
+		haikuRepo:      haikuRepo,
+		engagementRepo: engagementRepo,
+		platform:       platform,
+		trackFor:       trackFor,
+		now:            now,
+	}
+}
+
+// TrackEngagement samples every haiku published within the tracking window,
+// asking the platform about batchSize replies at a time. Replies the
+// platform no longer shows are skipped. Samples of earlier batches are kept
+// when a later one fails or would exceed the read budget; the rest wait for
+// the next run.
+func (s *EngagementService) TrackEngagement(ctx context.Context, batchSize int) error {
+	haikus, err := s.haikuRepo.FindPublishedSince(ctx, nil, s.now().Add(-s.trackFor))
+	if err != nil {
+		return err
+	}
+
+	sampled := 0
+	for batch := range slices.Chunk(haikus, batchSize) {
+		replyIDs := make([]string, 0, len(batch))
+		for _, h := range batch {
+			replyIDs = append(replyIDs, h.ReplyID.String)
+		}
+		engagement, err := s.platform.FetchEngagement(ctx, replyIDs)
+		if errors.Is(err, quota.ErrBudgetExceeded) {
+			slog.InfoContext(ctx, "Deferring engagement sampling", "sampled", sampled, "haikus", len(haikus), "error", err)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to fetch engagement: %w", err)
+		}
+
+		now := s.now()
+		samples := make([]entities.EngagementSample, 0, len(engagement))
+		for _, h := range batch {
+			e, ok := engagement[h.ReplyID.String]
+			if !ok {
+				slog.DebugContext(logging.With(ctx, logging.Haiku(&h)...), "Published haiku not found on the platform", "reply_id", h.ReplyID.String)
+				continue
+			}
+			samples = append(samples, entities.EngagementSample{
+				HaikuID:    h.ID,
+				Platform:   h.Post.Platform,
+				ReplyID:    h.ReplyID.String,
+				Engagement: e,
+				SampledAt:  now,
+			})
+		}
+		if err := s.engagementRepo.AddSamples(ctx, nil, samples); err != nil {
+			return fmt.Errorf("failed to store engagement: %w", err)
+		}
+		for _, sample := range samples {
+			metrics.EngagementSamples.WithLabelValues(string(sample.Platform)).Inc()
+		}
+		sampled += len(samples)
+	}
+
+	slog.InfoContext(ctx, "Engagement sampled", "sampled", sampled, "haikus", len(haikus))
+	return nil
+}
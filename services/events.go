@@ -0,0 +1,12 @@
+package services
+
+import "github.com/dapplux/twitter-haiku-bot/entities"
+
+// PostsSavedChannel is notified after FetchAndSave stores new posts.
+const PostsSavedChannel = "posts_saved"
+
+// StateChannel returns the notification channel announcing haikus that
+// entered state, e.g. "haiku_summary_got". The payload is the haiku ID.
+func StateChannel(state entities.HaikuState) string {
+	return "haiku_" + string(state)
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/logging"
+	"github.com/guregu/null"
+	"gorm.io/gorm"
+)
+
+// Manual changes made through the admin API. Each locks the haiku, checks its
+// state and records the change in its history in one transaction, so they
+// never interleave with a pipeline stage working on the same haiku.
+
+// Retry puts a failed haiku back into the pipeline. A failed delivery is
+// queued again; otherwise the haiku resumes after the last stage it completed.
+func (s *HaikuService) Retry(ctx context.Context, haikuID string) (*entities.Haiku, error) {
+	return s.adminUpdate(ctx, haikuID, "retry", func(tx *gorm.DB, h *entities.Haiku) error {
+		if h.State != entities.HaikuStateFailed {
+			return fmt.Errorf("%w: only failed haikus can be retried, haiku %s is %s", ErrInvalidState, h.ID, h.State)
+		}
+
+		h.Attempt = 0
+		entries, err := s.outboxRepo.FindByHaikuID(ctx, tx, h.ID)
+		if err != nil {
+			return fmt.Errorf("failed to look up queued publications: %w", err)
+		}
+		for _, e := range entries {
+			if e.Status == entities.OutboxStatusFailed {
+				h.State = entities.HaikuStateComenting
+				return s.queuePublication(ctx, tx, h)
+			}
+		}
+
+		switch {
+		case h.Text.Valid:
+			h.State = entities.HaikuStateHaikuTextGot
+		case h.Summary.Valid:
+			h.State = entities.HaikuStateSummaryGot
+		default:
+			h.State = entities.HaikuStateCreated
+		}
+		return nil
+	})
+}
+
+// EditText replaces the text of a haiku that has not been queued for
+// publishing. Haikus under review can only be edited by a named reviewer, and
+// an approved haiku goes back to pending_review to have its new text approved.
+func (s *HaikuService) EditText(ctx context.Context, haikuID, text, reviewer, reason string) (*entities.Haiku, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("%w: haiku text must not be empty", ErrInvalidInput)
+	}
+	reviewer = strings.TrimSpace(reviewer)
+
+	note := "text edited"
+	if reviewer != "" {
+		note += " by " + reviewer
+	}
+	if reason = strings.TrimSpace(reason); reason != "" {
+		note += ": " + reason
+	}
+
+	return s.adminUpdate(ctx, haikuID, note, func(tx *gorm.DB, h *entities.Haiku) error {
+		switch h.State {
+		case entities.HaikuStateHaikuTextGot, entities.HaikuStateFailed, entities.HaikuStateCancelled, entities.HaikuStateFiltered, entities.HaikuStateSkippedStale, entities.HaikuStateSkippedLanguage:
+		case entities.HaikuStatePendingReview, entities.HaikuStateApproved:
+			if reviewer == "" {
+				return fmt.Errorf("%w: a reviewer is required to edit haiku %s while it is %s", ErrInvalidInput, h.ID, h.State)
+			}
+			h.State = entities.HaikuStatePendingReview
+		default:
+			return fmt.Errorf("%w: cannot edit haiku %s while it is %s", ErrInvalidState, h.ID, h.State)
+		}
+
+		h.Text = null.StringFrom(text)
+		return nil
+	})
+}
+
+// Approve releases a haiku waiting for review, or one rejected earlier, for
+// publishing.
+func (s *HaikuService) Approve(ctx context.Context, haikuID, reviewer, reason string) (*entities.Haiku, error) {
+	reviewer, reason = strings.TrimSpace(reviewer), strings.TrimSpace(reason)
+	if reviewer == "" {
+		return nil, fmt.Errorf("%w: reviewer must not be empty", ErrInvalidInput)
+	}
+
+	note := "approved by " + reviewer
+	if reason != "" {
+		note += ": " + reason
+	}
+
+	return s.adminUpdate(ctx, haikuID, note, func(tx *gorm.DB, h *entities.Haiku) error {
+		if h.State != entities.HaikuStatePendingReview && h.State != entities.HaikuStateRejected {
+			return fmt.Errorf("%w: cannot approve haiku %s while it is %s", ErrInvalidState, h.ID, h.State)
+		}
+
+		h.State = entities.HaikuStateApproved
+		setReview(h, reviewer, reason)
+		return nil
+	})
+}
+
+// Reject keeps a haiku waiting for review, or approved but not yet queued,
+// from being published. Rejected haikus can still be approved later.
+func (s *HaikuService) Reject(ctx context.Context, haikuID, reviewer, reason string) (*entities.Haiku, error) {
+	reviewer, reason = strings.TrimSpace(reviewer), strings.TrimSpace(reason)
+	if reviewer == "" || reason == "" {
+		return nil, fmt.Errorf("%w: reviewer and reason must not be empty", ErrInvalidInput)
+	}
+
+	return s.adminUpdate(ctx, haikuID, "rejected by "+reviewer+": "+reason, func(tx *gorm.DB, h *entities.Haiku) error {
+		if h.State != entities.HaikuStatePendingReview && h.State != entities.HaikuStateApproved {
+			return fmt.Errorf("%w: cannot reject haiku %s while it is %s", ErrInvalidState, h.ID, h.State)
+		}
+
+		h.State = entities.HaikuStateRejected
+		setReview(h, reviewer, reason)
+		return nil
+	})
+}
+
+// setReview records a review decision on h. reason may be empty.
+func setReview(h *entities.Haiku, reviewer, reason string) {
+	h.ReviewedBy = null.StringFrom(reviewer)
+	h.ReviewReason = null.NewString(reason, reason != "")
+	h.ReviewedAt = null.TimeFrom(time.Now())
+}
+
+// Cancel stops a haiku from being published. A publication already being
+// delivered cannot be recalled; other queued publications are cancelled when
+// the dispatcher picks them up.
+func (s *HaikuService) Cancel(ctx context.Context, haikuID, reason string) (*entities.Haiku, error) {
+	note := "cancelled"
+	if reason = strings.TrimSpace(reason); reason != "" {
+		note += ": " + reason
+	}
+
+	return s.adminUpdate(ctx, haikuID, note, func(tx *gorm.DB, h *entities.Haiku) error {
+		switch h.State {
+		case entities.HaikuStateDone, entities.HaikuStateCancelled, entities.HaikuStateRejected, entities.HaikuStateFiltered, entities.HaikuStateSkippedStale, entities.HaikuStateSkippedLanguage:
+			return fmt.Errorf("%w: haiku %s is already %s", ErrInvalidState, h.ID, h.State)
+		}
+
+		h.State = entities.HaikuStateCancelled
+		return nil
+	})
+}
+
+// Fail gives up on a haiku that has not been queued for publishing. A stage
+// still working on it notices the change when it saves its result and stops.
+func (s *HaikuService) Fail(ctx context.Context, haikuID, reason string) (*entities.Haiku, error) {
+	if reason = strings.TrimSpace(reason); reason == "" {
+		return nil, fmt.Errorf("%w: reason must not be empty", ErrInvalidInput)
+	}
+
+	return s.adminUpdate(ctx, haikuID, "marked failed: "+reason, func(tx *gorm.DB, h *entities.Haiku) error {
+		switch h.State {
+		case entities.HaikuStateComenting, entities.HaikuStateDone, entities.HaikuStateFailed,
+			entities.HaikuStateCancelled, entities.HaikuStateRejected, entities.HaikuStateFiltered, entities.HaikuStateSkippedStale, entities.HaikuStateSkippedLanguage:
+			return fmt.Errorf("%w: cannot fail haiku %s while it is %s", ErrInvalidState, h.ID, h.State)
+		}
+
+		h.State = entities.HaikuStateFailed
+		return nil
+	})
+}
+
+// ForcePublish queues a haiku with text for publishing right away, skipping
+// the publishing schedule and any pending review. It also overrides the
+// safety filter, so check filtered haikus before publishing them, but never
+// the author lists. The dispatcher still respects the API budget.
+func (s *HaikuService) ForcePublish(ctx context.Context, haikuID string) (*entities.Haiku, error) {
+	return s.adminUpdate(ctx, haikuID, "force-published", func(tx *gorm.DB, h *entities.Haiku) error {
+		switch h.State {
+		case entities.HaikuStateHaikuTextGot, entities.HaikuStatePendingReview, entities.HaikuStateApproved,
+			entities.HaikuStateFailed, entities.HaikuStateCancelled, entities.HaikuStateFiltered, entities.HaikuStateSkippedStale, entities.HaikuStateSkippedLanguage:
+		default:
+			return fmt.Errorf("%w: cannot publish haiku %s while it is %s", ErrInvalidState, h.ID, h.State)
+		}
+		if !h.Text.Valid {
+			return fmt.Errorf("%w: haiku %s has no text", ErrInvalidState, h.ID)
+		}
+		reason, err := exclusionReason(ctx, tx, s.authorRepo, h)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			return fmt.Errorf("%w: cannot publish haiku %s: %s", ErrInvalidState, h.ID, reason)
+		}
+
+		h.State = entities.HaikuStateComenting
+		h.Attempt = 0
+		return s.queuePublication(ctx, tx, h)
+	})
+}
+
+// adminUpdate locks a haiku, lets apply change it and saves it together with
+// an event carrying note, all in one transaction.
+func (s *HaikuService) adminUpdate(ctx context.Context, haikuID, note string, apply func(tx *gorm.DB, h *entities.Haiku) error) (*entities.Haiku, error) {
+	var haiku *entities.Haiku
+	var from entities.HaikuState
+	err := s.unit.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.haikuRepo.FindByIDForUpdate(ctx, tx, haikuID); err != nil {
+			return fmt.Errorf("failed to lock haiku %s: %w", haikuID, err)
+		}
+		// Read again with the post attached; its platform is needed to queue publications.
+		h, err := s.haikuRepo.FindByID(ctx, tx, haikuID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch haiku %s: %w", haikuID, err)
+		}
+
+		from = h.State
+		if err := apply(tx, h); err != nil {
+			return err
+		}
+		if err := s.haikuRepo.Save(ctx, tx, h); err != nil {
+			return fmt.Errorf("failed to save row: %w", err)
+		}
+		haiku = h
+		return s.recordChange(ctx, tx, h, from, entities.EventActorAdmin, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if from != haiku.State {
+		recordTransition(from, haiku.State)
+	}
+	slog.InfoContext(ctx, "Haiku changed by admin", append(logging.Haiku(haiku), "from", string(from), "note", note)...)
+	return haiku, nil
+}
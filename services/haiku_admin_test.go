@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+)
+
+func TestAdminTransitions(t *testing.T) {
+	ctx := context.Background()
+	actions := map[string]func(s *HaikuService, id string) (*entities.Haiku, error){
+		"retry":   func(s *HaikuService, id string) (*entities.Haiku, error) { return s.Retry(ctx, id) },
+		"cancel":  func(s *HaikuService, id string) (*entities.Haiku, error) { return s.Cancel(ctx, id, "") },
+		"publish": func(s *HaikuService, id string) (*entities.Haiku, error) { return s.ForcePublish(ctx, id) },
+		"edit": func(s *HaikuService, id string) (*entities.Haiku, error) {
+			return s.EditText(ctx, id, "a new text", "", "")
+		},
+		"clear": func(s *HaikuService, id string) (*entities.Haiku, error) { return s.EditText(ctx, id, " ", "", "") },
+	}
+
+	tests := []struct {
+		name      string
+		state     entities.HaikuState
+		action    string
+		wantErr   error
+		wantState entities.HaikuState
+	}{
+		{"retry failed resumes after the last stage", entities.HaikuStateFailed, "retry", nil, entities.HaikuStateHaikuTextGot},
+		{"retry only failed", entities.HaikuStateDone, "retry", ErrInvalidState, entities.HaikuStateDone},
+		{"cancel waiting", entities.HaikuStateCreated, "cancel", nil, entities.HaikuStateCancelled},
+		{"cancel published", entities.HaikuStateDone, "cancel", ErrInvalidState, entities.HaikuStateDone},
+		{"cancel twice", entities.HaikuStateCancelled, "cancel", ErrInvalidState, entities.HaikuStateCancelled},
+		{"publish generated", entities.HaikuStateHaikuTextGot, "publish", nil, entities.HaikuStateComenting},
+		{"publish cancelled", entities.HaikuStateCancelled, "publish", nil, entities.HaikuStateComenting},
+		{"publish while publishing", entities.HaikuStateComenting, "publish", ErrInvalidState, entities.HaikuStateComenting},
+		{"publish published", entities.HaikuStateDone, "publish", ErrInvalidState, entities.HaikuStateDone},
+		{"edit generated", entities.HaikuStateHaikuTextGot, "edit", nil, entities.HaikuStateHaikuTextGot},
+		{"edit while publishing", entities.HaikuStateComenting, "edit", ErrInvalidState, entities.HaikuStateComenting},
+		{"edit to nothing", entities.HaikuStateHaikuTextGot, "clear", ErrInvalidInput, entities.HaikuStateHaikuTextGot},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOutboxFixture(nil, 3)
+			f.create(t, "h1", tt.state, "an old silent pond")
+
+			_, err := actions[tt.action](f.svc, "h1")
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("%s error = %v", tt.action, err)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("%s error = %v, want %v", tt.action, err, tt.wantErr)
+			}
+
+			h, _ := f.haikus.FindByID(ctx, nil, "h1")
+			if h.State != tt.wantState {
+				t.Errorf("state after %s = %s, want %s", tt.action, h.State, tt.wantState)
+			}
+			wantEvents := 0
+			if tt.wantErr == nil {
+				wantEvents = 1
+			}
+			if events, _ := f.haikus.FindEvents(ctx, nil, "h1"); len(events) != wantEvents {
+				t.Errorf("%d events recorded, want %d", len(events), wantEvents)
+			}
+		})
+	}
+
+	if _, err := newOutboxFixture(nil, 3).svc.Cancel(ctx, "missing", ""); err == nil {
+		t.Error("Cancel succeeded for a missing haiku")
+	}
+}
+
+func TestCancelEditPublish(t *testing.T) {
+	ctx := context.Background()
+	f := newOutboxFixture(nil, 3)
+	f.publish(t, "h1", "first draft")
+
+	if _, err := f.svc.Cancel(ctx, "h1", "typo"); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if _, err := f.svc.EditText(ctx, "h1", "second draft", "", ""); err != nil {
+		t.Fatalf("EditText() error = %v", err)
+	}
+	if _, err := f.svc.ForcePublish(ctx, "h1"); err != nil {
+		t.Fatalf("ForcePublish() error = %v", err)
+	}
+	f.dispatch(1)
+
+	if len(f.platform.comments) != 1 || f.platform.comments[0] != "second draft" {
+		t.Errorf("comments = %q, want only the edited text", f.platform.comments)
+	}
+}
@@ -2,135 +2,521 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/experiment"
+	"github.com/dapplux/twitter-haiku-bot/freshness"
+	"github.com/dapplux/twitter-haiku-bot/haikuform"
 	"github.com/dapplux/twitter-haiku-bot/infrastructure/ai"
 	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
-	"github.com/dapplux/twitter-haiku-bot/infrastructure/platforms"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/logging"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/metrics"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/quota"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/tracing"
+	"github.com/dapplux/twitter-haiku-bot/language"
+	"github.com/dapplux/twitter-haiku-bot/review"
+	"github.com/dapplux/twitter-haiku-bot/safety"
+	"github.com/dapplux/twitter-haiku-bot/scoring"
 	"github.com/google/uuid"
 	"github.com/guregu/null"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
+// ErrNoWork is returned by a pipeline stage when nothing is waiting in its input state.
+var ErrNoWork = errors.New("no work available")
+
+// ErrInvalidState is returned when a haiku is not in a state that allows the requested change.
+var ErrInvalidState = errors.New("invalid haiku state")
+
+// ErrInvalidInput is returned when a requested change carries invalid values.
+var ErrInvalidInput = errors.New("invalid input")
+
 type HaikuService struct {
 	haikuRepo     repositories.HaikuRepository
+	outboxRepo    repositories.OutboxRepository
 	textProcessor ai.TextProcessor
-	platform      platforms.PlatformProvider
 	unit          repositories.UnitOfWork
+	notifier      repositories.Notifier
+	// review decides which haikus skip human review; nil turns review off.
+	review *review.Policy
+	// safety screens post and haiku text; nil turns screening off.
+	safety     *safety.Filter
+	authorRepo repositories.AuthorListRepository
+	// scorer ages queued haikus; nil takes them in arrival order.
+	scorer *scoring.Scorer
+	// freshness skips haikus whose post grew too old; nil never skips.
+	freshness *freshness.Policy
+	// languages picks the language each post is haikued in; nil haikus
+	// every post in its own.
+	languages *language.Policy
+	// experiment assigns haikus to the arm they are generated with; nil
+	// generates every haiku with the processor's defaults.
+	experiment *experiment.Experiment
+	// forms pick between candidate texts by the haiku's language.
+	forms haikuform.ByLanguage
+
+	// inFlight holds the haikus a stage is currently working on, by ID.
+	inFlight sync.Map
 }
 
-func NewHaikuService(unit repositories.UnitOfWork, haikuRepo repositories.HaikuRepository, textProcessor ai.TextProcessor, platform platforms.PlatformProvider) *HaikuService {
+func NewHaikuService(unit repositories.UnitOfWork, haikuRepo repositories.HaikuRepository, outboxRepo repositories.OutboxRepository, authorRepo repositories.AuthorListRepository, textProcessor ai.TextProcessor, notifier repositories.Notifier, reviewPolicy *review.Policy, safetyFilter *safety.Filter, scorer *scoring.Scorer, freshnessPolicy *freshness.Policy, languagePolicy *language.Policy, exp *experiment.Experiment) *HaikuService {
 	return &HaikuService{
 		haikuRepo:     haikuRepo,
+		outboxRepo:    outboxRepo,
 		textProcessor: textProcessor,
-		platform:      platform,
 		unit:          unit,
+		notifier:      notifier,
+		review:        reviewPolicy,
+		safety:        safetyFilter,
+		authorRepo:    authorRepo,
+		scorer:        scorer,
+		freshness:     freshnessPolicy,
+		languages:     languagePolicy,
+		experiment:    exp,
+		forms:         languagePolicy.Forms(),
 	}
 }
 
-func (s *HaikuService) CreateHaikuFromUnprocessedPost(ctx context.Context) error {
-	post, err := s.haikuRepo.FindOldestUnprocessedPost(ctx)
-	if post == nil {
-		log.Printf("No unprocessed posts found: %v", err)
-		return nil
+// CreateHaikuFromUnprocessedPost starts a haiku for the highest-priority post
+// without one, which the haiku inherits along with the language it is
+// written in. A post too old to reply to, or in a language that is not
+// haikued, gets a haiku that is skipped right away, so it is not picked
+// again. Each haiku gets a trace of its own, stored on the row and continued by
+// every later stage; the run that created it is linked from the trace root.
+func (s *HaikuService) CreateHaikuFromUnprocessedPost(ctx context.Context) (err error) {
+	post, err := s.haikuRepo.FindNextUnprocessedPost(ctx, s.scorer.AgingPerHour())
+	if err != nil {
+		return noWorkOr(err)
 	}
 
-	if err != nil {
-		return err
+	haiku := &entities.Haiku{
+		ID:       uuid.New().String(),
+		State:    entities.HaikuStateCreated,
+		PostID:   post.ID,
+		Post:     *post,
+		Priority: post.Priority,
+	}
+	lang := language.Of(*post)
+	haiku.Language = s.languages.HaikuLanguage(lang)
+	note := ""
+	if reason := s.freshness.Check(*post); reason != "" {
+		haiku.State = entities.HaikuStateSkippedStale
+		note = "skipped: " + reason
+	} else if haiku.Language == "" {
+		haiku.State = entities.HaikuStateSkippedLanguage
+		note = fmt.Sprintf("skipped: posts in %q are not haikued", lang)
+	}
+	return s.create(ctx, haiku, note, nil)
+}
+
+// create inserts haiku and records its creation with note. before, if not
+// nil, runs first in the same transaction.
+func (s *HaikuService) create(ctx context.Context, haiku *entities.Haiku, note string, before func(tx *gorm.DB) error) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "HaikuService.CreateHaiku",
+		trace.WithNewRoot(),
+		trace.WithLinks(trace.LinkFromContext(ctx)),
+		trace.WithAttributes(tracing.Haiku(haiku)...),
+	)
+	defer func() { tracing.End(span, err) }()
+	if traceparent := tracing.Inject(ctx); traceparent != "" {
+		haiku.TraceContext = null.StringFrom(traceparent)
 	}
 
-	haiku := entities.Haiku{
-		ID:     uuid.New().String(),
-		State:  entities.HaikuStateCreated,
-		PostID: post.ID,
-		Post:   *post,
+	err = s.unit.Transaction(func(tx *gorm.DB) error {
+		if before != nil {
+			if err := before(tx); err != nil {
+				return err
+			}
+		}
+		if err := s.haikuRepo.Create(ctx, tx, haiku); err != nil {
+			return err
+		}
+		return s.recordChange(ctx, tx, haiku, "", entities.EventActorPipeline, note)
+	})
+	if err != nil {
+		return err
 	}
 
-	return s.haikuRepo.Create(ctx, nil, &haiku)
+	recordTransition("", haiku.State)
+	slog.InfoContext(ctx, "Haiku created", logging.Haiku(haiku)...)
+	return nil
 }
 
 // Step 1: Process Summary Generation
-func (s *HaikuService) ProcessSummary(ctx context.Context) error {
-	haiku, err := s.haikuRepo.FindOldestByState(ctx, nil, entities.HaikuStateCreated)
-	if haiku == nil {
-		log.Printf("No created haikus found: %v", err)
-		return nil
-	}
-
+func (s *HaikuService) ProcessSummary(ctx context.Context) (err error) {
+	haiku, err := s.haikuRepo.FindNextByState(ctx, nil, entities.HaikuStateCreated, s.scorer.AgingPerHour())
 	if err != nil {
+		return noWorkOr(err)
+	}
+	if skipped, err := s.skipIfStale(ctx, haiku); skipped || err != nil {
 		return err
 	}
 
 	haiku.State = entities.HaikuStateSummaryGetting
+	haiku.Attempt++
+	ctx, span := startStage(ctx, "HaikuService.ProcessSummary", haiku)
+	defer func() { tracing.End(span, err) }()
+	defer s.track(haiku)()
 	if err := s.SafeUpdate(ctx, haiku, entities.HaikuStateCreated); err != nil {
 		return err
 	}
 
-	summary, err := s.textProcessor.GenerateSummary(haiku.Post.Text)
+	// Posts about tragedies and the like get no reply at all.
+	reason, err := s.screen(ctx, haiku, "post", haiku.Post.Text)
+	if err != nil {
+		return s.handleStageError(ctx, haiku, entities.HaikuStateCreated, err)
+	}
+	if reason != "" {
+		return s.filter(ctx, haiku, reason)
+	}
+
+	// Posts translated first are summarized in the haiku's language.
+	text := haiku.Post.Text
+	if lang := language.Of(haiku.Post); lang != haiku.Language {
+		text, err = s.textProcessor.Translate(ctx, text, lang, haiku.Language)
+		if err != nil {
+			return s.handleStageError(ctx, haiku, entities.HaikuStateCreated, err)
+		}
+	}
+
+	summary, err := s.textProcessor.GenerateSummary(ctx, text, haiku.Language)
 	if err != nil {
-		return s.markFailedAndReturn(ctx, haiku.ID, err)
+		return s.handleStageError(ctx, haiku, entities.HaikuStateCreated, err)
 	}
 
 	haiku.Summary = null.StringFrom(summary)
 	haiku.State = entities.HaikuStateSummaryGot
+	haiku.Attempt = 0
 
 	return s.SafeUpdate(ctx, haiku, entities.HaikuStateSummaryGetting)
 }
 
 // Step 2: Process Haiku Generation
-func (s *HaikuService) ProcessHaikuText(ctx context.Context) error {
-	haiku, err := s.haikuRepo.FindOldestByState(ctx, nil, entities.HaikuStateSummaryGot)
-	if haiku == nil {
-		log.Printf("No haikus ready for generation: %v", err)
-		return nil
+func (s *HaikuService) ProcessHaikuText(ctx context.Context) (err error) {
+	haiku, err := s.haikuRepo.FindNextByState(ctx, nil, entities.HaikuStateSummaryGot, s.scorer.AgingPerHour())
+	if err != nil {
+		return noWorkOr(err)
+	}
+	if skipped, err := s.skipIfStale(ctx, haiku); skipped || err != nil {
+		return err
 	}
 
 	haiku.State = entities.HaikuStateHaikuTextGetting
+	haiku.Attempt++
+	ctx, span := startStage(ctx, "HaikuService.ProcessHaikuText", haiku)
+	defer func() { tracing.End(span, err) }()
+	defer s.track(haiku)()
 	if err := s.SafeUpdate(ctx, haiku, entities.HaikuStateSummaryGot); err != nil {
 		return err
 	}
 
-	haikuText, err := s.textProcessor.GenerateHaiku(haiku.Summary.String)
+	var opts ai.HaikuOptions
+	candidates := 1
+	haiku.Experiment, haiku.Arm = null.String{}, null.String{}
+	if arm, ok := s.experiment.Assign(haiku.ID); ok {
+		opts = arm.Options
+		candidates = arm.Candidates
+		haiku.Experiment = null.StringFrom(s.experiment.Name())
+		haiku.Arm = null.StringFrom(arm.Name)
+	}
+
+	opts.Language = haiku.Language
+	haikuText, err := s.generate(ctx, haiku.Summary.String, opts, candidates)
 	if err != nil {
-		return s.markFailedAndReturn(ctx, haiku.ID, err)
+		return s.handleStageError(ctx, haiku, entities.HaikuStateSummaryGot, err)
 	}
 
 	haiku.Text = null.StringFrom(haikuText)
+	desc := ai.Describe(s.textProcessor, opts)
+	haiku.Model = null.NewString(desc.Model, desc.Model != "")
+	haiku.Prompt = null.NewString(desc.Prompt, desc.Prompt != "")
 	haiku.State = entities.HaikuStateHaikuTextGot
-	return s.SafeUpdate(ctx, haiku, entities.HaikuStateHaikuTextGetting)
+	haiku.Attempt = 0
+
+	// Text off the 5-7-5 form goes on, noted for reviewers and the review
+	// rules to judge.
+	note := ""
+	if form, ok := s.forms[haiku.Language]; ok {
+		var formErr *haikuform.FormError
+		if err := form.Validate(haikuText); errors.As(err, &formErr) {
+			note = "off form: " + err.Error()
+		} else if err != nil {
+			note = err.Error()
+		}
+	}
+	return s.safeUpdate(ctx, haiku, entities.HaikuStateHaikuTextGetting, note, nil)
 }
 
-// Step 3: Post Haiku to Platform
-func (s *HaikuService) PostHaiku(ctx context.Context) error {
-	haiku, err := s.haikuRepo.FindOldestByState(ctx, nil, entities.HaikuStateHaikuTextGot)
-	if haiku == nil {
-		log.Printf("No haikus ready for posting: %v", err)
-		return nil
+// generate writes up to candidates haiku texts and returns the first in
+// 5-7-5 form, or the first written if none is or the form of the language
+// cannot be checked. It stops at the first error, which is returned only if
+// no text was written yet.
+func (s *HaikuService) generate(ctx context.Context, summary string, opts ai.HaikuOptions, candidates int) (string, error) {
+	form, checked := s.forms[opts.Language]
+	var first string
+	for i := 0; i < candidates; i++ {
+		text, err := s.textProcessor.GenerateHaiku(ctx, summary, opts)
+		if err != nil {
+			if i == 0 {
+				return "", err
+			}
+			slog.WarnContext(ctx, "Stopped generating candidates", "written", i, "error", err)
+			break
+		}
+		if candidates == 1 || !checked || form.Validate(text) == nil {
+			return text, nil
+		}
+		if i == 0 {
+			first = text
+		}
+	}
+	return first, nil
+}
+
+// Step 3 (review mode only): Submit Haiku for Review
+// Haikus passing every auto-approval rule are approved right away; the rest
+// wait in pending_review for a reviewer.
+func (s *HaikuService) SubmitForReview(ctx context.Context) (err error) {
+	if s.review == nil {
+		return fmt.Errorf("%w: review mode is off", ErrNoWork)
 	}
 
+	haiku, err := s.haikuRepo.FindNextByState(ctx, nil, entities.HaikuStateHaikuTextGot, s.scorer.AgingPerHour())
 	if err != nil {
+		return noWorkOr(err)
+	}
+	ctx, span := startStage(ctx, "HaikuService.SubmitForReview", haiku)
+	defer func() { tracing.End(span, err) }()
+	if skipped, err := s.skipIfStale(ctx, haiku); skipped || err != nil {
 		return err
 	}
 
-	haiku.State = entities.HaikuStateComenting
-	if err := s.SafeUpdate(ctx, haiku, entities.HaikuStateHaikuTextGot); err != nil {
+	// The haiku is not claimed here, so a failed check counts as an attempt
+	// and leaves it in haiku_text_got to be deferred or failed.
+	reason, err := s.screen(ctx, haiku, "haiku", haiku.Text.String)
+	if err != nil {
+		haiku.Attempt++
+		return s.handleStageError(ctx, haiku, entities.HaikuStateHaikuTextGot, err)
+	}
+	if reason != "" {
+		return s.filter(ctx, haiku, reason)
+	}
+
+	decision, err := s.review.Evaluate(ctx, haiku)
+	if err != nil {
+		haiku.Attempt++
+		return s.handleStageError(ctx, haiku, entities.HaikuStateHaikuTextGot, err)
+	}
+
+	haiku.State = entities.HaikuStatePendingReview
+	haiku.Attempt = 0
+	note := "awaiting review: " + decision.Reason
+	if decision.Approved {
+		haiku.State = entities.HaikuStateApproved
+		haiku.ReviewedBy = null.StringFrom(review.AutoReviewer)
+		haiku.ReviewReason = null.StringFrom(decision.Reason)
+		haiku.ReviewedAt = null.TimeFrom(time.Now())
+		note = "auto-approved: " + decision.Reason
+	}
+	return s.safeUpdate(ctx, haiku, entities.HaikuStateHaikuTextGot, note, nil)
+}
+
+// Step 4: Queue Haiku for Publishing
+// The outbox entry is written in the same transaction as the move to
+// comenting, so a haiku is never marked as publishing without a queued
+// publication. OutboxDispatcher delivers it and marks the haiku done.
+// In review mode only approved haikus are published.
+func (s *HaikuService) PostHaiku(ctx context.Context) (err error) {
+	from := entities.HaikuState(entities.HaikuStateHaikuTextGot)
+	if s.review != nil {
+		from = entities.HaikuStateApproved
+	}
+	haiku, err := s.haikuRepo.FindNextByState(ctx, nil, from, s.scorer.AgingPerHour())
+	if err != nil {
+		return noWorkOr(err)
+	}
+	ctx, span := startStage(ctx, "HaikuService.PostHaiku", haiku)
+	defer func() { tracing.End(span, err) }()
+	if skipped, err := s.skipIfStale(ctx, haiku); skipped || err != nil {
+		return err
+	}
+
+	// Screened again, as the text may have been edited since it was generated.
+	reason, err := s.screen(ctx, haiku, "haiku", haiku.Text.String)
+	if err != nil {
+		haiku.Attempt++
+		return s.handleStageError(ctx, haiku, from, err)
+	}
+	if reason != "" {
+		return s.filter(ctx, haiku, reason)
+	}
+	// The author, or whoever asked for the haiku, may have opted out or been
+	// blocked since.
+	reason, err = exclusionReason(ctx, nil, s.authorRepo, haiku)
+	if err != nil {
 		return err
 	}
+	if reason != "" {
+		return s.filter(ctx, haiku, reason)
+	}
+
+	// Delivery attempts are counted on the outbox entry.
+	haiku.State = entities.HaikuStateComenting
+	haiku.Attempt = 0
+	return s.safeUpdate(ctx, haiku, from, "", func(tx *gorm.DB) error {
+		return s.queuePublication(ctx, tx, haiku)
+	})
+}
+
+// ReviewEnabled reports whether haikus need approval before publishing.
+func (s *HaikuService) ReviewEnabled() bool {
+	return s.review != nil
+}
+
+// screen scores text, the haiku's or its post's as named by what, raising
+// haiku's safety score to the verdict's. It returns why haiku must be
+// filtered, or an empty string if it may go on. With screening off every
+// text passes unscored.
+func (s *HaikuService) screen(ctx context.Context, haiku *entities.Haiku, what, text string) (string, error) {
+	if s.safety == nil {
+		return "", nil
+	}
+
+	verdict, flagged, err := s.safety.Check(ctx, text)
+	if err != nil {
+		return "", fmt.Errorf("failed to screen %s text: %w", what, err)
+	}
+	if !haiku.SafetyScore.Valid || verdict.Score > haiku.SafetyScore.Float64 {
+		haiku.SafetyScore = null.FloatFrom(verdict.Score)
+	}
+	if !flagged {
+		return "", nil
+	}
+	return what + " " + verdict.Reason(), nil
+}
+
+// filter stops haiku for reason. Filtered haikus are never published unless
+// an admin forces it.
+func (s *HaikuService) filter(ctx context.Context, haiku *entities.Haiku, reason string) error {
+	from := haiku.State
+	haiku.State = entities.HaikuStateFiltered
+	haiku.Attempt = 0
+	haiku.FilterReason = null.StringFrom(reason)
+	return s.safeUpdate(ctx, haiku, from, "filtered: "+reason, nil)
+}
+
+// exclusionReason returns why haiku must not be published because its post's
+// author or its requester is on an author list, or an empty string if
+// neither is. haiku must have its post attached.
+func exclusionReason(ctx context.Context, tx *gorm.DB, authorRepo repositories.AuthorListRepository, haiku *entities.Haiku) (string, error) {
+	authorIDs := []string{haiku.Post.Author.ID}
+	if haiku.RequestedBy.Valid {
+		authorIDs = append(authorIDs, haiku.RequestedBy.String)
+	}
+	excluded, err := authorRepo.FindExcluded(ctx, tx, haiku.Post.Platform, authorIDs)
+	if err != nil {
+		return "", fmt.Errorf("failed to check author lists: %w", err)
+	}
+	if list, ok := excluded[haiku.Post.Author.ID]; ok {
+		return fmt.Sprintf("author @%s is on the %s", haiku.Post.Author.Username, list), nil
+	}
+	if list, ok := excluded[haiku.RequestedBy.String]; ok && haiku.RequestedBy.Valid {
+		return fmt.Sprintf("requester %s is on the %s", haiku.RequestedBy.String, list), nil
+	}
+	return "", nil
+}
+
+// skipIfStale moves haiku to skipped_stale if its post has grown too old to
+// reply to, and reports whether it did. Requested haikus are never skipped,
+// as their requester is waiting for an answer.
+func (s *HaikuService) skipIfStale(ctx context.Context, haiku *entities.Haiku) (bool, error) {
+	if haiku.MentionID.Valid {
+		return false, nil
+	}
+	reason := s.freshness.Check(haiku.Post)
+	if reason == "" {
+		return false, nil
+	}
 
-	err = s.platform.CommentOn(haiku.PostID, haiku.Text.String)
+	from := haiku.State
+	haiku.State = entities.HaikuStateSkippedStale
+	haiku.Attempt = 0
+	return true, s.safeUpdate(ctx, haiku, from, "skipped: "+reason, nil)
+}
+
+// queuePublication queues haiku's text for delivery. Only one entry may exist
+// per haiku and platform, so an entry that failed or was cancelled earlier is
+// reset and reused. A pending one takes the haiku's current text and trace but
+// keeps its attempts and any lease, so a delivery in flight is not repeated.
+func (s *HaikuService) queuePublication(ctx context.Context, tx *gorm.DB, haiku *entities.Haiku) error {
+	key := entities.PublishDedupeKey(haiku.ID, haiku.Post.Platform)
+	entries, err := s.outboxRepo.FindByHaikuID(ctx, tx, haiku.ID)
 	if err != nil {
-		return s.markFailedAndReturn(ctx, haiku.ID, err)
+		return fmt.Errorf("failed to look up queued publications: %w", err)
+	}
+
+	for _, e := range entries {
+		if e.DedupeKey != key {
+			continue
+		}
+		switch e.Status {
+		case entities.OutboxStatusPending:
+		case entities.OutboxStatusDelivered:
+			return fmt.Errorf("%w: haiku %s was already published as %s", ErrInvalidState, haiku.ID, e.Receipt.String)
+		default:
+			e.Status = entities.OutboxStatusPending
+			e.Attempts = 0
+		}
+
+		e.Message = haiku.Text.String
+		e.TraceContext = haiku.TraceContext
+		if now := time.Now(); e.NextAttemptAt.Before(now) {
+			e.NextAttemptAt = now
+		}
+		if err := s.outboxRepo.Save(ctx, tx, &e); err != nil {
+			return fmt.Errorf("failed to requeue publication: %w", err)
+		}
+		return nil
 	}
 
-	haiku.State = entities.HaikuStateDone
-	return s.SafeUpdate(ctx, haiku, entities.HaikuStateComenting)
+	// Requested haikus answer in the thread of the request.
+	target := haiku.PostID
+	if haiku.MentionID.Valid {
+		target = haiku.MentionID.String
+	}
+	entry := entities.OutboxEntry{
+		ID:        uuid.New().String(),
+		DedupeKey: key,
+		HaikuID:   haiku.ID,
+		Platform:  haiku.Post.Platform,
+		TargetID:  target,
+		Message:   haiku.Text.String,
+		Status:    entities.OutboxStatusPending,
+		// Delivery continues the haiku's trace.
+		TraceContext: haiku.TraceContext,
+	}
+	if err := s.outboxRepo.Create(ctx, tx, &entry); err != nil {
+		return fmt.Errorf("failed to queue publication: %w", err)
+	}
+	return nil
 }
 
 // SafeUpdateState uses the transaction manager to safely update a Haiku's state.
 func (s *HaikuService) SafeUpdate(ctx context.Context, haiku *entities.Haiku, requiredState entities.HaikuState) error {
-	return s.unit.Transaction(func(tx *gorm.DB) error {
+	return s.safeUpdate(ctx, haiku, requiredState, "", nil)
+}
+
+// safeUpdate is SafeUpdate with note, if not empty, added to the recorded
+// event and also, if not nil, run in the same transaction.
+func (s *HaikuService) safeUpdate(ctx context.Context, haiku *entities.Haiku, requiredState entities.HaikuState, note string, also func(tx *gorm.DB) error) error {
+	err := s.unit.Transaction(func(tx *gorm.DB) error {
 		h, err := s.haikuRepo.FindByIDForUpdate(ctx, tx, haiku.ID)
 		if err != nil {
 			return fmt.Errorf("failed to fetch row for update: %w", err)
@@ -143,30 +529,180 @@ func (s *HaikuService) SafeUpdate(ctx context.Context, haiku *entities.Haiku, re
 		if err := s.haikuRepo.Save(ctx, tx, haiku); err != nil {
 			return fmt.Errorf("failed to save row: %w", err)
 		}
+		if also != nil {
+			if err := also(tx); err != nil {
+				return err
+			}
+		}
+		return s.recordChange(ctx, tx, haiku, requiredState, entities.EventActorPipeline, note)
+	})
+	if err != nil {
+		return err
+	}
+
+	if requiredState != haiku.State {
+		recordTransition(requiredState, haiku.State)
+	}
+	slog.InfoContext(ctx, "Haiku state changed", append(logging.Haiku(haiku), "from", string(requiredState))...)
+	return nil
+}
+
+// recordChange adds the change of haiku from state from to its event history
+// and, if the state changed, announces the new state. Sent inside tx,
+// listeners only hear about it once the change is committed and visible.
+func (s *HaikuService) recordChange(ctx context.Context, tx *gorm.DB, haiku *entities.Haiku, from entities.HaikuState, actor, note string) error {
+	if err := addEvent(ctx, tx, s.haikuRepo, haiku.ID, from, haiku.State, actor, note); err != nil {
+		return err
+	}
+	if from == haiku.State {
 		return nil
+	}
+	if err := s.notifier.Notify(ctx, tx, StateChannel(haiku.State), haiku.ID); err != nil {
+		return fmt.Errorf("failed to notify state change: %w", err)
+	}
+	return nil
+}
+
+// addEvent appends an event to a haiku's history. note may be empty.
+func addEvent(ctx context.Context, tx *gorm.DB, haikuRepo repositories.HaikuRepository, haikuID string, from, to entities.HaikuState, actor, note string) error {
+	event := entities.HaikuEvent{
+		HaikuID:   haikuID,
+		FromState: from,
+		ToState:   to,
+		Actor:     actor,
+	}
+	if note != "" {
+		event.Note = null.StringFrom(note)
+	}
+	if err := haikuRepo.AddEvent(ctx, tx, &event); err != nil {
+		return fmt.Errorf("failed to record haiku event: %w", err)
+	}
+	return nil
+}
+
+// recordTransition counts a committed state change. from is empty for new haikus.
+func recordTransition(from, to entities.HaikuState) {
+	metrics.HaikuTransitions.WithLabelValues(string(from), string(to)).Inc()
+}
+
+// startStage starts the span of a stage working on haiku, in the haiku's own
+// trace and linked to the run that picked it up.
+func startStage(ctx context.Context, name string, haiku *entities.Haiku) (context.Context, trace.Span) {
+	return tracing.StartContinued(ctx, haiku.TraceContext.String, name, trace.WithAttributes(tracing.Haiku(haiku)...))
+}
+
+// track marks haiku as being worked on and returns a func that unmarks it.
+func (s *HaikuService) track(haiku *entities.Haiku) func() {
+	s.inFlight.Store(haiku.ID, *haiku)
+	return func() { s.inFlight.Delete(haiku.ID) }
+}
+
+// InFlight returns a snapshot of the haikus currently held by a stage, with
+// the state they were claimed into. Used to report work cut short by shutdown.
+func (s *HaikuService) InFlight() []entities.Haiku {
+	var haikus []entities.Haiku
+	s.inFlight.Range(func(_, value any) bool {
+		haikus = append(haikus, value.(entities.Haiku))
+		return true
 	})
+	sort.Slice(haikus, func(i, j int) bool { return haikus[i].ID < haikus[j].ID })
+	return haikus
+}
+
+// claimedStates pairs each state a stage claims haikus into with the state
+// it claimed them from.
+var claimedStates = []struct{ claimed, from entities.HaikuState }{
+	{entities.HaikuStateSummaryGetting, entities.HaikuStateCreated},
+	{entities.HaikuStateHaikuTextGetting, entities.HaikuStateSummaryGot},
+}
+
+// ReleaseStale returns the haikus claimed by a stage more than olderThan ago
+// to the state they were claimed from, so work cut short by a crash or an
+// interrupted shutdown is retried. It returns the number of haikus released.
+func (s *HaikuService) ReleaseStale(ctx context.Context, olderThan time.Duration) (int, error) {
+	before := time.Now().Add(-olderThan)
+	released := 0
+	for _, stage := range claimedStates {
+		stale, err := s.haikuRepo.FindStale(ctx, nil, stage.claimed, before)
+		if err != nil {
+			return released, err
+		}
+		for i := range stale {
+			haiku := &stale[i]
+			haiku.State = stage.from
+			if err := s.safeUpdate(ctx, haiku, stage.claimed, "released after interrupted stage", nil); err != nil {
+				return released, fmt.Errorf("failed to release haiku %s: %w", haiku.ID, err)
+			}
+			released++
+		}
+	}
+	return released, nil
+}
+
+// noWorkOr maps a not-found lookup to ErrNoWork and passes other errors through.
+func noWorkOr(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %v", ErrNoWork, err)
+	}
+	return err
+}
+
+// handleStageError decides what happens to a haiku whose stage call failed.
+// A refused quota budget is not the haiku's fault, so it is put back into
+// previousState to be retried in a later run; any other error fails it.
+// Stages that do not claim the haiku pass its current state as previousState.
+func (s *HaikuService) handleStageError(ctx context.Context, haiku *entities.Haiku, previousState entities.HaikuState, originalErr error) error {
+	if !errors.Is(originalErr, quota.ErrBudgetExceeded) {
+		return s.markFailedAndReturn(ctx, haiku, originalErr)
+	}
+
+	slog.InfoContext(ctx, "Deferring haiku until budget is available", append(logging.Haiku(haiku), "error", originalErr)...)
+	claimedState := haiku.State
+	haiku.State = previousState
+	if err := s.SafeUpdate(ctx, haiku, claimedState); err != nil {
+		return fmt.Errorf("original error: %v; also failed to defer haiku: %w", originalErr, err)
+	}
+	return originalErr
 }
 
-func (s *HaikuService) markFailedAndReturn(ctx context.Context, haikuID string, originalErr error) error {
-	if markErr := s.MarkAsFailed(ctx, haikuID); markErr != nil {
+func (s *HaikuService) markFailedAndReturn(ctx context.Context, haiku *entities.Haiku, originalErr error) error {
+	slog.WarnContext(ctx, "Haiku stage failed", append(logging.Haiku(haiku), "error", originalErr)...)
+	if markErr := s.MarkAsFailed(ctx, haiku.ID, originalErr.Error()); markErr != nil {
 		return fmt.Errorf("original error: %v; also failed to mark as failed: %w", originalErr, markErr)
 	}
+
+	from := haiku.State
+	haiku.State = entities.HaikuStateFailed
+	slog.InfoContext(ctx, "Haiku state changed", append(logging.Haiku(haiku), "from", string(from))...)
 	return originalErr
 }
 
-// Example usage: reading with no lock, then updating in transaction
-func (s *HaikuService) MarkAsFailed(ctx context.Context, haikuID string) error {
-	return s.unit.Transaction(func(tx *gorm.DB) error {
+// MarkAsFailed fails a haiku, recording reason in its history. Haikus that
+// were cancelled, rejected, filtered or published meanwhile are left alone.
+func (s *HaikuService) MarkAsFailed(ctx context.Context, haikuID, reason string) error {
+	var from entities.HaikuState
+	err := s.unit.Transaction(func(tx *gorm.DB) error {
 		// Get the row with a FOR UPDATE lock.
 		h, err := s.haikuRepo.FindByIDForUpdate(ctx, tx, haikuID)
 		if err != nil {
 			return fmt.Errorf("failed to fetch row for update: %w", err)
 		}
+		switch h.State {
+		case entities.HaikuStateCancelled, entities.HaikuStateDone, entities.HaikuStateRejected, entities.HaikuStateFiltered, entities.HaikuStateSkippedStale, entities.HaikuStateSkippedLanguage:
+			return fmt.Errorf("%w: haiku %s is %s", ErrInvalidState, h.ID, h.State)
+		}
 
+		from = h.State
 		h.State = entities.HaikuStateFailed
 		if err := s.haikuRepo.Save(ctx, tx, h); err != nil {
 			return fmt.Errorf("failed to save row: %w", err)
 		}
-		return nil
+		return s.recordChange(ctx, tx, h, from, entities.EventActorPipeline, reason)
 	})
+	if err != nil {
+		return err
+	}
+
+	recordTransition(from, entities.HaikuStateFailed)
+	return nil
 }
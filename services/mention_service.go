@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/metrics"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/platforms"
+	"github.com/dapplux/twitter-haiku-bot/language"
+	"github.com/google/uuid"
+	"github.com/guregu/null"
+	"gorm.io/gorm"
+)
+
+// requestCursor remembers the newest mention checked for haiku requests.
+const requestCursor = "mentions.requests"
+
+// Outcomes of a mention, as counted by metrics.Mentions.
+const (
+	mentionRequested   = "requested"
+	mentionIgnored     = "ignored"
+	mentionDuplicate   = "duplicate"
+	mentionRateLimited = "rate_limited"
+	mentionExcluded    = "excluded"
+	mentionNotFound    = "not_found"
+	mentionUnsupported = "unsupported_language"
+)
+
+// MentionPolicy says which mentions ask for a haiku and how often one author
+// may ask.
+type MentionPolicy struct {
+	// Trigger must match the text of a mention asking for a haiku.
+	Trigger *regexp.Regexp
+	// PerUserLimit is how many haikus an author may request per PerUserWindow.
+	PerUserLimit  int
+	PerUserWindow time.Duration
+	// Priority is added to the score of requested posts.
+	Priority float64
+}
+
+// MentionService turns mentions of the bot into haikus: a user replies to a
+// post mentioning the bot, and the bot haikus that post and answers in the
+// mention's thread.
+type MentionService struct {
+	haikuService *HaikuService
+	haikuRepo    repositories.HaikuRepository
+	postRepo     repositories.PostRepository
+	authorRepo   repositories.AuthorListRepository
+	outboxRepo   repositories.OutboxRepository
+	cursorRepo   repositories.CursorRepository
+	platform     platforms.PlatformProvider
+	policy       MentionPolicy
+	now          func() time.Time
+}
+
+// NewMentionService creates a MentionService. now may be nil to use
+// wall-clock time.
+func NewMentionService(haikuSvc *HaikuService, haikuRepo repositories.HaikuRepository, postRepo repositories.PostRepository, authorRepo repositories.AuthorListRepository, outboxRepo repositories.OutboxRepository, cursorRepo repositories.CursorRepository, platform platforms.PlatformProvider, policy MentionPolicy, now func() time.Time) *MentionService {
+	if now == nil {
+		now = time.Now
+	}
+
+	return &MentionService{
+		haikuService: haikuSvc,
+		haikuRepo:    haikuRepo,
+		postRepo:     postRepo,
+		authorRepo:   authorRepo,
+		outboxRepo:   outboxRepo,
+		cursorRepo:   cursorRepo,
+		platform:     platform,
+		policy:       policy,
+		now:          now,
+	}
+}
+
+// ProcessMentions reads up to limit new mentions and creates a haiku for
+// every post a mention asks for. The cursor is advanced past each mention
+// once it is handled; a mention that fails is retried on the next run.
+func (s *MentionService) ProcessMentions(ctx context.Context, limit int) error {
+	since, err := s.cursorRepo.Get(ctx, nil, requestCursor)
+	if err != nil {
+		return fmt.Errorf("failed to read mention cursor: %w", err)
+	}
+	mentions, err := s.platform.FetchMentions(ctx, since, limit)
+	if err != nil {
+		return err
+	}
+
+	handled := since
+	for _, m := range mentions {
+		outcome, err := s.handle(ctx, m)
+		if err != nil {
+			err = fmt.Errorf("failed to handle mention %s: %w", m.ID, err)
+			if handled != since {
+				if cerr := s.cursorRepo.Set(ctx, nil, requestCursor, handled); cerr != nil {
+					err = errors.Join(err, fmt.Errorf("failed to save mention cursor: %w", cerr))
+				}
+			}
+			return err
+		}
+
+		metrics.Mentions.WithLabelValues(string(m.Platform), outcome).Inc()
+		handled = m.ID
+	}
+
+	if handled == since {
+		return nil
+	}
+	return s.cursorRepo.Set(ctx, nil, requestCursor, handled)
+}
+
+// handle creates a haiku if m asks for one and is allowed to, and returns
+// the outcome.
+func (s *MentionService) handle(ctx context.Context, m entities.Mention) (string, error) {
+	if m.InReplyToID == "" || !s.policy.Trigger.MatchString(m.Text) {
+		return mentionIgnored, nil
+	}
+	// Replies to the bot's own haikus are feedback, not requests.
+	if _, err := s.outboxRepo.FindByReceipt(ctx, nil, m.Platform, m.InReplyToID); err == nil {
+		return mentionIgnored, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", fmt.Errorf("failed to look up replied-to haiku: %w", err)
+	}
+
+	if _, err := s.haikuRepo.FindByMentionID(ctx, nil, m.ID); err == nil {
+		return mentionDuplicate, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+
+	requested, err := s.haikuRepo.CountRequestedSince(ctx, nil, m.Author.ID, s.now().Add(-s.policy.PerUserWindow))
+	if err != nil {
+		return "", err
+	}
+	if requested >= int64(s.policy.PerUserLimit) {
+		slog.InfoContext(ctx, "Haiku request over the per-user limit", "mention_id", m.ID, "author_id", m.Author.ID, "requested", requested)
+		return mentionRateLimited, nil
+	}
+
+	post, stored, err := s.findPost(ctx, m.InReplyToID)
+	if errors.Is(err, platforms.ErrPostNotFound) {
+		return mentionNotFound, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if stored {
+		if _, err := s.haikuRepo.FindByPostID(ctx, nil, post.ID); err == nil {
+			slog.InfoContext(ctx, "Requested post already has a haiku", "mention_id", m.ID, "post_id", post.ID)
+			return mentionDuplicate, nil
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", err
+		}
+	} else {
+		posts := []entities.Post{*post}
+		if err := prioritize(ctx, s.haikuRepo, s.haikuService.scorer, posts, s.now()); err != nil {
+			return "", fmt.Errorf("failed to score post: %w", err)
+		}
+		post = &posts[0]
+	}
+
+	excluded, err := s.authorRepo.FindExcluded(ctx, nil, m.Platform, []string{m.Author.ID, post.Author.ID})
+	if err != nil {
+		return "", fmt.Errorf("failed to check author lists: %w", err)
+	}
+	if len(excluded) > 0 {
+		slog.InfoContext(ctx, "Haiku request involves an excluded author", "mention_id", m.ID, "author_id", m.Author.ID, "post_author_id", post.Author.ID)
+		return mentionExcluded, nil
+	}
+	haikuLanguage := s.haikuService.languages.HaikuLanguage(post.Language)
+	if haikuLanguage == "" {
+		slog.InfoContext(ctx, "Requested post is in a language that is not haikued", "mention_id", m.ID, "post_id", post.ID, "language", post.Language)
+		return mentionUnsupported, nil
+	}
+
+	haiku := &entities.Haiku{
+		ID:          uuid.New().String(),
+		State:       entities.HaikuStateCreated,
+		PostID:      post.ID,
+		Post:        *post,
+		Priority:    post.Priority + s.policy.Priority,
+		MentionID:   null.StringFrom(m.ID),
+		RequestedBy: null.StringFrom(m.Author.ID),
+		Language:    haikuLanguage,
+	}
+	note := fmt.Sprintf("requested by @%s in %s", m.Author.Username, m.ID)
+	err = s.haikuService.create(ctx, haiku, note, func(tx *gorm.DB) error {
+		if stored {
+			return nil
+		}
+		return s.postRepo.Create(ctx, tx, post)
+	})
+	if err != nil {
+		return "", err
+	}
+	return mentionRequested, nil
+}
+
+// findPost returns the post with the given ID, from the database if it was
+// fetched before and from the platform otherwise, and whether it is stored.
+// The language of every post returned is known if it can be detected.
+func (s *MentionService) findPost(ctx context.Context, postID string) (*entities.Post, bool, error) {
+	post, err := s.postRepo.FindByID(ctx, nil, postID)
+	if err == nil {
+		post.Language = language.Of(*post)
+		return post, true, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	post, err = s.platform.FetchPost(ctx, postID)
+	if err != nil {
+		return nil, false, err
+	}
+	post.Language = language.Of(*post)
+	return post, false, nil
+}
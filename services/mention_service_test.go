@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/memory"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/platforms"
+)
+
+// mentionPlatform serves mentions in ID order and the posts they reply to.
+type mentionPlatform struct {
+	platforms.PlatformProvider
+	mentions []entities.Mention
+}
+
+func (p *mentionPlatform) FetchMentions(ctx context.Context, sinceID string, limit int) ([]entities.Mention, error) {
+	var found []entities.Mention
+	for _, m := range p.mentions {
+		if m.ID > sinceID && len(found) < limit {
+			found = append(found, m)
+		}
+	}
+	return found, nil
+}
+
+func (p *mentionPlatform) FetchPost(ctx context.Context, postID string) (*entities.Post, error) {
+	if postID == "deleted" {
+		return nil, platforms.ErrPostNotFound
+	}
+	return &entities.Post{ID: postID, Platform: entities.PlatformTwitter, Text: "A new compiler release", Author: entities.Author{ID: "author-" + postID}}, nil
+}
+
+// request is a mention of fan asking for a haiku of post.
+func request(id, fan, post string) entities.Mention {
+	return entities.Mention{ID: id, Author: entities.Author{ID: fan, Username: fan}, Text: "@bot haiku please", InReplyToID: post, Platform: entities.PlatformTwitter}
+}
+
+func TestProcessMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		// runs are processed one after another, an hour apart.
+		runs       [][]entities.Mention
+		limit      int
+		wantHaikus []string
+	}{
+		{"request", [][]entities.Mention{{request("m1", "fan", "p1")}}, 3, []string{"m1"}},
+		{"no trigger", [][]entities.Mention{{{ID: "m1", Text: "@bot nice", InReplyToID: "p1", Platform: entities.PlatformTwitter}}}, 3, nil},
+		{"not a reply", [][]entities.Mention{{request("m1", "fan", "")}}, 3, nil},
+		{"mention seen again", [][]entities.Mention{{request("m1", "fan", "p1")}, {request("m1", "fan", "p1")}}, 3, []string{"m1"}},
+		{"post requested twice", [][]entities.Mention{{request("m1", "fan", "p1"), request("m2", "other", "p1")}}, 3, []string{"m1"}},
+		{"post gone", [][]entities.Mention{{request("m1", "fan", "deleted"), request("m2", "fan", "p2")}}, 3, []string{"m2"}},
+		{"per-user limit", [][]entities.Mention{{request("m1", "fan", "p1"), request("m2", "fan", "p2"), request("m3", "fan", "p3")}}, 2, []string{"m1", "m2"}},
+		{"limit is per user", [][]entities.Mention{{request("m1", "fan", "p1"), request("m2", "other", "p2")}}, 1, []string{"m1", "m2"}},
+		{"limit window passes", [][]entities.Mention{{request("m1", "fan", "p1"), request("m2", "fan", "p2")}, {request("m3", "fan", "p3")}}, 1, []string{"m1", "m3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+			clock := func() time.Time { return now }
+			store := memory.NewStore(clock)
+			haikuRepo := memory.NewHaikuRepository(store)
+			postRepo := memory.NewPostRepository(store)
+			outboxRepo := memory.NewOutboxRepository(store)
+			authorRepo := memory.NewAuthorListRepository(store)
+			cursorRepo := memory.NewCursorRepository(store)
+			haikuSvc := &HaikuService{unit: memory.NewUnitOfWork(store), haikuRepo: haikuRepo, outboxRepo: outboxRepo, authorRepo: authorRepo, notifier: memory.NewNotifier(store)}
+			platform := &mentionPlatform{}
+			policy := MentionPolicy{Trigger: regexp.MustCompile(`(?i)\bhaiku\b`), PerUserLimit: tt.limit, PerUserWindow: 30 * time.Minute}
+			svc := NewMentionService(haikuSvc, haikuRepo, postRepo, authorRepo, outboxRepo, cursorRepo, platform, policy, clock)
+
+			for _, run := range tt.runs {
+				platform.mentions = run
+				// Every run rereads all of its mentions, as after a lost cursor.
+				if err := cursorRepo.Set(ctx, nil, requestCursor, ""); err != nil {
+					t.Fatal(err)
+				}
+				if err := svc.ProcessMentions(ctx, 10); err != nil {
+					t.Fatalf("ProcessMentions() error = %v", err)
+				}
+				now = now.Add(time.Hour)
+			}
+
+			haikus, _, err := haikuRepo.List(ctx, nil, repositories.HaikuFilter{}, repositories.Page{Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, h := range haikus {
+				got = append(got, h.MentionID.String)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.wantHaikus) {
+				t.Errorf("haikus requested by %v, want %v", got, tt.wantHaikus)
+			}
+		})
+	}
+}
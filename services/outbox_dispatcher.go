@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/logging"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/metrics"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/platforms"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/quota"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/tracing"
+	"github.com/guregu/null"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// deliveryLease is how long a claimed entry is hidden from other dispatchers.
+// If the process dies mid-delivery the entry becomes due again afterwards.
+const deliveryLease = 5 * time.Minute
+
+// maxRetryBackoff caps the doubling delay between failed delivery attempts.
+const maxRetryBackoff = 6 * time.Hour
+
+// OutboxDispatcher delivers queued publications to the platform.
+//
+// Delivery is at-least-once: an entry is claimed and leased in one
+// transaction, delivered, and its outcome recorded in another. A crash
+// between delivery and recording leads to a redelivery once the lease expires.
+// Every attempt is logged with its receipt or error.
+type OutboxDispatcher struct {
+	unit         repositories.UnitOfWork
+	outboxRepo   repositories.OutboxRepository
+	haikuRepo    repositories.HaikuRepository
+	authorRepo   repositories.AuthorListRepository
+	platform     platforms.PlatformProvider
+	notifier     repositories.Notifier
+	maxAttempts  int
+	retryBackoff time.Duration
+	now          func() time.Time
+}
+
+// NewOutboxDispatcher creates a dispatcher that gives up after maxAttempts
+// failed deliveries, waiting retryBackoff after the first failure and twice
+// as long after each further one, up to maxRetryBackoff. now may be nil to
+// use wall-clock time.
+func NewOutboxDispatcher(unit repositories.UnitOfWork, outboxRepo repositories.OutboxRepository, haikuRepo repositories.HaikuRepository, authorRepo repositories.AuthorListRepository, platform platforms.PlatformProvider, notifier repositories.Notifier, maxAttempts int, retryBackoff time.Duration, now func() time.Time) *OutboxDispatcher {
+	if now == nil {
+		now = time.Now
+	}
+
+	return &OutboxDispatcher{
+		unit:         unit,
+		outboxRepo:   outboxRepo,
+		haikuRepo:    haikuRepo,
+		authorRepo:   authorRepo,
+		platform:     platform,
+		notifier:     notifier,
+		maxAttempts:  maxAttempts,
+		retryBackoff: retryBackoff,
+		now:          now,
+	}
+}
+
+// DispatchNext delivers the oldest due entry. It returns ErrNoWork when nothing is due.
+// Entries whose haiku was cancelled, or whose post's author or requester has
+// opted out or been blocked since it was queued, are cancelled instead of
+// delivered.
+func (d *OutboxDispatcher) DispatchNext(ctx context.Context) error {
+	var entry *entities.OutboxEntry
+	var filtered entities.HaikuState
+	err := d.unit.Transaction(func(tx *gorm.DB) error {
+		e, err := d.outboxRepo.FindDueForUpdate(ctx, tx, d.now())
+		if err != nil {
+			return err
+		}
+
+		h, err := d.haikuRepo.FindByID(ctx, tx, e.HaikuID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch haiku of outbox entry: %w", err)
+		}
+		logCtx := logging.With(ctx,
+			"outbox_id", e.ID,
+			"haiku_id", e.HaikuID,
+			"post_id", e.TargetID,
+			"platform", string(e.Platform),
+			"state", string(h.State),
+			"attempt", e.Attempts,
+		)
+		if h.State == entities.HaikuStateCancelled {
+			slog.InfoContext(logCtx, "Cancelling publication of cancelled haiku")
+			e.Status = entities.OutboxStatusCancelled
+			return d.outboxRepo.Save(ctx, tx, e)
+		}
+		reason, err := exclusionReason(ctx, tx, d.authorRepo, h)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			slog.InfoContext(logCtx, "Cancelling publication to excluded author", "reason", reason)
+			e.Status = entities.OutboxStatusCancelled
+			if err := d.outboxRepo.Save(ctx, tx, e); err != nil {
+				return fmt.Errorf("failed to cancel outbox entry: %w", err)
+			}
+			filtered, err = d.finishHaiku(ctx, tx, e, entities.HaikuStateFiltered, reason)
+			return err
+		}
+
+		e.Attempts++
+		e.NextAttemptAt = d.now().Add(deliveryLease)
+		if err := d.outboxRepo.Save(ctx, tx, e); err != nil {
+			return fmt.Errorf("failed to claim outbox entry: %w", err)
+		}
+		entry = e
+		return nil
+	})
+	if err != nil {
+		return noWorkOr(err)
+	}
+	if filtered != "" {
+		recordTransition(entities.HaikuStateComenting, filtered)
+	}
+	if entry == nil {
+		return nil
+	}
+	return d.deliver(ctx, entry)
+}
+
+// deliver publishes a claimed entry and records the outcome, in a span
+// continuing the trace of the entry's haiku.
+func (d *OutboxDispatcher) deliver(ctx context.Context, entry *entities.OutboxEntry) (err error) {
+	ctx, span := tracing.StartContinued(ctx, entry.TraceContext.String, "OutboxDispatcher.Deliver", trace.WithAttributes(
+		attribute.String("outbox.id", entry.ID),
+		attribute.Int("outbox.attempt", entry.Attempts),
+		attribute.String("haiku.id", entry.HaikuID),
+		attribute.String("post.id", entry.TargetID),
+		attribute.String("post.platform", string(entry.Platform)),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	ctx = logging.With(ctx,
+		"outbox_id", entry.ID,
+		"haiku_id", entry.HaikuID,
+		"post_id", entry.TargetID,
+		"platform", string(entry.Platform),
+		"attempt", entry.Attempts,
+	)
+
+	receipt, deliverErr := d.platform.CommentOn(ctx, entry.TargetID, entry.Message)
+	var finished entities.HaikuState
+	if err := d.unit.Transaction(func(tx *gorm.DB) error {
+		var err error
+		finished, err = d.recordAttempt(ctx, tx, entry, receipt, deliverErr)
+		return err
+	}); err != nil {
+		if deliverErr != nil {
+			return fmt.Errorf("delivery error: %v; also failed to record attempt: %w", deliverErr, err)
+		}
+		return fmt.Errorf("delivered outbox entry %s as %s but failed to record it: %w", entry.ID, receipt, err)
+	}
+
+	metrics.OutboxDeliveries.WithLabelValues(string(entry.Platform), deliveryOutcome(deliverErr)).Inc()
+	state := entities.HaikuState(entities.HaikuStateComenting)
+	if finished != "" {
+		recordTransition(entities.HaikuStateComenting, finished)
+		state = finished
+	}
+
+	if deliverErr != nil {
+		slog.WarnContext(ctx, "Outbox delivery failed",
+			"state", string(state), "outbox_status", string(entry.Status), "next_attempt_at", entry.NextAttemptAt, "error", deliverErr)
+		return fmt.Errorf("failed to deliver outbox entry %s (attempt %d): %w", entry.ID, entry.Attempts, deliverErr)
+	}
+	slog.InfoContext(ctx, "Outbox entry delivered", "state", string(state), "receipt", receipt)
+	return nil
+}
+
+// deliveryOutcome labels a delivery attempt for metrics.
+func deliveryOutcome(deliverErr error) string {
+	switch {
+	case deliverErr == nil:
+		return "delivered"
+	case errors.Is(deliverErr, quota.ErrBudgetExceeded):
+		return "deferred"
+	default:
+		return "error"
+	}
+}
+
+// recordAttempt stores the outcome of delivering entry and moves its haiku on.
+// It returns the state the haiku was moved into, if any.
+func (d *OutboxDispatcher) recordAttempt(ctx context.Context, tx *gorm.DB, entry *entities.OutboxEntry, receipt string, deliverErr error) (entities.HaikuState, error) {
+	now := d.now()
+
+	// A refused budget never reached the platform: release the entry without
+	// spending an attempt and try again after the backoff.
+	if errors.Is(deliverErr, quota.ErrBudgetExceeded) {
+		entry.Attempts--
+		entry.NextAttemptAt = now.Add(d.retryBackoff)
+		return "", d.outboxRepo.Save(ctx, tx, entry)
+	}
+
+	delivery := entities.OutboxDelivery{
+		OutboxID:    entry.ID,
+		Attempt:     entry.Attempts,
+		Succeeded:   deliverErr == nil,
+		AttemptedAt: now,
+	}
+
+	var finalState entities.HaikuState
+	switch {
+	case deliverErr == nil:
+		delivery.Receipt = null.StringFrom(receipt)
+		entry.Status = entities.OutboxStatusDelivered
+		entry.Receipt = null.StringFrom(receipt)
+		entry.DeliveredAt = null.TimeFrom(now)
+		entry.LastError = null.String{}
+		finalState = entities.HaikuStateDone
+	case entry.Attempts >= d.maxAttempts:
+		delivery.Error = null.StringFrom(deliverErr.Error())
+		entry.Status = entities.OutboxStatusFailed
+		entry.LastError = null.StringFrom(deliverErr.Error())
+		finalState = entities.HaikuStateFailed
+	default:
+		delivery.Error = null.StringFrom(deliverErr.Error())
+		entry.LastError = null.StringFrom(deliverErr.Error())
+		entry.NextAttemptAt = now.Add(d.backoff(entry.Attempts))
+	}
+
+	if err := d.outboxRepo.AddDelivery(ctx, tx, &delivery); err != nil {
+		return "", fmt.Errorf("failed to log delivery: %w", err)
+	}
+	if err := d.outboxRepo.Save(ctx, tx, entry); err != nil {
+		return "", fmt.Errorf("failed to save outbox entry: %w", err)
+	}
+	if finalState == "" {
+		return "", nil
+	}
+	note := "published as " + receipt
+	if deliverErr != nil {
+		note = deliverErr.Error()
+	}
+	return d.finishHaiku(ctx, tx, entry, finalState, note)
+}
+
+// backoff returns the delay after the attempt-th failed delivery.
+func (d *OutboxDispatcher) backoff(attempt int) time.Duration {
+	delay := d.retryBackoff
+	for i := 1; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryBackoff)
+}
+
+// finishHaiku moves the publishing haiku of entry into state and returns it,
+// recording note in its history. A published haiku keeps the reply's ID so its
+// engagement can be tracked; a filtered one keeps note as its filter reason.
+// Haikus that already left the comenting state are left alone and an empty
+// state is returned.
+func (d *OutboxDispatcher) finishHaiku(ctx context.Context, tx *gorm.DB, entry *entities.OutboxEntry, state entities.HaikuState, note string) (entities.HaikuState, error) {
+	h, err := d.haikuRepo.FindByIDForUpdate(ctx, tx, entry.HaikuID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch row for update: %w", err)
+	}
+	if h.State != entities.HaikuStateComenting {
+		slog.WarnContext(ctx, "Haiku left publishing before delivery was recorded", "state", string(h.State), "target_state", string(state))
+		return "", nil
+	}
+
+	h.State = state
+	if state == entities.HaikuStateDone {
+		h.ReplyID = entry.Receipt
+		h.PublishedAt = entry.DeliveredAt
+	}
+	if state == entities.HaikuStateFiltered {
+		h.FilterReason = null.StringFrom(note)
+	}
+	if err := d.haikuRepo.Save(ctx, tx, h); err != nil {
+		return "", fmt.Errorf("failed to save row: %w", err)
+	}
+	if err := addEvent(ctx, tx, d.haikuRepo, h.ID, entities.HaikuStateComenting, state, entities.EventActorPipeline, note); err != nil {
+		return "", err
+	}
+	if err := d.notifier.Notify(ctx, tx, StateChannel(h.State), h.ID); err != nil {
+		return "", fmt.Errorf("failed to notify state change: %w", err)
+	}
+	return state, nil
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/memory"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/platforms"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/quota"
+	"github.com/guregu/null"
+)
+
+// scriptedPlatform fails CommentOn with each of errs in turn, then succeeds.
+// The dispatcher uses no other method.
+type scriptedPlatform struct {
+	platforms.PlatformProvider
+	errs     []error
+	comments []string
+}
+
+func (p *scriptedPlatform) CommentOn(ctx context.Context, postID, message string) (string, error) {
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		return "", err
+	}
+	p.comments = append(p.comments, message)
+	return fmt.Sprintf("reply-%d", len(p.comments)), nil
+}
+
+// outboxFixture wires a dispatcher to an in-memory store whose clock only
+// moves when a test advances it. Queued entries become due at wall-clock
+// time, so the clock starts there.
+type outboxFixture struct {
+	now        time.Time
+	haikus     repositories.HaikuRepository
+	posts      repositories.PostRepository
+	outbox     repositories.OutboxRepository
+	authors    repositories.AuthorListRepository
+	platform   *scriptedPlatform
+	svc        *HaikuService
+	dispatcher *OutboxDispatcher
+}
+
+func newOutboxFixture(errs []error, maxAttempts int) *outboxFixture {
+	f := &outboxFixture{now: time.Now(), platform: &scriptedPlatform{errs: errs}}
+	clock := func() time.Time { return f.now }
+	store := memory.NewStore(clock)
+	unit := memory.NewUnitOfWork(store)
+	notifier := memory.NewNotifier(store)
+	f.haikus = memory.NewHaikuRepository(store)
+	f.posts = memory.NewPostRepository(store)
+	f.outbox = memory.NewOutboxRepository(store)
+	f.authors = memory.NewAuthorListRepository(store)
+	f.svc = &HaikuService{unit: unit, haikuRepo: f.haikus, outboxRepo: f.outbox, authorRepo: f.authors, notifier: notifier}
+	f.dispatcher = NewOutboxDispatcher(unit, f.outbox, f.haikus, f.authors, f.platform, notifier, maxAttempts, time.Minute, clock)
+	return f
+}
+
+// create stores a haiku of text in state, replying to a post of its own.
+func (f *outboxFixture) create(t *testing.T, id string, state entities.HaikuState, text string) {
+	t.Helper()
+	ctx := context.Background()
+	post := entities.Post{ID: "post-" + id, Platform: entities.PlatformTwitter, Author: entities.Author{ID: "author-" + id, Username: "author"}}
+	if err := f.posts.Create(ctx, nil, &post); err != nil {
+		t.Fatal(err)
+	}
+	h := entities.Haiku{ID: id, PostID: post.ID, State: state, Text: null.StringFrom(text)}
+	if err := f.haikus.Create(ctx, nil, &h); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// publish stores a haiku of text in the comenting state and queues it.
+func (f *outboxFixture) publish(t *testing.T, id, text string) *entities.Haiku {
+	t.Helper()
+	f.create(t, id, entities.HaikuStateComenting, text)
+	return f.requeue(t, id, text)
+}
+
+// requeue sets the haiku's text and queues its publication again.
+func (f *outboxFixture) requeue(t *testing.T, id, text string) *entities.Haiku {
+	t.Helper()
+	ctx := context.Background()
+	h, err := f.haikus.FindByID(ctx, nil, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.State = entities.HaikuStateComenting
+	h.Text = null.StringFrom(text)
+	if err := f.haikus.Save(ctx, nil, h); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.svc.queuePublication(ctx, nil, h); err != nil {
+		t.Fatalf("queuePublication() error = %v", err)
+	}
+	return h
+}
+
+// dispatch runs the dispatcher n times, a day apart so every retry is due.
+func (f *outboxFixture) dispatch(n int) {
+	for i := 0; i < n; i++ {
+		f.now = f.now.Add(24 * time.Hour)
+		_ = f.dispatcher.DispatchNext(context.Background())
+	}
+}
+
+// entry returns the haiku's only outbox entry.
+func (f *outboxFixture) entry(t *testing.T, haikuID string) entities.OutboxEntry {
+	t.Helper()
+	entries, err := f.outbox.FindByHaikuID(context.Background(), nil, haikuID)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("FindByHaikuID() = %d entries, %v, want one", len(entries), err)
+	}
+	return entries[0]
+}
+
+func TestDispatchNext(t *testing.T) {
+	errDown := errors.New("platform down")
+
+	tests := []struct {
+		name         string
+		errs         []error
+		dispatches   int
+		wantStatus   entities.OutboxStatus
+		wantAttempts int
+		wantState    entities.HaikuState
+		wantComments int
+	}{
+		{"delivered", nil, 1, entities.OutboxStatusDelivered, 1, entities.HaikuStateDone, 1},
+		{"retried then delivered", []error{errDown}, 2, entities.OutboxStatusDelivered, 2, entities.HaikuStateDone, 1},
+		{"retried until attempts run out", []error{errDown, errDown, errDown}, 3, entities.OutboxStatusFailed, 3, entities.HaikuStateFailed, 0},
+		{"failed entries are not retried", []error{errDown, errDown, errDown}, 5, entities.OutboxStatusFailed, 3, entities.HaikuStateFailed, 0},
+		{"budget refusals spend no attempt", []error{quota.ErrBudgetExceeded}, 1, entities.OutboxStatusPending, 0, entities.HaikuStateComenting, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOutboxFixture(tt.errs, 3)
+			f.publish(t, "h1", "an old silent pond")
+			f.dispatch(tt.dispatches)
+
+			e := f.entry(t, "h1")
+			if e.Status != tt.wantStatus || e.Attempts != tt.wantAttempts {
+				t.Errorf("entry is %s after %d attempts, want %s after %d", e.Status, e.Attempts, tt.wantStatus, tt.wantAttempts)
+			}
+			h, _ := f.haikus.FindByID(context.Background(), nil, "h1")
+			if h.State != tt.wantState {
+				t.Errorf("haiku state = %s, want %s", h.State, tt.wantState)
+			}
+			if len(f.platform.comments) != tt.wantComments {
+				t.Errorf("%d comments posted, want %d", len(f.platform.comments), tt.wantComments)
+			}
+		})
+	}
+}
+
+func TestDispatchBackoff(t *testing.T) {
+	errDown := errors.New("platform down")
+	f := newOutboxFixture([]error{errDown, errDown, errDown}, 10)
+	f.publish(t, "h1", "an old silent pond")
+
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+		f.dispatch(1)
+		if got := f.entry(t, "h1").NextAttemptAt.Sub(f.now); got != want {
+			t.Errorf("next attempt in %s, want %s", got, want)
+		}
+	}
+
+	for _, tt := range []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Minute},
+		{9, 256 * time.Minute},
+		{10, maxRetryBackoff},
+		{100, maxRetryBackoff},
+	} {
+		if got := f.dispatcher.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestQueuePublication(t *testing.T) {
+	errDown := errors.New("platform down")
+
+	tests := []struct {
+		name         string
+		errs         []error
+		maxAttempts  int
+		dispatches   int
+		wantStatus   entities.OutboxStatus
+		wantAttempts int
+	}{
+		{"pending entry takes the new text", nil, 3, 0, entities.OutboxStatusPending, 0},
+		{"pending entry keeps its attempts", []error{errDown}, 3, 1, entities.OutboxStatusPending, 1},
+		{"failed entry is requeued", []error{errDown}, 1, 1, entities.OutboxStatusPending, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOutboxFixture(tt.errs, tt.maxAttempts)
+			f.publish(t, "h1", "first draft")
+			f.dispatch(tt.dispatches)
+			f.requeue(t, "h1", "second draft")
+
+			e := f.entry(t, "h1")
+			if e.Status != tt.wantStatus || e.Attempts != tt.wantAttempts {
+				t.Errorf("entry is %s after %d attempts, want %s after %d", e.Status, e.Attempts, tt.wantStatus, tt.wantAttempts)
+			}
+			if e.Message != "second draft" {
+				t.Errorf("entry message = %q, want the requeued text", e.Message)
+			}
+
+			f.dispatch(1)
+			if len(f.platform.comments) != 1 || f.platform.comments[0] != "second draft" {
+				t.Errorf("comments = %q, want the requeued text posted once", f.platform.comments)
+			}
+		})
+	}
+}
+
+func TestQueuePublicationAfterDelivery(t *testing.T) {
+	f := newOutboxFixture(nil, 3)
+	f.publish(t, "h1", "an old silent pond")
+	f.dispatch(1)
+
+	h, _ := f.haikus.FindByID(context.Background(), nil, "h1")
+	if err := f.svc.queuePublication(context.Background(), nil, h); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("queuePublication() error = %v for a published haiku, want ErrInvalidState", err)
+	}
+}
+
+func TestDispatchCancelled(t *testing.T) {
+	ctx := context.Background()
+	f := newOutboxFixture(nil, 3)
+	f.publish(t, "h1", "an old silent pond")
+	if _, err := f.svc.Cancel(ctx, "h1", ""); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	f.dispatch(1)
+
+	if e := f.entry(t, "h1"); e.Status != entities.OutboxStatusCancelled || e.Attempts != 0 {
+		t.Errorf("entry is %s after %d attempts, want cancelled without an attempt", e.Status, e.Attempts)
+	}
+	if len(f.platform.comments) != 0 {
+		t.Errorf("comments = %q, want none for a cancelled haiku", f.platform.comments)
+	}
+	if h, _ := f.haikus.FindByID(ctx, nil, "h1"); h.State != entities.HaikuStateCancelled {
+		t.Errorf("haiku state = %s, want cancelled", h.State)
+	}
+}
+
+func TestDispatchExcludedAuthors(t *testing.T) {
+	tests := []struct {
+		name        string
+		list        entities.AuthorList
+		authorID    string
+		requestedBy string
+		wantStatus  entities.OutboxStatus
+		wantState   entities.HaikuState
+		wantReason  string
+	}{
+		{"author blocked", entities.AuthorListBlocklist, "author-h1", "", entities.OutboxStatusCancelled, entities.HaikuStateFiltered, "author @author is on the blocklist"},
+		{"author opted out", entities.AuthorListOptOut, "author-h1", "", entities.OutboxStatusCancelled, entities.HaikuStateFiltered, "author @author is on the optout"},
+		{"requester opted out", entities.AuthorListOptOut, "fan", "fan", entities.OutboxStatusCancelled, entities.HaikuStateFiltered, "requester fan is on the optout"},
+		{"someone else excluded", entities.AuthorListBlocklist, "someone", "", entities.OutboxStatusDelivered, entities.HaikuStateDone, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newOutboxFixture(nil, 3)
+			f.create(t, "h1", entities.HaikuStateComenting, "an old silent pond")
+			if tt.requestedBy != "" {
+				h, _ := f.haikus.FindByID(ctx, nil, "h1")
+				h.RequestedBy = null.StringFrom(tt.requestedBy)
+				if err := f.haikus.Save(ctx, nil, h); err != nil {
+					t.Fatal(err)
+				}
+			}
+			f.requeue(t, "h1", "an old silent pond")
+			// The author is excluded after the haiku was queued.
+			entry := entities.AuthorListEntry{Platform: entities.PlatformTwitter, AuthorID: tt.authorID}
+			if _, err := f.authors.Add(ctx, nil, tt.list, &entry); err != nil {
+				t.Fatal(err)
+			}
+			f.dispatch(1)
+
+			if e := f.entry(t, "h1"); e.Status != tt.wantStatus {
+				t.Errorf("entry is %s, want %s", e.Status, tt.wantStatus)
+			}
+			h, _ := f.haikus.FindByID(ctx, nil, "h1")
+			if h.State != tt.wantState || h.FilterReason.String != tt.wantReason {
+				t.Errorf("haiku is %s (%q), want %s (%q)", h.State, h.FilterReason.String, tt.wantState, tt.wantReason)
+			}
+			if published := tt.wantReason == ""; (len(f.platform.comments) == 1) != published {
+				t.Errorf("comments = %q, want published %v", f.platform.comments, published)
+			}
+			if tt.wantReason == "" {
+				return
+			}
+
+			if _, err := f.svc.ForcePublish(ctx, "h1"); !errors.Is(err, ErrInvalidState) || !strings.Contains(err.Error(), tt.wantReason) {
+				t.Errorf("ForcePublish() error = %v, want ErrInvalidState naming %q", err, tt.wantReason)
+			}
+		})
+	}
+}
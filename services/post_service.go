@@ -3,26 +3,50 @@ package services
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
 
+	"github.com/dapplux/twitter-haiku-bot/entities"
 	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/metrics"
 	"github.com/dapplux/twitter-haiku-bot/infrastructure/platforms"
+	"github.com/dapplux/twitter-haiku-bot/language"
+	"github.com/dapplux/twitter-haiku-bot/scoring"
 )
 
 // PostService orchestrates the fetching and saving of posts.
 type PostService struct {
-	platform platforms.PlatformProvider
-	repo     repositories.PostRepository
+	platform   platforms.PlatformProvider
+	repo       repositories.PostRepository
+	haikuRepo  repositories.HaikuRepository
+	authorRepo repositories.AuthorListRepository
+	notifier   repositories.Notifier
+	// scorer sets the priority posts are haikued in; nil leaves them in
+	// the order they were fetched.
+	scorer *scoring.Scorer
+	now    func() time.Time
 }
 
-func NewPostService(repo repositories.PostRepository, platform platforms.PlatformProvider) *PostService {
+// NewPostService creates a PostService. now may be nil to use wall-clock
+// time.
+func NewPostService(repo repositories.PostRepository, haikuRepo repositories.HaikuRepository, authorRepo repositories.AuthorListRepository, platform platforms.PlatformProvider, notifier repositories.Notifier, scorer *scoring.Scorer, now func() time.Time) *PostService {
+	if now == nil {
+		now = time.Now
+	}
+
 	return &PostService{
-		platform: platform,
-		repo:     repo,
+		platform:   platform,
+		repo:       repo,
+		haikuRepo:  haikuRepo,
+		authorRepo: authorRepo,
+		notifier:   notifier,
+		scorer:     scorer,
+		now:        now,
 	}
 }
 
-// FetchAndSave fetches posts from all platforms and saves them in the database.
+// FetchAndSave fetches posts from all platforms and saves them in the
+// database, detecting the language of posts the platform did not tag.
 func (s *PostService) FetchAndSave(ctx context.Context, limit int) error {
 	posts, err := s.platform.FetchPosts(ctx, limit)
 	if err != nil {
@@ -33,11 +57,89 @@ func (s *PostService) FetchAndSave(ctx context.Context, limit int) error {
 		return fmt.Errorf("No new posts found")
 	}
 
+	posts, err = s.dropExcluded(ctx, posts)
+	if err != nil {
+		return fmt.Errorf("Error checking author lists: %v", err)
+	}
+	if len(posts) == 0 {
+		slog.InfoContext(ctx, "All fetched posts are by blocked or opted-out authors")
+		return nil
+	}
+	if err := prioritize(ctx, s.haikuRepo, s.scorer, posts, s.now()); err != nil {
+		return fmt.Errorf("Error scoring posts: %v", err)
+	}
+	for i := range posts {
+		posts[i].Language = language.Of(posts[i])
+	}
+
 	if err := s.repo.SaveBatch(ctx, nil, posts); err != nil {
 		return fmt.Errorf("Error saving posts: %v", err)
 	}
 
-	log.Printf("Successfully saved %d posts", len(posts))
+	slog.InfoContext(ctx, "Saved fetched posts", "count", len(posts))
+	for _, post := range posts {
+		metrics.PostsFetched.WithLabelValues(string(post.Platform)).Inc()
+	}
+
+	// Wake up haiku creation; polling picks the posts up anyway if this fails.
+	if err := s.notifier.Notify(ctx, nil, PostsSavedChannel, fmt.Sprintf("%d", len(posts))); err != nil {
+		slog.WarnContext(ctx, "Failed to notify", "channel", PostsSavedChannel, "error", err)
+	}
 
 	return nil
 }
+
+// prioritize sets the priority of posts with scorer. Posts of the same
+// author count against each other, as do the author's recent haikus.
+func prioritize(ctx context.Context, haikuRepo repositories.HaikuRepository, scorer *scoring.Scorer, posts []entities.Post, now time.Time) error {
+	if scorer == nil {
+		return nil
+	}
+
+	byPlatform := make(map[entities.Platform][]string)
+	for _, p := range posts {
+		byPlatform[p.Platform] = append(byPlatform[p.Platform], p.Author.ID)
+	}
+	recent := make(map[entities.Platform]map[string]int, len(byPlatform))
+	for platform, ids := range byPlatform {
+		counts, err := haikuRepo.CountByAuthorsSince(ctx, nil, platform, ids, now.Add(-scorer.DiversityWindow()))
+		if err != nil {
+			return err
+		}
+		recent[platform] = counts
+	}
+
+	for i := range posts {
+		p := &posts[i]
+		p.Priority = scorer.Score(*p, recent[p.Platform][p.Author.ID], now)
+		recent[p.Platform][p.Author.ID]++
+	}
+	return nil
+}
+
+// dropExcluded removes posts by authors on the blocklist or opt-out list.
+func (s *PostService) dropExcluded(ctx context.Context, posts []entities.Post) ([]entities.Post, error) {
+	byPlatform := make(map[entities.Platform][]string)
+	for _, p := range posts {
+		byPlatform[p.Platform] = append(byPlatform[p.Platform], p.Author.ID)
+	}
+	excluded := make(map[entities.Platform]map[string]entities.AuthorList, len(byPlatform))
+	for platform, ids := range byPlatform {
+		found, err := s.authorRepo.FindExcluded(ctx, nil, platform, ids)
+		if err != nil {
+			return nil, err
+		}
+		excluded[platform] = found
+	}
+
+	kept := posts[:0]
+	for _, p := range posts {
+		if list, ok := excluded[p.Platform][p.Author.ID]; ok {
+			slog.DebugContext(ctx, "Skipping post by excluded author", "post_id", p.ID, "author_id", p.Author.ID, "list", string(list))
+			metrics.PostsExcluded.WithLabelValues(string(p.Platform), string(list)).Inc()
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return kept, nil
+}
@@ -0,0 +1,234 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dapplux/twitter-haiku-bot/entities"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/ai"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/memory"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/repositories"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/platforms"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/quota"
+)
+
+// DaySample is a snapshot of the pipeline taken at the end of a simulated day.
+type DaySample struct {
+	Day         time.Time // start of the sampled day
+	PostsStored int
+	Published   int // comments posted during the day
+	Failed      int // haikus that entered the failed state during the day
+	QueueDepth  map[entities.HaikuState]int
+}
+
+// Quota summarizes calls made against the fake backends and the state of
+// the quota budgets at the end of the run.
+type Quota struct {
+	Platform platforms.FakeStats
+	AI       ai.FakeStats
+	Budgets  []quota.Status
+}
+
+// Report is the outcome of a simulation run.
+type Report struct {
+	Options    Options
+	Days       []DaySample
+	JobRuns    map[string]int
+	QueueDepth map[entities.HaikuState]int
+	Quota      Quota
+	// Notifications counts pipeline events per channel that a LISTEN-ing
+	// deployment would have reacted to.
+	Notifications map[string]int
+	// Outbox counts publication entries per status, and Deliveries the
+	// attempts made to deliver them.
+	Outbox     map[entities.OutboxStatus]int
+	Deliveries int
+	// Authors counts the entries on each author list at the end of the run.
+	Authors map[entities.AuthorList]int
+	// Engagement ranks the published haikus by their latest engagement,
+	// grouped each way the admin API offers; at most engagementTop haikus
+	// are listed.
+	Engagement map[repositories.EngagementGroup][]repositories.EngagementRank
+
+	lastComments int
+	lastFailed   int
+}
+
+// engagementTop is the number of best-received haikus listed in the report.
+const engagementTop = 5
+
+func newReport(opts Options) *Report {
+	return &Report{
+		Options: opts,
+		JobRuns: make(map[string]int),
+	}
+}
+
+// sampleDay records the pipeline state at end, the end of a simulated day.
+func (r *Report) sampleDay(end time.Time, store *memory.Store, platform *platforms.FakeProvider) {
+	depth := store.CountByState()
+	comments := platform.Stats().Comments
+
+	r.Days = append(r.Days, DaySample{
+		Day:         end.Add(-24 * time.Hour),
+		PostsStored: store.PostCount(),
+		Published:   comments - r.lastComments,
+		Failed:      depth[entities.HaikuStateFailed] - r.lastFailed,
+		QueueDepth:  depth,
+	})
+
+	r.lastComments = comments
+	r.lastFailed = depth[entities.HaikuStateFailed]
+}
+
+func (r *Report) finish(ctx context.Context, store *memory.Store, engagementRepo repositories.EngagementRepository, platform *platforms.FakeProvider, textProcessor *ai.FakeProcessor, tracker *quota.Tracker) error {
+	budgets, err := tracker.Statuses(ctx)
+	if err != nil {
+		return err
+	}
+	r.Engagement = make(map[repositories.EngagementGroup][]repositories.EngagementRank)
+	for _, group := range repositories.EngagementGroups {
+		// Every prompt, model and search profile is listed: no group can
+		// outnumber the posts haikued.
+		limit := max(store.PostCount(), 1)
+		if group == repositories.GroupByHaiku {
+			limit = engagementTop
+		}
+		ranks, err := engagementRepo.Rank(ctx, nil, group, r.Options.Start, limit)
+		if err != nil {
+			return err
+		}
+		r.Engagement[group] = ranks
+	}
+
+	r.QueueDepth = store.CountByState()
+	r.Notifications = store.Notifications()
+	r.Outbox = store.CountOutboxByStatus()
+	r.Deliveries = store.DeliveryCount()
+	r.Authors = store.AuthorListCounts()
+	r.Quota = Quota{
+		Platform: platform.Stats(),
+		AI:       textProcessor.Stats(),
+		Budgets:  budgets,
+	}
+	return nil
+}
+
+// Throughput returns the average number of haikus published per simulated day.
+func (r *Report) Throughput() float64 {
+	if r.Options.Days == 0 {
+		return 0
+	}
+	return float64(r.Quota.Platform.Comments) / float64(r.Options.Days)
+}
+
+// Print writes a human-readable report to w.
+func (r *Report) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Simulated %d day(s) from %s (seed %d)\n\n",
+		r.Options.Days, r.Options.Start.Format(time.RFC3339), r.Options.Seed)
+
+	fmt.Fprintln(tw, "Per day:")
+	header := []string{"day", "posts", "published", "new failures"}
+	for _, state := range entities.HaikuStates {
+		header = append(header, string(state))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, d := range r.Days {
+		row := []string{
+			d.Day.Format("2006-01-02 Mon"),
+			fmt.Sprint(d.PostsStored),
+			fmt.Sprint(d.Published),
+			fmt.Sprint(d.Failed),
+		}
+		for _, state := range entities.HaikuStates {
+			row = append(row, fmt.Sprint(d.QueueDepth[state]))
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "Final queue depth:")
+	for _, state := range entities.HaikuStates {
+		fmt.Fprintf(tw, "  %s\t%d\n", state, r.QueueDepth[state])
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "Job runs:")
+	names := make([]string, 0, len(r.JobRuns))
+	for name := range r.JobRuns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(tw, "  %s\t%d\n", name, r.JobRuns[name])
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "Notifications:")
+	channels := make([]string, 0, len(r.Notifications))
+	for channel := range r.Notifications {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	for _, channel := range channels {
+		fmt.Fprintf(tw, "  %s\t%d\n", channel, r.Notifications[channel])
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "Outbox:")
+	for _, status := range []entities.OutboxStatus{entities.OutboxStatusPending, entities.OutboxStatusDelivered, entities.OutboxStatusFailed} {
+		fmt.Fprintf(tw, "  %s\t%d\n", status, r.Outbox[status])
+	}
+	fmt.Fprintf(tw, "  delivery attempts\t%d\n", r.Deliveries)
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "Author lists:")
+	for _, list := range entities.AuthorLists {
+		fmt.Fprintf(tw, "  %s\t%d\n", list, r.Authors[list])
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "Engagement (latest sample per haiku):")
+	for _, group := range repositories.EngagementGroups {
+		fmt.Fprintf(tw, "  by %s\thaikus\tlikes\tshares\treplies\timpressions\tscore (95%% CI)\trate\n", group)
+		for _, rank := range r.Engagement[group] {
+			key := rank.Key
+			if key == "" {
+				key = "(none)"
+			}
+			low, high := rank.Interval()
+			fmt.Fprintf(tw, "    %s\t%d\t%d\t%d\t%d\t%d\t%.2f (%.2f-%.2f)\t%.2f%%\n",
+				key, rank.Haikus, rank.Likes, rank.Shares, rank.Replies, rank.Impressions, rank.Score(), low, high, 100*rank.Rate())
+		}
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintf(tw, "Throughput:\t%.2f haikus/day\n", r.Throughput())
+	fmt.Fprintf(tw, "Failures:\t%d haikus failed, %d fetch errors, %d comment errors\n",
+		r.QueueDepth[entities.HaikuStateFailed], r.Quota.Platform.FetchFailures, r.Quota.Platform.CommentErrors)
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "Quota usage:")
+	fmt.Fprintf(tw, "  platform fetch requests\t%d\n", r.Quota.Platform.FetchRequests)
+	fmt.Fprintf(tw, "  platform posts read\t%d\n", r.Quota.Platform.PostsRead)
+	fmt.Fprintf(tw, "  platform comments posted\t%d\n", r.Quota.Platform.Comments)
+	fmt.Fprintf(tw, "  platform mentions\t%d\n", r.Quota.Platform.Mentions)
+	fmt.Fprintf(tw, "  ai summary calls\t%d\n", r.Quota.AI.SummaryCalls)
+	fmt.Fprintf(tw, "  ai haiku calls\t%d\n", r.Quota.AI.HaikuCalls)
+	fmt.Fprintf(tw, "  ai translate calls\t%d\n", r.Quota.AI.TranslateCalls)
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "Budgets at end of run:")
+	for _, b := range r.Quota.Budgets {
+		fmt.Fprintf(tw, "  %s %s %s (%s)\t%d/%d\n", b.Provider, b.Resource, b.Period, b.PeriodKey, b.Used, b.Limit)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,200 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+
+	"github.com/dapplux/twitter-haiku-bot/config"
+	"github.com/dapplux/twitter-haiku-bot/experiment"
+	"github.com/dapplux/twitter-haiku-bot/freshness"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/ai"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/database/memory"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/logging"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/platforms"
+	"github.com/dapplux/twitter-haiku-bot/infrastructure/quota"
+	"github.com/dapplux/twitter-haiku-bot/language"
+	"github.com/dapplux/twitter-haiku-bot/review"
+	"github.com/dapplux/twitter-haiku-bot/safety"
+	"github.com/dapplux/twitter-haiku-bot/scheduler"
+	"github.com/dapplux/twitter-haiku-bot/scoring"
+	"github.com/dapplux/twitter-haiku-bot/services"
+)
+
+// Options controls a simulation run.
+type Options struct {
+	// Start is the virtual time the simulation begins at.
+	Start time.Time
+	// Days is the number of simulated days.
+	Days int
+	// Seed makes the fake backends deterministic.
+	Seed int64
+	// PlatformFailureRate is the probability (0..1) of a platform call failing.
+	PlatformFailureRate float64
+	// AIFailureRate is the probability (0..1) of an AI call failing.
+	AIFailureRate float64
+	// Budgets are the quota budgets enforced during the run.
+	Budgets []quota.Budget
+	// Schedule is the job schedule under test. Jitter is ignored so runs stay reproducible.
+	Schedule config.Schedule
+	// OutboxMaxAttempts and OutboxRetryBackoff configure publication delivery.
+	OutboxMaxAttempts  int
+	OutboxRetryBackoff time.Duration
+	// Review configures review mode. Nobody reviews during a simulation, so
+	// only auto-approved haikus are published.
+	Review config.Review
+	// Safety configures the safety filter.
+	Safety config.Safety
+	// Scoring configures the priority posts are haikued in.
+	Scoring config.Scoring
+	// Freshness configures how old posts may get before their haikus are
+	// skipped.
+	Freshness config.Freshness
+	// Engagement configures how long published haikus are sampled.
+	Engagement config.Engagement
+	// Experiment configures the arms haikus are generated with.
+	Experiment config.Experiment
+	// Languages configures how posts are haikued by language; some fake
+	// posts are in Japanese or Vietnamese.
+	Languages config.Languages
+	// Mentions configures haiku requests by mention; fans of the fake
+	// platform ask for haikus now and then.
+	Mentions config.Mentions
+}
+
+// scheduledJob tracks the next virtual fire time of a scheduler job.
+type scheduledJob struct {
+	job      scheduler.Job
+	schedule cron.Schedule
+	next     time.Time
+}
+
+// Run wires the real services and Scheduler jobs to fake backends and an
+// in-memory store, then fires every job on its cron schedule over a virtual
+// clock for the requested number of days.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if opts.Days <= 0 {
+		return nil, fmt.Errorf("days must be positive, got %d", opts.Days)
+	}
+
+	clock := NewVirtualClock(opts.Start)
+	store := memory.NewStore(clock.Now)
+	platform := platforms.NewFakeProvider(clock.Now, opts.Seed, opts.PlatformFailureRate)
+	textProcessor := ai.NewFakeProcessor(opts.Seed, opts.AIFailureRate)
+	notifier := memory.NewNotifier(store)
+	tracker := quota.NewTracker(memory.NewQuotaRepository(store), opts.Budgets, clock.Now)
+
+	meteredPlatform := platforms.NewMeteredProvider(platform, tracker, quota.ProviderTwitter)
+	meteredProcessor := ai.NewMeteredProcessor(textProcessor, tracker, quota.ProviderHuggingFace)
+
+	unit := memory.NewUnitOfWork(store)
+	haikuRepo := memory.NewHaikuRepository(store)
+	outboxRepo := memory.NewOutboxRepository(store)
+	authorRepo := memory.NewAuthorListRepository(store)
+
+	safetyFilter, err := safety.FromConfig(opts.Safety)
+	if err != nil {
+		return nil, err
+	}
+	scorer := scoring.FromConfig(opts.Scoring)
+	freshnessPolicy, err := freshness.FromConfig(opts.Freshness, clock.Now)
+	if err != nil {
+		return nil, err
+	}
+	languagePolicy, err := language.FromConfig(opts.Languages)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := experiment.FromConfig(opts.Experiment)
+	if err != nil {
+		return nil, err
+	}
+	haikuSvc := services.NewHaikuService(unit, haikuRepo, outboxRepo, authorRepo, meteredProcessor, notifier, review.FromConfig(opts.Review, languagePolicy.Forms()), safetyFilter, scorer, freshnessPolicy, languagePolicy, exp)
+	postRepo := memory.NewPostRepository(store)
+	postSvc := services.NewPostService(postRepo, haikuRepo, authorRepo, meteredPlatform, notifier, scorer, clock.Now)
+	cursorRepo := memory.NewCursorRepository(store)
+	authorSvc := services.NewAuthorService(unit, authorRepo, outboxRepo, cursorRepo, meteredPlatform)
+	var mentionSvc *services.MentionService
+	if opts.Mentions.Enabled {
+		trigger, err := regexp.Compile(opts.Mentions.Trigger)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mention trigger: %w", err)
+		}
+		mentionSvc = services.NewMentionService(haikuSvc, haikuRepo, postRepo, authorRepo, outboxRepo, cursorRepo, meteredPlatform, services.MentionPolicy{
+			Trigger:       trigger,
+			PerUserLimit:  opts.Mentions.PerUserLimit,
+			PerUserWindow: opts.Mentions.PerUserWindow,
+			Priority:      opts.Mentions.Priority,
+		}, clock.Now)
+	}
+	engagementRepo := memory.NewEngagementRepository(store)
+	engagementSvc := services.NewEngagementService(haikuRepo, engagementRepo, meteredPlatform, opts.Engagement.TrackFor, clock.Now)
+	dispatcher := services.NewOutboxDispatcher(unit, outboxRepo, haikuRepo, authorRepo, meteredPlatform, notifier, opts.OutboxMaxAttempts, opts.OutboxRetryBackoff, clock.Now)
+	sched := scheduler.NewScheduler(haikuSvc, postSvc, authorSvc, mentionSvc, engagementSvc, dispatcher, tracker, opts.Schedule, nil)
+
+	var jobs []*scheduledJob
+	for _, job := range sched.Jobs() {
+		schedule, err := scheduler.ParseSpec(job.Spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spec %q for job %s: %w", job.Spec, job.Name, err)
+		}
+		jobs = append(jobs, &scheduledJob{
+			job:      job,
+			schedule: schedule,
+			next:     schedule.Next(opts.Start),
+		})
+	}
+
+	report := newReport(opts)
+	end := opts.Start.Add(time.Duration(opts.Days) * 24 * time.Hour)
+	nextSample := opts.Start.Add(24 * time.Hour)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		due := nextDue(jobs)
+		if due == nil || due.next.After(end) {
+			break
+		}
+
+		for !due.next.Before(nextSample) {
+			clock.Set(nextSample)
+			report.sampleDay(nextSample, store, platform)
+			nextSample = nextSample.Add(24 * time.Hour)
+		}
+
+		clock.Set(due.next)
+		due.job.Run(logging.With(ctx, "job", due.job.Name, "run_id", uuid.NewString()))
+		report.JobRuns[due.job.Name]++
+		due.next = due.schedule.Next(due.next)
+	}
+
+	for !nextSample.After(end) {
+		clock.Set(nextSample)
+		report.sampleDay(nextSample, store, platform)
+		nextSample = nextSample.Add(24 * time.Hour)
+	}
+
+	if err := report.finish(ctx, store, engagementRepo, platform, textProcessor, tracker); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// nextDue returns the job with the earliest next fire time. Ties go to the
+// job registered first, matching the order the Scheduler adds them.
+func nextDue(jobs []*scheduledJob) *scheduledJob {
+	var due *scheduledJob
+	for _, j := range jobs {
+		if due == nil || j.next.Before(due.next) {
+			due = j
+		}
+	}
+	return due
+}